@@ -0,0 +1,337 @@
+// Package audit is the append-only log of registry mutations. Each entry
+// carries an HMAC-SHA256 over its fields and the MAC of the previous one,
+// keyed by a key kept apart from the log.
+//
+// Without the key nobody can edit, drop, insert or reorder entries without
+// breaking the chain, except cutting entries off the end: a log truncated
+// after any entry verifies. Whoever holds the key can rewrite the log as
+// they like, so keep Audit.key_file off the host or out of reach of those
+// who can write the data dir when that matters.
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	auditFile = "audit.log"
+	keyFile   = "audit.key"
+	keySize   = 32
+	// indexEvery is the distance in entries between the offsets Page
+	// seeks to
+	indexEvery = 256
+)
+
+var ErrTampered = errors.New("audit chain broken")
+
+// errTorn is a last line without its newline, left by a crash in the middle
+// of a write
+var errTorn = fmt.Errorf("%w: unterminated last entry", ErrTampered)
+
+// genesis is the prev MAC of the first entry in a fresh log
+var genesis = hex.EncodeToString(make([]byte, sha256.Size))
+
+type Entry struct {
+	Seq     uint64          `json:"seq"`
+	Time    time.Time       `json:"time"`
+	Peer    string          `json:"peer"`
+	Subject string          `json:"subject,omitempty"`
+	Method  string          `json:"method"`
+	Region  string          `json:"region"`
+	Key     string          `json:"key"`
+	Old     json.RawMessage `json:"old,omitempty"`
+	New     json.RawMessage `json:"new,omitempty"`
+	Prev    string          `json:"prev"`
+	Hash    string          `json:"hash"`
+}
+
+type Log struct {
+	mu     sync.Mutex
+	f      *os.File
+	path   string
+	key    []byte
+	writer *bufio.Writer
+	seq    uint64
+	last   string
+	// size is the end of the last entry written
+	size int64
+	// index holds the offset of entry i*indexEvery+1 at i
+	index []int64
+}
+
+// File is the path of the audit log in the data directory dir.
+func File(dir string) string {
+	return filepath.Join(dir, auditFile)
+}
+
+// KeyFile is the path of the key generated in the data directory dir when
+// Audit.key_file is not set.
+func KeyFile(dir string) string {
+	return filepath.Join(dir, keyFile)
+}
+
+// LoadKey reads the base64 key in path. A missing file is created with a
+// new random key when create is set.
+func LoadKey(path string, create bool) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		key := make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(key) + "\n"
+		if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit key file %s: %w", path, err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("invalid audit key file %s: key must be %d bytes", path, keySize)
+	}
+	return key, nil
+}
+
+// Open opens the audit log in dir, chained with key.
+func Open(dir string, key []byte) (*Log, error) {
+	return OpenPath(File(dir), key)
+}
+
+func OpenPath(path string, key []byte) (*Log, error) {
+	l := &Log{path: path, key: key, last: genesis}
+	var last *Entry
+	err := scan(path, 0, func(e *Entry, offset, end int64) error {
+		l.indexEntry(e.Seq, offset)
+		l.seq = e.Seq
+		l.last = e.Hash
+		l.size = end
+		last = e
+		return nil
+	})
+	if errors.Is(err, errTorn) {
+		// the entry was never acknowledged, cut it so the next one starts
+		// on a line of its own
+		err = os.Truncate(path, l.size)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	// appending to a log chained with another key would break it for good
+	if last != nil && !hmac.Equal([]byte(last.Hash), []byte(last.mac(key))) {
+		return nil, fmt.Errorf("%w: the last entry of %s does not verify with this key, restore its key or move the log aside", ErrTampered, path)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	l.f = f
+	l.writer = bufio.NewWriter(f)
+	return l, nil
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	return l.f.Close()
+}
+
+func (l *Log) indexEntry(seq uint64, offset int64) {
+	if (seq-1)%indexEvery == 0 && int((seq-1)/indexEvery) == len(l.index) {
+		l.index = append(l.index, offset)
+	}
+}
+
+// Record appends a mutation and fsyncs it. old and new are marshalled to
+// JSON, nil is omitted.
+func (l *Log) Record(peer, subject, method, region, key string, old, new any) error {
+	oldRaw, err := marshal(old)
+	if err != nil {
+		return err
+	}
+	newRaw, err := marshal(new)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := &Entry{
+		Seq:     l.seq + 1,
+		Time:    time.Now().UTC(),
+		Peer:    peer,
+		Subject: subject,
+		Method:  method,
+		Region:  region,
+		Key:     key,
+		Old:     oldRaw,
+		New:     newRaw,
+		Prev:    l.last,
+	}
+	entry.Hash = entry.mac(l.key)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := l.write(append(line, '\n')); err != nil {
+		// a partial line would end the chain for good, cut it off
+		l.writer.Reset(l.f)
+		return errors.Join(err, l.f.Truncate(l.size))
+	}
+
+	l.indexEntry(entry.Seq, l.size)
+	l.size += int64(len(line)) + 1
+	l.seq = entry.Seq
+	l.last = entry.Hash
+	return nil
+}
+
+// write appends line and fsyncs it.
+func (l *Log) write(line []byte) error {
+	if _, err := l.writer.Write(line); err != nil {
+		return err
+	}
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+// Page returns up to limit entries with a sequence number greater than
+// after. It seeks to the indexed entry closest before them.
+func (l *Log) Page(after uint64, limit int) ([]*Entry, error) {
+	l.mu.Lock()
+	if err := l.writer.Flush(); err != nil {
+		l.mu.Unlock()
+		return nil, err
+	}
+	if after >= l.seq {
+		l.mu.Unlock()
+		return nil, nil
+	}
+	offset := l.index[min(int(after/indexEvery), len(l.index)-1)]
+	l.mu.Unlock()
+
+	result := make([]*Entry, 0, limit)
+	err := scan(l.path, offset, func(e *Entry, _, _ int64) error {
+		if e.Seq <= after {
+			return nil
+		}
+		result = append(result, e)
+		if len(result) >= limit {
+			return io.EOF
+		}
+		return nil
+	})
+	// an entry written meanwhile may be read half way
+	if err == io.EOF || errors.Is(err, errTorn) {
+		err = nil
+	}
+	return result, err
+}
+
+// Verify walks the whole log and checks every MAC and link with key. It
+// returns the number of valid entries.
+func Verify(path string, key []byte) (uint64, error) {
+	var count uint64
+	prev := genesis
+	err := scan(path, 0, func(e *Entry, _, _ int64) error {
+		if e.Seq != count+1 {
+			return fmt.Errorf("%w: expected seq %d, got %d", ErrTampered, count+1, e.Seq)
+		}
+		if e.Prev != prev {
+			return fmt.Errorf("%w: seq %d does not link to previous entry", ErrTampered, e.Seq)
+		}
+		if !hmac.Equal([]byte(e.Hash), []byte(e.mac(key))) {
+			return fmt.Errorf("%w: seq %d MAC mismatch", ErrTampered, e.Seq)
+		}
+		prev = e.Hash
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func (e *Entry) mac(key []byte) string {
+	body := *e
+	body.Hash = ""
+	data, _ := json.Marshal(body)
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func marshal(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil || string(raw) == "null" {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// scan calls fn with every entry from offset on, along with where the
+// entry starts and ends.
+func scan(path string, offset int64, fn func(e *Entry, offset, end int64) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		} else if err == io.EOF {
+			return errTorn
+		} else if err != nil {
+			return err
+		}
+
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("%w: %v", ErrTampered, err)
+		}
+		end := offset + int64(len(line))
+		if err := fn(&e, offset, end); err != nil {
+			return err
+		}
+		offset = end
+	}
+}
@@ -0,0 +1,201 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestKey(t *testing.T, dir string) []byte {
+	t.Helper()
+	key, err := LoadKey(KeyFile(dir), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// writeLog records n entries in a new log in dir and closes it.
+func writeLog(t *testing.T, dir string, key []byte, n int) {
+	t.Helper()
+	l, err := Open(dir, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range n {
+		if err := l.Record("10.0.0.1:5000", "alice", "RegisterAgent", "eu", fmt.Sprintf("a%d.example.com", i), nil, map[string]int{"i": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	dir := t.TempDir()
+	key := newTestKey(t, dir)
+	if len(key) != keySize {
+		t.Fatalf("generated a %d byte key", len(key))
+	}
+	info, err := os.Stat(KeyFile(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+	again, err := LoadKey(KeyFile(dir), true)
+	if err != nil || !bytes.Equal(again, key) {
+		t.Errorf("LoadKey() read another key, %v", err)
+	}
+
+	if _, err := LoadKey(filepath.Join(dir, "missing.key"), false); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadKey() of a missing file error = %v", err)
+	}
+	short := filepath.Join(dir, "short.key")
+	if err := os.WriteFile(short, []byte("c2hvcnQ=\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKey(short, false); err == nil {
+		t.Error("LoadKey() took a short key")
+	}
+}
+
+func TestPage(t *testing.T) {
+	dir := t.TempDir()
+	key := newTestKey(t, dir)
+	n := 2*indexEvery + 10
+	writeLog(t, dir, key, n)
+
+	// reopening rebuilds the index and carries on the chain
+	l, err := Open(dir, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := l.Record("10.0.0.1:5000", "alice", "DeleteGateway", "eu", "g1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	n++
+	if len(l.index) != 3 {
+		t.Errorf("index holds %d offsets, want 3", len(l.index))
+	}
+
+	for _, after := range []uint64{0, 1, indexEvery - 1, indexEvery, indexEvery + 1, uint64(n) - 3, uint64(n), uint64(n) + 5} {
+		page, err := l.Page(after, 5)
+		if err != nil {
+			t.Fatalf("Page(%d) error = %v", after, err)
+		}
+		want := min(5, max(0, n-int(after)))
+		if len(page) != want {
+			t.Fatalf("Page(%d) returned %d entries, want %d", after, len(page), want)
+		}
+		for i, e := range page {
+			if e.Seq != after+uint64(i)+1 {
+				t.Errorf("Page(%d)[%d] has seq %d", after, i, e.Seq)
+			}
+		}
+	}
+
+	if count, err := Verify(File(dir), key); err != nil || count != uint64(n) {
+		t.Errorf("Verify() = %d, %v, want %d entries", count, err, n)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(lines [][]byte) [][]byte
+	}{
+		{
+			name: "edited",
+			damage: func(lines [][]byte) [][]byte {
+				lines[1] = bytes.Replace(lines[1], []byte("a1.example.com"), []byte("evil.example"), 1)
+				return lines
+			},
+		},
+		{
+			name: "dropped",
+			damage: func(lines [][]byte) [][]byte {
+				return append(lines[:1], lines[2:]...)
+			},
+		},
+		{
+			name: "reordered",
+			damage: func(lines [][]byte) [][]byte {
+				lines[0], lines[1] = lines[1], lines[0]
+				return lines
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			key := newTestKey(t, dir)
+			writeLog(t, dir, key, 3)
+
+			data, err := os.ReadFile(File(dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := bytes.SplitAfter(data, []byte("\n"))
+			if err := os.WriteFile(File(dir), bytes.Join(tt.damage(lines), nil), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Verify(File(dir), key); !errors.Is(err, ErrTampered) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrTampered)
+			}
+		})
+	}
+}
+
+func TestOpenWithAnotherKey(t *testing.T) {
+	dir := t.TempDir()
+	key := newTestKey(t, dir)
+	writeLog(t, dir, key, 2)
+
+	other := bytes.Repeat([]byte{1}, keySize)
+	if _, err := Open(dir, other); !errors.Is(err, ErrTampered) {
+		t.Errorf("Open() with another key error = %v, want %v", err, ErrTampered)
+	}
+	if _, err := Verify(File(dir), other); !errors.Is(err, ErrTampered) {
+		t.Errorf("Verify() with another key error = %v, want %v", err, ErrTampered)
+	}
+}
+
+func TestOpenTornTail(t *testing.T) {
+	dir := t.TempDir()
+	key := newTestKey(t, dir)
+	writeLog(t, dir, key, 2)
+
+	// a crash in the middle of the third entry
+	f, err := os.OpenFile(File(dir), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"seq":3,"time":"2026-`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := Verify(File(dir), key); !errors.Is(err, ErrTampered) {
+		t.Errorf("Verify() of a torn log error = %v, want %v", err, ErrTampered)
+	}
+
+	l, err := Open(dir, key)
+	if err != nil {
+		t.Fatalf("Open() of a torn log error = %v", err)
+	}
+	if err := l.Record("10.0.0.1:5000", "alice", "RegisterAgent", "eu", "a2.example.com", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := Verify(File(dir), key); count != 3 || err != nil {
+		t.Errorf("Verify() = %d, %v, want 3 entries", count, err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/odio4u/mem-sdk/certengine/pkg"
+	"github.com/odio4u/memstore/seeder/pkg/config"
+)
+
+func certFingurePrint(permfile string) (*string, error) {
+	certPEM, err := os.ReadFile(permfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file use `seeder gen-cert` to create certificates")
+	}
+
+	block, _ := pem.Decode(certPEM)
+
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("failed to decode PEM block containing certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(cert.Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+	return &fingerprint, nil
+}
+
+// generateCerts writes the server certificate and key into dir. certengine
+// always writes to the working directory, so it runs from inside dir.
+func generateCerts(config *config.Config, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+
+	routerIps := []string{config.Seeder.IP}
+	dns := []string{config.Seeder.Dns}
+
+	_, err = pkg.GenerateSelfSignedGPR(config.Seeder.Name, routerIps, dns)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Certificates generated successfully.")
+	return nil
+}
+
+// genCertCommand implements `seeder gen-cert`.
+func genCertCommand(opts *options, args []string) error {
+	fs, opts := opts.flags("gen-cert")
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	if err := generateCerts(opts.cfg, opts.dataDir); err != nil {
+		return fmt.Errorf("failed to generate certs: %w", err)
+	}
+	return nil
+}
+
+// fingerprintCommand implements `seeder fingerprint`, printing only the
+// SHA256 of the server certificate for scripts.
+func fingerprintCommand(opts *options, args []string) error {
+	fs, opts := opts.flags("fingerprint")
+	if err := opts.parse(fs, args); err != nil {
+		return err
+	}
+
+	fingerprint, err := certFingurePrint(opts.cfg.CertPath())
+	if err != nil {
+		return err
+	}
+	fmt.Println(*fingerprint)
+	return nil
+}
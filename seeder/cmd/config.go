@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/odio4u/memstore/seeder/pkg/config"
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+)
+
+// newVerifier returns nil when no issuers are configured, which keeps the
+// registration endpoints trusting the bare VerifiableCredHash.
+func newVerifier(config config.Identity) (*identity.Verifier, error) {
+	if len(config.Issuers) == 0 {
+		return nil, nil
+	}
+
+	issuers := make(map[string]string, len(config.Issuers))
+	for _, issuer := range config.Issuers {
+		issuers[issuer.ID] = issuer.PublicKey
+	}
+	return identity.NewVerifier(issuers, config.MaxSkew)
+}
+
+// configCommand implements `seeder config validate|keys`.
+func configCommand(opts *options, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: seeder config validate|keys")
+	}
+
+	switch args[0] {
+	case "validate":
+		fs, opts := opts.flags("config validate")
+		fs.Parse(args[1:])
+
+		name := opts.config
+		if name == "" {
+			name = defaultConfig
+		}
+		cfg, err := opts.load(true)
+		if err != nil {
+			return err
+		}
+
+		errs := cfg.Validate()
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("%d problems in %s", len(errs), name)
+		}
+		fmt.Printf("%s is valid\n", name)
+
+	case "keys":
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVARIABLE")
+		for _, key := range config.Default().Keys() {
+			fmt.Fprintf(w, "%s\t%s\n", key[0], key[1])
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown config command %q", args[0])
+	}
+	return nil
+}
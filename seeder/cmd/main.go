@@ -1,252 +1,213 @@
 package main
 
 import (
-	"crypto/sha256"
-	"crypto/tls"
-	"crypto/x509"
-	"encoding/hex"
-	"encoding/pem"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"net"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
+	"runtime"
 	"runtime/debug"
+	"slices"
+	"strings"
 
-	"github.com/gorilla/mux"
-	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
-	mapper "github.com/odio4u/agni-schema/maps"
-	walpb "github.com/odio4u/agni-schema/wal"
-	"github.com/odio4u/mem-sdk/certengine/pkg"
-	"github.com/odio4u/memstore/seeder/pkg/api"
-	"github.com/odio4u/memstore/seeder/pkg/maps"
-	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
-	wal "github.com/odio4u/memstore/seeder/wal"
-	"gopkg.in/yaml.v3"
-
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/reflection"
+	"github.com/odio4u/memstore/seeder/audit"
+	"github.com/odio4u/memstore/seeder/pkg/config"
 )
 
-type Seeder struct {
-	IP     string `yaml:"ip"`
-	Port   string `yaml:"port"`
-	Dns    string `yaml:"dns"`
-	Name   string `yaml:"name"`
-	Viewer string `yaml:"viewer"`
-	Region string `yaml:"region"`
+// version is set at build time with -ldflags "-X main.version=v0.2.0".
+var version = "dev"
+
+const usage = `usage: seeder [-config path] [-data-dir dir] [-set key=value] <command> [flags]
+
+commands:
+  run                        serve gRPC and the viewer (the default)
+  gen-cert                   write server.pem and server-key.pem to the data dir
+  fingerprint                print the SHA256 of the server certificate
+  config validate            check the config and the data dir
+  config keys                list the keys -set and SEEDER_* variables take
+  wal keygen <path>          write a new key-encryption key
+  wal rekey                  rewrite the WAL and snapshot under a new data key
+  wal inspect|verify         list or check the WAL records
+  wal repair                 cut a damaged WAL after its last intact record
+  wal compact                fold the WAL into the snapshot
+  snapshot create [-out f]   write a snapshot of the data dir to a file
+  snapshot restore <file>    replace the data dir state with a snapshot
+  snapshot inspect [file]    list the snapshot records
+  audit verify [path]        check the audit log MAC chain
+  version                    print the build version
+
+Every command takes -config, -data-dir and -set. The config is the file
+over the defaults, then SEEDER_* variables, then -set flags and -data-dir.
+wal rekey|repair|compact and snapshot restore change the data dir and
+refuse to run while a seeder holds its seeder.lock.
+`
+
+// defaultConfig is read when -config is not given, if it exists.
+const defaultConfig = "seeder-config.yaml"
+
+// overrides collects repeated -set flags.
+type overrides []string
+
+func (o *overrides) String() string {
+	return strings.Join(*o, ",")
 }
 
-type Config struct {
-	Version string `yaml:"version"`
-	Seeder  Seeder `yaml:"Seeder"`
+func (o *overrides) Set(value string) error {
+	*o = append(*o, value)
+	return nil
 }
 
-func gracefulShutdown(server *grpc.Server) {
-
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-	<-stop
-
-	log.Println("Shutting down server...")
-
-	// Attempt graceful shutdown
-	server.Stop()
-
+// options are the flags every command takes.
+type options struct {
+	config  string
+	dataDir string
+	set     overrides
+	// cfg is the config after parse, every override applied
+	cfg *config.Config
 }
 
-func certFingurePrint() (*string, error) {
-	permfile := "server.pem" // replace with your file path
-	certPEM, err := os.ReadFile(permfile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read certificate file use `seeder -gen-cert` to create certificates")
-	}
-
-	block, _ := pem.Decode(certPEM)
-
-	if block == nil || block.Type != "CERTIFICATE" {
-		return nil, fmt.Errorf("failed to decode PEM block containing certificate")
-	}
-
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return nil, err
-	}
-
-	sum := sha256.Sum256(cert.Raw)
-	fingerprint := hex.EncodeToString(sum[:])
-	log.Printf("Client CERT fingerprint (SHA256): %s", fingerprint)
-	return &fingerprint, nil
+// flags returns the flag set of a command with -config and -data-dir,
+// defaulting to the values given before the command.
+func (o *options) flags(name string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	opts := *o
+	opts.set = slices.Clone(o.set)
+	fs.StringVar(&opts.config, "config", o.config, "Seeder config file, "+defaultConfig+" if it exists")
+	fs.StringVar(&opts.dataDir, "data-dir", o.dataDir, "Directory of the certificates, WAL, snapshot, keyring and audit log, overrides data_dir")
+	fs.Var(&opts.set, "set", "Override a config key like WAL.sync=always, repeatable")
+	return fs, &opts
 }
 
-func generateCerts(config Config) error {
+// parse parses the command flags and loads the config, leaving the data
+// directory in dataDir.
+func (o *options) parse(fs *flag.FlagSet, args []string) error {
+	fs.Parse(args)
 
-	routerIps := []string{config.Seeder.IP}
-	dns := []string{config.Seeder.Dns}
-
-	_, err := pkg.GenerateSelfSignedGPR(config.Seeder.Name, routerIps, dns)
+	cfg, err := o.load(false)
 	if err != nil {
 		return err
 	}
-
-	log.Println("Certificates generated successfully.")
+	o.cfg = cfg
+	o.dataDir = cfg.DataDir
 	return nil
 }
 
-func main() {
-
-	data, err := os.ReadFile("seeder-config.yaml")
-	if err != nil {
-		log.Fatal("[Agni Seeder] Can not read the seeder config file")
+// load reads the config with the environment, -set and -data-dir applied.
+// A missing -config file is an error, a missing default one is not.
+func (o *options) load(strict bool) (*config.Config, error) {
+	path := o.config
+	if path == "" {
+		path = defaultConfig
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			path = ""
+		}
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		log.Fatal("[Agni Seeder] Falied to unmarsal seeder config")
+	cfg, err := config.Load(path, strict)
+	if err != nil {
+		return nil, err
 	}
-
-	genCert := flag.Bool("gen-cert", false, "Generate self-signed certificates")
-	flag.Parse()
-
-	if *genCert {
-		if err := generateCerts(config); err != nil {
-			log.Fatalf("[Agni Seeder] Failed to generate certs: %v", err)
+	for _, assignment := range o.set {
+		if err := cfg.Set(assignment); err != nil {
+			return nil, fmt.Errorf("-set: %w", err)
 		}
-		return // exit after generating certs
 	}
-
-	log.Println("Registry Service for Ingress Tunnel")
-
-	cert, err := tls.LoadX509KeyPair("server.pem", "server-key.pem")
-	if err != nil {
-		log.Fatalf("[Agni Seeder] failed to load server certificate use `seeder -gen-cert` to create certificates")
+	if o.dataDir != "" {
+		cfg.DataDir = o.dataDir
 	}
+	return cfg, nil
+}
 
-	servertLs := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		MinVersion:   tls.VersionTLS13,
-		MaxVersion:   tls.VersionTLS13,
-
-		CipherSuites: []uint16{
-			tls.TLS_AES_128_GCM_SHA256,
-			tls.TLS_AES_256_GCM_SHA384,
-		},
-
-		SessionTicketsDisabled:   true,
-		PreferServerCipherSuites: true,
-
-		CurvePreferences: []tls.CurveID{
-			tls.X25519,
-			tls.CurveP256,
-		},
-		Renegotiation: tls.RenegotiateNever,
+// auditKey loads Audit.key_file, or the key in the data dir, created
+// first when create is set and Audit.key_file is not.
+func (o *options) auditKey(create bool) ([]byte, error) {
+	if o.cfg.Audit.KeyFile != "" {
+		return audit.LoadKey(o.cfg.Audit.KeyFile, false)
 	}
+	return audit.LoadKey(audit.KeyFile(o.dataDir), create)
+}
 
-	fingureprint, err := certFingurePrint()
-	if err != nil {
-		log.Fatalf("[Agni Seeder] Failed to print certificate fingerprint: %v", err)
+// verifyAudit implements `seeder audit verify [path]`.
+func verifyAudit(opts *options, args []string) error {
+	if len(args) == 0 || args[0] != "verify" {
+		return errors.New("usage: seeder audit verify [path]")
 	}
 
-	port := config.Seeder.Port
-	if port == "" {
-		port = "50051"
+	fs, opts := opts.flags("audit verify")
+	if err := opts.parse(fs, args[1:]); err != nil {
+		return err
 	}
-	port = fmt.Sprintf(":%s", port)
 
-	lis, err := net.Listen("tcp", port)
-	if err != nil {
-		log.Fatalf("[Agni Seeder] failed to listen: %v", err)
+	path := audit.File(opts.dataDir)
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
 	}
 
-	recoveryOpts := []grpc_recovery.Option{
-		grpc_recovery.WithRecoveryHandler(func(p interface{}) error {
-			stack := string(debug.Stack())
-			log.Printf("[PANIC RECOVERED] %v\nSTACK TRACE:\n%s", p, stack)
-			return fmt.Errorf("internal server error")
-		}),
+	key, err := opts.auditKey(false)
+	if err != nil {
+		return fmt.Errorf("failed to load audit key: %w", err)
 	}
-
-	waler, err := wal.OpenWAL()
+	count, err := audit.Verify(path, key)
 	if err != nil {
-		log.Fatalf("[Agni Seeder] failed to open WAL: %v", err)
+		return fmt.Errorf("audit log %s failed verification after %d entries: %w", path, count, err)
 	}
-	defer waler.Close()
-
-	s := grpc.NewServer(
-		grpc.Creds(credentials.NewTLS(servertLs)),
-		grpc.UnaryInterceptor(grpc_recovery.UnaryServerInterceptor(recoveryOpts...)),
-		grpc.StreamInterceptor(grpc_recovery.StreamServerInterceptor(recoveryOpts...)),
-	)
-
-	store := memstore.NewMemStore()
-	mapper.RegisterMapsServer(s, &maps.RPCMap{
-		MemStore: store,
-		WALer:    waler,
-	})
-	reflection.Register(s)
-
-	_ = waler.Replay(func(wr *walpb.WalRecord) error {
-		return wal.ApplyRecord(store, wr)
-
-	})
-
-	apis := api.NewApi(store)
-	router := mux.NewRouter()
+	log.Printf("[Agni Seeder] audit log %s verified, %d entries", path, count)
+	return nil
+}
 
-	api.SetRoutes(router, apis)
+func versionCommand(opts *options, args []string) error {
+	revision, modified := "unknown", false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	fmt.Printf("seeder %s (%s) %s %s/%s\n", version, revision, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return nil
+}
 
-	readyGRPC := make(chan struct{})
-	readyHTTP := make(chan struct{})
+var commands = map[string]func(*options, []string) error{
+	"run":         runCommand,
+	"gen-cert":    genCertCommand,
+	"fingerprint": fingerprintCommand,
+	"config":      configCommand,
+	"wal":         walCommand,
+	"snapshot":    snapshotCommand,
+	"audit":       verifyAudit,
+	"version":     versionCommand,
+}
 
-	// Start the server
-	go func() {
-		log.Println("GRPC server listening in :", port)
-		close(readyGRPC)
-		if err := s.Serve(lis); err != nil {
-			log.Fatalf("[Agni Seeder] failed to serve: %v", err)
-		}
-	}()
+func main() {
+	opts := &options{}
+	flag.StringVar(&opts.config, "config", "", "Seeder config file, "+defaultConfig+" if it exists")
+	flag.StringVar(&opts.dataDir, "data-dir", "", "Directory of the certificates, WAL, snapshot, keyring and audit log, overrides data_dir")
+	flag.Var(&opts.set, "set", "Override a config key like WAL.sync=always, repeatable")
+	genCert := flag.Bool("gen-cert", false, "Deprecated, use `seeder gen-cert`")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
 
-	httpserver := &http.Server{
-		Addr:         ":" + config.Seeder.Viewer,
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	name, args := "run", flag.Args()
+	if *genCert {
+		name = "gen-cert"
+	} else if len(args) > 0 {
+		name, args = args[0], args[1:]
 	}
 
-	go func() {
-		log.Printf("Starting server on port %s", config.Seeder.Viewer)
-		close(readyHTTP)
-		if err := httpserver.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
-
-	// Wait for both servers to signal readiness
-	<-readyGRPC
-	<-readyHTTP
-
-	// Now safe to run your data-saving function
-	log.Println("Both servers are up. Saving data...")
-	store.AddSeeder(
-		&memstore.SeederData{
-			SeederID:       "register-q",
-			Name:           config.Seeder.Name,
-			Dns:            config.Seeder.Dns,
-			SeedIP:         config.Seeder.IP,
-			SeedPort:       config.Seeder.Port,
-			Region:         config.Seeder.Region,
-			VerifiableHash: *fingureprint,
-		},
-	)
-
-	gracefulShutdown(s)
-
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+	if err := command(opts, args); err != nil {
+		log.Fatalf("[Agni Seeder] %v", err)
+	}
 }
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOptionsLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seeder.yaml")
+	err := os.WriteFile(path, []byte("data_dir: /var/lib/seeder\nSeeder:\n  port: 7000\n  viewer: 7100\nLogging:\n  level: warn\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SEEDER_PORT", "7001")
+	t.Setenv("SEEDER_VIEWER", "7101")
+
+	// the file, then the environment, then -set, then -data-dir
+	fs, opts := (&options{}).flags("run")
+	if err := fs.Parse([]string{"-config", path, "-set", "Seeder.port=7002", "-set", "logging.level=debug", "-data-dir", "/tmp/seeder"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := opts.load(true)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if cfg.Seeder.Port != 7002 || cfg.Seeder.Viewer != 7101 || cfg.Logging.Level != "debug" || cfg.DataDir != "/tmp/seeder" {
+		t.Errorf("load() = %+v, %+v, data dir %s", cfg.Seeder, cfg.Logging, cfg.DataDir)
+	}
+
+	bad := *opts
+	bad.set = overrides{"Seeder.port=http"}
+	if _, err := bad.load(false); err == nil || !strings.HasPrefix(err.Error(), "-set: invalid Seeder.port") {
+		t.Errorf("load() with a bad -set error = %v", err)
+	}
+
+	t.Setenv("SEEDER_PORT", "http")
+	if _, err := opts.load(false); err == nil || !strings.Contains(err.Error(), "SEEDER_PORT") {
+		t.Errorf("load() with a bad variable error = %v", err)
+	}
+
+	missing := options{config: filepath.Join(t.TempDir(), "missing.yaml")}
+	if _, err := missing.load(false); !os.IsNotExist(err) {
+		t.Errorf("load() of a missing -config error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/odio4u/memstore/seeder/pkg/acl"
+	"github.com/odio4u/memstore/seeder/pkg/config"
+	"github.com/odio4u/memstore/seeder/pkg/limits"
+	"github.com/odio4u/memstore/seeder/pkg/logging"
+	"github.com/odio4u/memstore/seeder/pkg/maps"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/metrics"
+)
+
+// reloader applies config changes to a running seeder, on SIGHUP and on
+// POST /v1/config/reload.
+type reloader struct {
+	mu   sync.Mutex
+	opts *options
+	// started is the config the seeder started with, current the last one
+	// applied
+	started *config.Config
+	current *config.Config
+
+	level   *slog.LevelVar
+	limiter *limits.Limiter
+	policy  *maps.Policy
+	store   *memstore.MemStore
+	logger  *slog.Logger
+}
+
+func touches(keys []string, section string) bool {
+	return slices.ContainsFunc(keys, func(key string) bool {
+		return key == section || strings.HasPrefix(key, section+".")
+	})
+}
+
+// Reload re-reads the config. An invalid config changes nothing; a valid
+// one has its reloadable changes applied together, the others are
+// reported until the next restart.
+func (r *reloader) Reload(ctx context.Context) (maps.ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result, err := r.reload()
+	if err != nil {
+		metrics.ConfigReloads.WithLabelValues("failed").Inc()
+		logging.FromContext(ctx, r.logger).Error("config reload failed, the running config is unchanged", "err", err)
+		return result, err
+	}
+
+	outcome := "applied"
+	if len(result.Applied) == 0 {
+		outcome = "unchanged"
+	}
+	metrics.ConfigReloads.WithLabelValues(outcome).Inc()
+	metrics.ConfigLastReload.SetToCurrentTime()
+	metrics.ConfigRestartPending.Set(float64(len(result.Ignored)))
+
+	log := logging.FromContext(ctx, r.logger)
+	for _, key := range result.Ignored {
+		log.Warn("config change needs a restart, ignored", "key", key)
+	}
+	log.Info("config reloaded", "result", outcome, "applied", result.Applied, "ignored", result.Ignored)
+	return result, nil
+}
+
+func (r *reloader) reload() (maps.ReloadResult, error) {
+	next, err := r.opts.load(false)
+	if err != nil {
+		return maps.ReloadResult{}, err
+	}
+	if errs := next.Validate(); len(errs) > 0 {
+		return maps.ReloadResult{}, errors.Join(errs...)
+	}
+
+	result := maps.ReloadResult{Applied: []string{}, Ignored: []string{}}
+	for _, key := range r.current.Changed(next) {
+		if config.Reloadable(key) {
+			result.Applied = append(result.Applied, key)
+		}
+	}
+	for _, key := range r.started.Changed(next) {
+		if !config.Reloadable(key) {
+			result.Ignored = append(result.Ignored, key)
+		}
+	}
+
+	// everything that can fail is done before anything is applied
+	verifier, err := newVerifier(next.Identity)
+	if err != nil {
+		return maps.ReloadResult{}, err
+	}
+	strategy, err := memstore.ParseStrategy(next.Placement.Strategy)
+	if err != nil {
+		return maps.ReloadResult{}, err
+	}
+	level := new(slog.LevelVar)
+	if err := logging.SetLevel(level, next.Logging.Level); err != nil {
+		return maps.ReloadResult{}, err
+	}
+
+	if touches(result.Applied, "Identity") {
+		r.policy.Set(verifier, acl.New(next.Identity.ACL))
+	}
+	if touches(result.Applied, "Limits.per_identity") || touches(result.Applied, "Limits.per_method") {
+		r.limiter.Update(next.Limits.PerIdentity, next.Limits.PerMethod)
+	}
+	r.store.SetQuotas(next.Limits.Quotas)
+	r.store.SetStrategy(strategy)
+	r.level.Set(level.Level())
+
+	r.current = next
+	return result, nil
+}
+
+// watchSignals reloads on every SIGHUP.
+func (r *reloader) watchSignals() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		r.Reload(context.Background())
+	}
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"runtime/debug"
+
+	"github.com/gorilla/mux"
+	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	mapper "github.com/odio4u/agni-schema/maps"
+	"github.com/odio4u/memstore/seeder/audit"
+	"github.com/odio4u/memstore/seeder/pkg/acl"
+	"github.com/odio4u/memstore/seeder/pkg/api"
+	cfgpkg "github.com/odio4u/memstore/seeder/pkg/config"
+	"github.com/odio4u/memstore/seeder/pkg/diag"
+	"github.com/odio4u/memstore/seeder/pkg/health"
+	"github.com/odio4u/memstore/seeder/pkg/limits"
+	"github.com/odio4u/memstore/seeder/pkg/logging"
+	"github.com/odio4u/memstore/seeder/pkg/maps"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/metrics"
+	"github.com/odio4u/memstore/seeder/pkg/tracing"
+	registrypb "github.com/odio4u/memstore/seeder/proto"
+	wal "github.com/odio4u/memstore/seeder/wal"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// clientAuth verifies client certificates against the TLS.client_ca pool.
+// Without one a certificate proves nothing, so none is asked for and callers
+// are known by their address.
+func clientAuth(mode string, pool *x509.CertPool) tls.ClientAuthType {
+	if pool == nil {
+		return tls.NoClientCert
+	}
+	switch mode {
+	case "request":
+		return tls.VerifyClientCertIfGiven
+	case "require":
+		return tls.RequireAndVerifyClientCert
+	}
+	return tls.NoClientCert
+}
+
+func loadClientCA(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificate in %s", path)
+	}
+	return pool, nil
+}
+
+// every calls fn each interval until ctx is done.
+func every(ctx context.Context, interval time.Duration, fn func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn(ctx)
+		}
+	}
+}
+
+// periodicSnapshot folds the WAL into the snapshot once minRecords were
+// appended since the last one.
+func periodicSnapshot(ctx context.Context, waler *wal.WALer, store *memstore.MemStore, minRecords uint64, logger *slog.Logger) {
+	st, err := waler.Stats()
+	if err != nil {
+		logger.Error("periodic snapshot skipped", "err", err)
+		return
+	}
+	if st.SinceSnapshot < minRecords {
+		return
+	}
+
+	_, err = waler.Snapshot(ctx, func(emit func(*walpb.WalRecord) error) error {
+		return wal.Dump(store, emit)
+	})
+	if err != nil {
+		logger.Error("periodic snapshot failed", "err", err)
+	}
+}
+
+// runCommand implements `seeder run`, serving gRPC and the viewer until
+// SIGINT or SIGTERM or until a server fails. Either way it shuts down in
+// order and returns an error when anything failed.
+func runCommand(opts *options, args []string) error {
+	fs, opts := opts.flags("run")
+	if err := opts.parse(fs, args); err != nil {
+		return fmt.Errorf("can not read the seeder config file: %w", err)
+	}
+	// `seeder run grpc server` is what older docs and scripts call
+	if rest := fs.Args(); len(rest) > 0 && !(len(rest) == 2 && rest[0] == "grpc" && rest[1] == "server") {
+		return fmt.Errorf("unexpected arguments %v", rest)
+	}
+
+	config := opts.cfg
+	// nothing is opened before the whole config checks out
+	if errs := config.Validate(); len(errs) > 0 {
+		for _, err := range errs {
+			log.Printf("[Agni Seeder] config: %v", err)
+		}
+		return fmt.Errorf("%d config problems, see `seeder config validate`", len(errs))
+	}
+	if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+		return err
+	}
+
+	logger, level, err := logging.New(os.Stderr, config.Logging)
+	if err != nil {
+		return fmt.Errorf("invalid logging config: %w", err)
+	}
+	// the standard log package now writes through the same handler
+	slog.SetDefault(logger)
+
+	logger.Info("registry service for ingress tunnel")
+
+	shutdownTracing, err := tracing.Setup(context.Background(), config.Tracing, config.Seeder.Name)
+	if err != nil {
+		return fmt.Errorf("invalid tracing config: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("failed to flush traces", "err", err)
+		}
+	}()
+
+	cert, err := tls.LoadX509KeyPair(config.CertPath(), config.CertKeyPath())
+	if err != nil {
+		return fmt.Errorf("failed to load server certificate, use `seeder -gen-cert` to create certificates: %w", err)
+	}
+
+	clientCAs, err := loadClientCA(config.TLS.ClientCA)
+	if err != nil {
+		return fmt.Errorf("failed to load client CA: %w", err)
+	}
+
+	servertLs := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+		MaxVersion:   tls.VersionTLS13,
+
+		CipherSuites: []uint16{
+			tls.TLS_AES_128_GCM_SHA256,
+			tls.TLS_AES_256_GCM_SHA384,
+		},
+
+		SessionTicketsDisabled:   true,
+		PreferServerCipherSuites: true,
+
+		CurvePreferences: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+		},
+		Renegotiation: tls.RenegotiateNever,
+		ClientAuth:    clientAuth(config.TLS.ClientAuth, clientCAs),
+		ClientCAs:     clientCAs,
+	}
+
+	fingureprint, err := certFingurePrint(config.CertPath())
+	if err != nil {
+		return fmt.Errorf("failed to print certificate fingerprint: %w", err)
+	}
+	// printed as is, operators copy it from here into every client config
+	log.Printf("Client CERT fingerprint (SHA256): %s", *fingureprint)
+
+	var keyring *wal.Keyring
+	if config.WAL.KeyFile != "" {
+		keyring, err = wal.LoadKeyring(config.WAL.KeyFile, opts.dataDir)
+		if err != nil {
+			return fmt.Errorf("failed to load WAL keyring: %w", err)
+		}
+	}
+	auditKey, err := opts.auditKey(true)
+	if err != nil {
+		return fmt.Errorf("failed to load audit key: %w", err)
+	}
+	verifier, err := newVerifier(config.Identity)
+	if err != nil {
+		return fmt.Errorf("invalid identity config: %w", err)
+	}
+
+	port := fmt.Sprintf(":%d", config.Seeder.Port)
+
+	lis, err := net.Listen("tcp", port)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	// the gRPC server closes it once it serves, this covers a failure
+	// before that
+	defer lis.Close()
+
+	recoveryOpts := []grpc_recovery.Option{
+		grpc_recovery.WithRecoveryHandler(func(p interface{}) error {
+			stack := string(debug.Stack())
+			logger.Error("panic recovered", "panic", p, "stack", stack)
+			return fmt.Errorf("internal server error")
+		}),
+	}
+
+	// held until the process exits, the offline wal and snapshot commands
+	// refuse to run meanwhile
+	dirLock, err := wal.LockDir(opts.dataDir)
+	if err != nil {
+		return err
+	}
+	defer dirLock.Close()
+
+	waler, err := wal.OpenWAL(opts.dataDir, keyring, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open WAL: %w", err)
+	}
+	waler.SyncAppends(config.WAL.Sync == cfgpkg.SyncAlways)
+
+	auditLog, err := audit.Open(opts.dataDir, auditKey)
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to open audit log: %w", err), waler.Close())
+	}
+
+	limiter := limits.NewLimiter(config.Limits.PerIdentity, config.Limits.PerMethod)
+
+	checker := health.New(
+		mapper.Maps_ServiceDesc.ServiceName,
+		registrypb.Registry_ServiceDesc.ServiceName,
+	)
+	checker.AddGate(health.GateWALReplay)
+	checker.AddCheck("wal", waler.Health)
+
+	// methods changing the store, they carry credentials
+	writes := []string{
+		mapper.Maps_RegisterGateway_FullMethodName,
+		mapper.Maps_RegisterAgent_FullMethodName,
+	}
+
+	// shared with the JSON transcoding on the viewer port
+	interceptors := []grpc.UnaryServerInterceptor{
+		tracing.UnaryServerInterceptor(),
+		logging.UnaryServerInterceptor(),
+		metrics.UnaryServerInterceptor(),
+		grpc_recovery.UnaryServerInterceptor(recoveryOpts...),
+		// reads go on while the WAL cannot be written
+		checker.UnaryServerInterceptor(writes...),
+		limiter.UnaryServerInterceptor(),
+	}
+
+	s := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(servertLs)),
+		grpc.ChainUnaryInterceptor(interceptors...),
+		grpc.StreamInterceptor(grpc_recovery.StreamServerInterceptor(recoveryOpts...)),
+	)
+
+	if verifier == nil {
+		logger.Warn("no credential issuers configured, registrations are not verified")
+	}
+
+	policy := maps.NewPolicy(verifier, acl.New(config.Identity.ACL))
+
+	store := memstore.NewMemStore(logger)
+	strategy, _ := memstore.ParseStrategy(config.Placement.Strategy)
+	store.SetStrategy(strategy)
+	metrics.MustRegister(metrics.NewStoreCollector(store))
+	mapsServer := &maps.RPCMap{
+		MemStore: store,
+		Policy:   policy,
+		Audit:    auditLog,
+		// in-band errors stay the default until clients read statuses
+		StatusErrors: config.Seeder.StatusErrors,
+		Logger:       logger.With("component", "maps"),
+	}
+	mapper.RegisterMapsServer(s, mapsServer)
+	registry := &maps.RPCRegistry{
+		MemStore: store,
+		Policy:   policy,
+	}
+	registrypb.RegisterRegistryServer(s, registry)
+	healthpb.RegisterHealthServer(s, checker.Server())
+	reflection.Register(s)
+
+	reload := &reloader{
+		opts:    opts,
+		started: config,
+		current: config,
+		level:   level,
+		limiter: limiter,
+		policy:  policy,
+		store:   store,
+		logger:  logger.With("component", "config"),
+	}
+	admin := &maps.Admin{
+		MemStore: store,
+		WALer:    waler,
+		Policy:   policy,
+		Audit:    auditLog,
+		Reload:   reload.Reload,
+		Logger:   logger.With("component", "admin"),
+	}
+
+	apis := api.NewApi(store, auditLog, checker, registry, admin, logger)
+	router := mux.NewRouter()
+
+	api.SetRoutes(router, apis)
+	mapsDesc := &mapper.Maps_ServiceDesc
+	if !config.Seeder.ViewerTLS {
+		// registrations would cross the network in clear text
+		mapsDesc = api.WithoutMethods(mapsDesc, writes...)
+		logger.Warn("viewer_tls is off, registrations are not served as JSON on the viewer port")
+	}
+	api.MountService(router.PathPrefix("/v1/maps").Subrouter(), mapsDesc, mapsServer, interceptors...)
+
+	// a server that stops on its own shuts the seeder down
+	serveErr := make(chan error, 3)
+
+	// pprof and store internals stay off the viewer port
+	var debugServer *diag.Server
+	if config.Debug.Listen != "" {
+		debugServer = diag.New(config.Debug, store, logger)
+		go func() {
+			if err := debugServer.Serve(); err != nil && err != http.ErrServerClosed {
+				serveErr <- fmt.Errorf("debug server: %w", err)
+			}
+		}()
+	}
+
+	readyGRPC := make(chan struct{})
+	readyHTTP := make(chan struct{})
+
+	// Start the server
+	go func() {
+		logger.Info("grpc server listening", "addr", port)
+		close(readyGRPC)
+		if err := s.Serve(lis); err != nil {
+			serveErr <- fmt.Errorf("grpc server: %w", err)
+		}
+	}()
+
+	httpserver := &http.Server{
+		Addr:         fmt.Sprintf(":%d", config.Seeder.Viewer),
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	if config.Seeder.ViewerTLS {
+		httpserver.TLSConfig = servertLs
+	}
+	httpserver.RegisterOnShutdown(apis.CloseStreams)
+
+	go func() {
+		logger.Info("viewer server listening", "addr", httpserver.Addr)
+		close(readyHTTP)
+		var err error
+		if httpserver.TLSConfig != nil {
+			// the certificate is already in TLSConfig
+			err = httpserver.ListenAndServeTLS("", "")
+		} else {
+			err = httpserver.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			serveErr <- fmt.Errorf("viewer server: %w", err)
+		}
+	}()
+
+	// Wait for both servers to signal readiness
+	<-readyGRPC
+	<-readyHTTP
+
+	stopped := &shutdown{
+		config:   config.Shutdown,
+		snapshot: config.Snapshot.OnShutdown,
+		checker:  checker,
+		grpc:     s,
+		viewer:   httpserver,
+		debug:    debugServer,
+		waler:    waler,
+		store:    store,
+		audit:    auditLog,
+		logger:   logger.With("component", "shutdown"),
+	}
+	// a partly replayed store is neither served nor snapshotted
+	abort := func(err error) error {
+		stopped.config.Delay = 0
+		stopped.snapshot = false
+		return errors.Join(err, stopped.run())
+	}
+
+	// both servers answer health checks with NOT_SERVING while the store
+	// is rebuilt from the snapshot and the WAL
+	apply := func(wr *walpb.WalRecord) error {
+		return wal.ApplyRecord(store, wr)
+	}
+	if err := waler.ReplaySnapshot(apply); err != nil {
+		return abort(fmt.Errorf("failed to load snapshot: %w", err))
+	}
+	if err := waler.Replay(apply); err != nil {
+		// appends would land after the damage and be lost to the next
+		// replay, so the seeder never serves a partly replayed WAL
+		return abort(fmt.Errorf("WAL replay failed, run `seeder wal repair` to cut it after its last intact record: %w", err))
+	}
+
+	// quotas only apply to new registrations, replay restores whatever was
+	// accepted before they were lowered
+	store.SetQuotas(config.Limits.Quotas)
+	// every change from now on is appended to the WAL before it is applied
+	store.SetJournal(waler.Journal())
+
+	checker.Open(health.GateWALReplay)
+
+	// the periodic work stops before shutdown closes the WAL
+	background, stopBackground := context.WithCancel(context.Background())
+	go checker.Watch(background, 5*time.Second)
+	go reload.watchSignals()
+
+	if config.WAL.Sync == cfgpkg.SyncInterval {
+		go every(background, config.WAL.SyncInterval, func(ctx context.Context) {
+			if err := waler.Sync(ctx); err != nil {
+				logger.Error("wal sync failed", "err", err)
+			}
+		})
+	}
+	if config.Snapshot.Interval > 0 {
+		go every(background, config.Snapshot.Interval, func(ctx context.Context) {
+			periodicSnapshot(ctx, waler, store, config.Snapshot.MinRecords, logger)
+		})
+	}
+	if config.Rebalance.Interval > 0 {
+		rebalancer := memstore.NewRebalancer(store, config.Rebalance, logger)
+		rebalancer.OnMove = func(move memstore.Move) {
+			metrics.RebalanceMoves.WithLabelValues(move.Reason).Inc()
+		}
+		go rebalancer.Run(background)
+	}
+
+	// Now safe to run your data-saving function
+	logger.Info("both servers are up, registering seeder")
+	store.AddSeeder(
+		&memstore.SeederData{
+			SeederID:       config.SeederID(),
+			Name:           config.Seeder.Name,
+			Dns:            config.Seeder.Dns,
+			SeedIP:         config.Seeder.IP,
+			SeedPort:       config.Seeder.Port.String(),
+			Region:         config.Seeder.Region,
+			VerifiableHash: *fingureprint,
+		},
+	)
+	// peers are listed to clients as they are configured, nothing checks
+	// they are up
+	for _, peer := range config.Cluster.Peers {
+		id := peer.ID
+		if id == "" {
+			id = peer.Name
+		}
+		store.AddSeeder(&memstore.SeederData{
+			SeederID:       id,
+			Name:           peer.Name,
+			Dns:            peer.Dns,
+			SeedIP:         peer.IP,
+			SeedPort:       peer.Port.String(),
+			Region:         peer.Region,
+			VerifiableHash: peer.Fingerprint,
+		})
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	var cause error
+	select {
+	case sig := <-stop:
+		logger.Info("received signal", "signal", sig.String())
+	case cause = <-serveErr:
+		logger.Error("server failed", "err", cause)
+	}
+	signal.Stop(stop)
+	stopBackground()
+
+	return errors.Join(cause, stopped.run())
+}
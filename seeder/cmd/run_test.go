@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	wal "github.com/odio4u/memstore/seeder/wal"
+)
+
+// freePort returns a TCP port nothing listens on.
+func freePort(t *testing.T) int {
+	t.Helper()
+	lis, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+func TestRunFailure(t *testing.T) {
+	// run replaces the default logger
+	defaultLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+	grpcPort, viewerPort := freePort(t), freePort(t)
+	dir := t.TempDir()
+	opts := &options{dataDir: dir, set: overrides{
+		"Seeder.name=seeder-1",
+		"Seeder.region=eu",
+		"Logging.level=error",
+		fmt.Sprintf("Seeder.port=%d", grpcPort),
+		fmt.Sprintf("Seeder.viewer=%d", viewerPort),
+	}}
+	fs, parsed := opts.flags("gen-cert")
+	if err := parsed.parse(fs, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := generateCerts(parsed.cfg, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(wal.File(dir), []byte("not a wal record"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the servers are up and the WAL is open when replay fails
+	err := runCommand(opts, nil)
+	if err == nil || !strings.Contains(err.Error(), "WAL replay failed") {
+		t.Fatalf("runCommand() with a damaged WAL error = %v", err)
+	}
+
+	// everything it opened is released again
+	lock, err := wal.LockDir(dir)
+	if err != nil {
+		t.Errorf("data directory still locked: %v", err)
+	} else {
+		lock.Close()
+	}
+	for _, port := range []int{grpcPort, viewerPort} {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			t.Errorf("port %d still in use: %v", port, err)
+			continue
+		}
+		lis.Close()
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "wal.log")); err != nil || string(data) != "not a wal record" {
+		t.Errorf("WAL = %q, %v, want it left as it was", data, err)
+	}
+}
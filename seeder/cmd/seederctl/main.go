@@ -0,0 +1,323 @@
+// seederctl is the operator client of a seeder. It speaks gRPC over
+// mutual TLS, pinning the server certificate to its fingerprint, and uses
+// the viewer port for draining, watching and seeder listings.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	"github.com/odio4u/memstore/seeder/pkg/client"
+	registrypb "github.com/odio4u/memstore/seeder/proto"
+)
+
+const usage = `usage: seederctl [flags] <command> [flags]
+
+commands:
+  status                     health of the seeder, regions and their seeders
+  gateway register           register a gateway
+  gateway list               list gateways, all regions unless -region
+  gateway drain <id>         stop placing new agents on a gateway
+  gateway state <id> <state> set a gateway active, draining, maintenance or offline
+  agent register             register an agent on a gateway
+  agent resolve <domain>     show the gateway serving an agent
+  agent list                 list agents
+  watch                      stream registry changes until interrupted
+  config reload              make the seeder re-read its config, like SIGHUP
+  audit                      page through the audit log of one seeder
+
+flags:
+`
+
+// globals are the connection flags, each defaulting to a SEEDERCTL_*
+// environment variable.
+type globals struct {
+	addr        string
+	viewer      string
+	fingerprint string
+	caFile      string
+	certFile    string
+	keyFile     string
+	issuer      string
+	subject     string
+	signingKey  string
+	output      string
+	timeout     time.Duration
+}
+
+func env(name, fallback string) string {
+	if v := os.Getenv("SEEDERCTL_" + name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func (g *globals) dial() (*client.Client, error) {
+	config := client.Config{
+		CAFile:   g.caFile,
+		CertFile: g.certFile,
+		KeyFile:  g.keyFile,
+		Timeout:  g.timeout,
+	}
+	// the i-th address, viewer and fingerprint describe one seeder
+	viewers, fingerprints := split(g.viewer), split(g.fingerprint)
+	for i, addr := range split(g.addr) {
+		e := client.Endpoint{Address: addr}
+		if i < len(viewers) {
+			e.Viewer = viewers[i]
+		}
+		if i < len(fingerprints) {
+			e.Fingerprint = fingerprints[i]
+		}
+		config.Seeders = append(config.Seeders, e)
+	}
+	if g.signingKey != "" {
+		key, err := client.LoadKey(g.signingKey)
+		if err != nil {
+			return nil, err
+		}
+		if g.issuer == "" || g.subject == "" {
+			return nil, errors.New("-issuer and -subject are required with -signing-key")
+		}
+		config.Signer = &client.Signer{Issuer: g.issuer, Subject: g.subject, Key: key}
+	}
+	return client.Dial(config)
+}
+
+func split(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+type command func(ctx context.Context, c *client.Client, p *printer, args []string) error
+
+var commands = map[string]map[string]command{
+	"status": {"": statusCommand},
+	"watch":  {"": watchCommand},
+	"gateway": {
+		"register": registerGateway,
+		"list":     listGateways,
+		"drain":    drainGateway,
+		"state":    setGatewayState,
+	},
+	"agent": {
+		"register": registerAgent,
+		"resolve":  resolveAgent,
+		"list":     listAgents,
+	},
+	"config": {
+		"reload": reloadConfig,
+	},
+	"audit": {"": auditCommand},
+}
+
+func main() {
+	g := &globals{}
+	flag.StringVar(&g.addr, "addr", env("ADDR", "localhost:50051"), "Seeder gRPC addresses, comma separated")
+	flag.StringVar(&g.viewer, "viewer", env("VIEWER", ""), "Seeder viewer URLs like http://localhost:9000, comma separated in the order of -addr")
+	flag.StringVar(&g.fingerprint, "fingerprint", env("FINGERPRINT", ""), "SHA256 fingerprints of the seeder certificates, comma separated")
+	flag.StringVar(&g.caFile, "ca", env("CA", ""), "CA verifying the seeder certificates instead of fingerprints")
+	flag.StringVar(&g.certFile, "cert", env("CERT", ""), "Client certificate for mutual TLS")
+	flag.StringVar(&g.keyFile, "key", env("KEY", ""), "Client certificate key")
+	flag.StringVar(&g.issuer, "issuer", env("ISSUER", ""), "Credential issuer id")
+	flag.StringVar(&g.subject, "subject", env("SUBJECT", ""), "Credential subject")
+	flag.StringVar(&g.signingKey, "signing-key", env("SIGNING_KEY", ""), "Base64 Ed25519 key file signing registrations and admin calls")
+	flag.StringVar(&g.output, "o", env("OUTPUT", "table"), "Output format: table, json or yaml")
+	flag.DurationVar(&g.timeout, "timeout", 15*time.Second, "Timeout of every call except watch")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(g, flag.Args()); err != nil {
+		log.SetFlags(0)
+		log.Fatalf("seederctl: %v", err)
+	}
+}
+
+func run(g *globals, args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	group, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	name, rest := "", args[1:]
+	if _, single := group[""]; !single {
+		if len(rest) == 0 {
+			return fmt.Errorf("usage: seederctl %s %s", args[0], strings.Join(subcommands(group), "|"))
+		}
+		name, rest = rest[0], rest[1:]
+	}
+	cmd, ok := group[name]
+	if !ok {
+		return fmt.Errorf("unknown %s command %q", args[0], name)
+	}
+
+	p, err := newPrinter(g.output)
+	if err != nil {
+		return err
+	}
+	c, err := g.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if args[0] != "watch" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+	return cmd(ctx, c, p, rest)
+}
+
+func subcommands(group map[string]command) []string {
+	var names []string
+	for _, name := range []string{"register", "list", "drain", "state", "resolve", "reload"} {
+		if _, ok := group[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func statusCommand(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	st, err := c.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return p.print(st, func(w io.Writer) {
+		fmt.Fprintln(w, "SERVICE\tSTATUS")
+		for _, name := range []string{"server", mapper.Maps_ServiceDesc.ServiceName, registrypb.Registry_ServiceDesc.ServiceName} {
+			fmt.Fprintf(w, "%s\t%s\n", name, st.Services[name])
+		}
+		fmt.Fprintln(w, "\nREGION\tGATEWAYS\tAGENTS\tSEEDERS")
+		for _, r := range st.Regions {
+			var seeders []string
+			for _, s := range r.Seeders {
+				seeders = append(seeders, s.Name+"@"+s.SeedIP+":"+s.SeedPort)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", r.Name, r.Gateways, r.Agents, strings.Join(seeders, ","))
+		}
+	})
+}
+
+func reloadConfig(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	result, err := c.ReloadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	return p.print(result, func(w io.Writer) {
+		fmt.Fprintln(w, "KEY\tRESULT")
+		for _, key := range result.Applied {
+			fmt.Fprintf(w, "%s\tapplied\n", key)
+		}
+		for _, key := range result.Ignored {
+			fmt.Fprintf(w, "%s\tneeds a restart\n", key)
+		}
+	})
+}
+
+func auditCommand(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	after := fs.Uint64("after", 0, "Only entries after this sequence number")
+	limit := fs.Int("limit", 100, "Entries to return, up to 1000")
+	fs.Parse(args)
+
+	entries, err := c.Audit(ctx, *after, *limit)
+	if err != nil {
+		return err
+	}
+	return p.print(entries, func(w io.Writer) {
+		fmt.Fprintln(w, "SEQ	TIME	SUBJECT	PEER	METHOD	REGION	KEY")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Seq, e.Time.Format(time.RFC3339), e.Subject, e.Peer, e.Method, e.Region, e.Key)
+		}
+	})
+}
+
+// eventView is the JSON and YAML shape of an event, the same the seeder
+// sends.
+type eventView struct {
+	ID      string          `json:"id"`
+	Event   string          `json:"event"`
+	Region  string          `json:"region,omitempty"`
+	Key     string          `json:"key,omitempty"`
+	Time    *time.Time      `json:"time,omitempty"`
+	Gateway json.RawMessage `json:"gateway,omitempty"`
+	Agent   json.RawMessage `json:"agent,omitempty"`
+}
+
+func newEventView(ev client.Event) (eventView, error) {
+	view := eventView{ID: ev.ID, Event: ev.Name, Region: ev.Region, Key: ev.Key}
+	if !ev.Time.IsZero() {
+		view.Time = &ev.Time
+	}
+
+	var err error
+	switch {
+	case ev.Gateway != nil:
+		view.Gateway, err = jsonOptions.Marshal(ev.Gateway)
+	case ev.Agent != nil:
+		view.Agent, err = jsonOptions.Marshal(ev.Agent)
+	}
+	return view, err
+}
+
+func watchCommand(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	region := fs.String("region", "", "Only changes of this region")
+	resource := fs.String("resource", "", "gateway, agent or both comma separated")
+	since := fs.String("since", "", "Resume after this event id")
+	fs.Parse(args)
+
+	opts := client.WatchOptions{Region: *region, LastEventID: *since}
+	if *resource != "" {
+		opts.Resources = strings.Split(*resource, ",")
+	}
+
+	err := c.Watch(ctx, opts, func(ev client.Event) error {
+		view, err := newEventView(ev)
+		if err != nil {
+			return err
+		}
+		return p.stream(view, func(w io.Writer) {
+			if ev.Name == client.EventReset {
+				fmt.Fprintf(w, "%s\treset, list the registry again\n", ev.ID)
+				return
+			}
+			detail := ""
+			switch {
+			case ev.Gateway != nil:
+				detail = ev.Gateway.GatewayAddress + " " + ev.Gateway.State
+			case ev.Agent != nil:
+				detail = "gateway " + ev.Agent.GatewayId
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", ev.ID, ev.Time.Format(time.RFC3339), ev.Name, ev.Region, ev.Key, detail)
+		})
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+var jsonOptions = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+// printer writes results as a table, JSON or YAML.
+type printer struct {
+	format string
+	out    io.Writer
+}
+
+func newPrinter(format string) (*printer, error) {
+	switch format {
+	case "table", "json", "yaml":
+		return &printer{format: format, out: os.Stdout}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, use table, json or yaml", format)
+}
+
+// toJSON encodes protos with their proto field names, anything else with
+// encoding/json.
+func toJSON(v any) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return jsonOptions.Marshal(m)
+	}
+	return json.Marshal(v)
+}
+
+// protoList keeps the proto encoding for the items of a list.
+func protoList[T proto.Message](items []T) ([]json.RawMessage, error) {
+	list := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		data, err := jsonOptions.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, data)
+	}
+	return list, nil
+}
+
+// blockStyle drops the flow style the JSON input left on every node.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// toYAML goes through JSON so both formats use the same field names and
+// order.
+func toYAML(v any) ([]byte, error) {
+	data, err := toJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	blockStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// print renders v, calling table for the table format.
+func (p *printer) print(v any, table func(w io.Writer)) error {
+	switch p.format {
+	case "json":
+		data, err := toJSON(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.out, "%s\n", data)
+		return err
+	case "yaml":
+		data, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = p.out.Write(data)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+// stream renders one item of an unbounded list: a JSON object per line or
+// a YAML document.
+func (p *printer) stream(v any, line func(w io.Writer)) error {
+	switch p.format {
+	case "json":
+		data, err := toJSON(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.out, "%s\n", data)
+		return err
+	case "yaml":
+		data, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.out, "---\n%s", data)
+		return err
+	}
+	line(p.out)
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	"github.com/odio4u/memstore/seeder/pkg/client"
+	registrypb "github.com/odio4u/memstore/seeder/proto"
+)
+
+func registerGateway(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	fs := flag.NewFlagSet("gateway register", flag.ExitOnError)
+	req := &mapper.GatewayPutRequest{Capacity: &mapper.Capacity{}}
+	fs.StringVar(&req.Region, "region", "", "Region of the gateway")
+	fs.StringVar(&req.GatewayIp, "ip", "", "Gateway IP")
+	port := fs.Int("port", 0, "Gateway port")
+	wssPort := fs.Int("wss-port", 0, "Gateway websocket port")
+	cpu := fs.Int("cpu", 0, "Capacity: CPU")
+	memory := fs.Int("memory", 0, "Capacity: memory")
+	storage := fs.Int("storage", 0, "Capacity: storage")
+	bandwidth := fs.Int("bandwidth", 0, "Capacity: bandwidth")
+	fs.StringVar(&req.VerifiableCredHash, "cred", "", "Verifiable credential hash of the gateway")
+	fs.Parse(args)
+
+	req.GatewayPort = int32(*port)
+	req.WssPort = int32(*wssPort)
+	req.Capacity.Cpu = int32(*cpu)
+	req.Capacity.Memory = int32(*memory)
+	req.Capacity.Storage = int32(*storage)
+	req.Capacity.Bandwidth = int32(*bandwidth)
+
+	resp, err := c.RegisterGateway(ctx, req)
+	if err != nil {
+		return err
+	}
+	return p.print(resp, func(w io.Writer) {
+		fmt.Fprintln(w, "GATEWAY ID\tADDRESS\tPORT\tWSS PORT")
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", resp.GatewayId, resp.GatewayAddress, resp.GatewayPort, resp.WssPort)
+	})
+}
+
+func printGateways(p *printer, gateways []*registrypb.Gateway) error {
+	list, err := protoList(gateways)
+	if err != nil {
+		return err
+	}
+	return p.print(list, func(w io.Writer) {
+		fmt.Fprintln(w, "REGION\tGATEWAY ID\tADDRESS\tWSS PORT\tSTATE\tSUBJECT")
+		for _, g := range gateways {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", g.Region, g.GatewayId, g.GatewayAddress, g.WssPort, g.State, g.Subject)
+		}
+	})
+}
+
+func listGateways(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	fs := flag.NewFlagSet("gateway list", flag.ExitOnError)
+	req := &registrypb.ListGatewaysRequest{}
+	fs.StringVar(&req.Region, "region", "", "Only gateways of this region")
+	fs.StringVar(&req.GatewayIp, "ip", "", "Only gateways announcing this IP")
+	fs.Parse(args)
+
+	gateways, err := c.ListGateways(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printGateways(p, gateways)
+}
+
+func drainGateway(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	fs := flag.NewFlagSet("gateway drain", flag.ExitOnError)
+	region := fs.String("region", "", "Region of the gateway, needed when the id is not unique")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: seederctl gateway drain [-region r] <gateway id>")
+	}
+
+	gateway, err := c.DrainGateway(ctx, *region, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return printGateways(p, []*registrypb.Gateway{gateway})
+}
+
+func setGatewayState(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	fs := flag.NewFlagSet("gateway state", flag.ExitOnError)
+	region := fs.String("region", "", "Region of the gateway, needed when the id is not unique")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errors.New("usage: seederctl gateway state [-region r] <gateway id> active|draining|maintenance|offline")
+	}
+
+	gateway, err := c.SetGatewayState(ctx, *region, fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	return printGateways(p, []*registrypb.Gateway{gateway})
+}
+
+func registerAgent(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	fs := flag.NewFlagSet("agent register", flag.ExitOnError)
+	req := &mapper.AgentConnectionRequest{}
+	fs.StringVar(&req.Region, "region", "", "Region of the gateway")
+	fs.StringVar(&req.AgentDomain, "domain", "", "Agent domain")
+	fs.StringVar(&req.GatewayId, "gateway", "", "Gateway id to place the agent on")
+	fs.StringVar(&req.VerifiableCredHash, "cred", "", "Verifiable credential hash of the agent")
+	fs.Parse(args)
+
+	resp, err := c.RegisterAgent(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printAgent(p, resp)
+}
+
+func printAgent(p *printer, resp *mapper.AgentResponse) error {
+	return p.print(resp, func(w io.Writer) {
+		fmt.Fprintln(w, "AGENT DOMAIN\tGATEWAY ID\tADDRESS\tPORT\tWSS PORT")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n", resp.AgentDomain, resp.GatewayId, resp.GatewayAddress, resp.GatewayPort, resp.WssPort)
+	})
+}
+
+func resolveAgent(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	fs := flag.NewFlagSet("agent resolve", flag.ExitOnError)
+	region := fs.String("region", "", "Region of the agent")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: seederctl agent resolve -region r <agent domain>")
+	}
+
+	resp, err := c.ResolveAgent(ctx, *region, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return printAgent(p, resp)
+}
+
+func listAgents(ctx context.Context, c *client.Client, p *printer, args []string) error {
+	fs := flag.NewFlagSet("agent list", flag.ExitOnError)
+	req := &registrypb.ListAgentsRequest{}
+	fs.StringVar(&req.Region, "region", "", "Only agents of this region")
+	fs.StringVar(&req.GatewayId, "gateway", "", "Only agents on this gateway")
+	fs.StringVar(&req.VerifiableCredHash, "cred", "", "Only agents registered with this credential hash")
+	fs.StringVar(&req.DomainPrefix, "prefix", "", "Only domains starting with this prefix")
+	fs.Parse(args)
+
+	agents, err := c.ListAgents(ctx, req)
+	if err != nil {
+		return err
+	}
+	list, err := protoList(agents)
+	if err != nil {
+		return err
+	}
+	return p.print(list, func(w io.Writer) {
+		fmt.Fprintln(w, "REGION\tAGENT DOMAIN\tGATEWAY ID\tADDRESS\tSUBJECT")
+		for _, a := range agents {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", a.Region, a.AgentDomain, a.GatewayId, a.GatewayAddress, a.Subject)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/odio4u/memstore/seeder/audit"
+	"github.com/odio4u/memstore/seeder/pkg/config"
+	"github.com/odio4u/memstore/seeder/pkg/diag"
+	"github.com/odio4u/memstore/seeder/pkg/health"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	wal "github.com/odio4u/memstore/seeder/wal"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+	"google.golang.org/grpc"
+)
+
+// shutdown stops a running seeder in order: readiness first so no new work
+// arrives, then the servers, then the WAL. Every step runs even after an
+// earlier one failed.
+type shutdown struct {
+	config   config.Shutdown
+	snapshot bool
+
+	checker *health.Checker
+	grpc    *grpc.Server
+	viewer  *http.Server
+	// debug is nil without Debug.listen
+	debug  *diag.Server
+	waler  *wal.WALer
+	store  *memstore.MemStore
+	audit  *audit.Log
+	logger *slog.Logger
+}
+
+// run returns the failed steps joined, nil when everything stopped cleanly.
+func (s *shutdown) run() error {
+	start := time.Now()
+	var errs []error
+	step := func(name string, err error) {
+		if err != nil {
+			s.logger.Error("shutdown step failed", "step", name, "err", err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	s.checker.Shutdown()
+	s.logger.Info("shutting down, readiness reports NOT_SERVING", "delay", s.config.Delay, "timeout", s.config.Timeout)
+	time.Sleep(s.config.Delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	step("grpc", drainGRPC(ctx, s.grpc))
+	// the event streams are ended through RegisterOnShutdown
+	if err := s.viewer.Shutdown(ctx); err != nil {
+		s.viewer.Close()
+		step("viewer", err)
+	}
+	if s.debug != nil {
+		step("debug", s.debug.Shutdown(ctx))
+	}
+
+	// nothing appends any more, what is buffered is all there is
+	step("wal sync", s.waler.Sync(context.Background()))
+	if s.snapshot {
+		_, err := s.waler.Snapshot(context.Background(), func(emit func(*walpb.WalRecord) error) error {
+			return wal.Dump(s.store, emit)
+		})
+		step("snapshot", err)
+	}
+	step("wal close", s.waler.Close())
+	step("audit log", s.audit.Close())
+
+	s.logger.Info("shutdown complete", "duration", time.Since(start), "failed_steps", len(errs))
+	return errors.Join(errs...)
+}
+
+// drainGRPC waits for running calls until ctx is done, then cancels them.
+func drainGRPC(ctx context.Context, server *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		<-done
+		return errors.New("calls still running at the deadline were cancelled")
+	}
+}
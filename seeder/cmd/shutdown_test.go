@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	"github.com/odio4u/memstore/seeder/audit"
+	"github.com/odio4u/memstore/seeder/pkg/config"
+	"github.com/odio4u/memstore/seeder/pkg/health"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	wal "github.com/odio4u/memstore/seeder/wal"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// blockingMaps holds RegisterGateway calls until release is closed.
+type blockingMaps struct {
+	mapper.UnimplementedMapsServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingMaps) RegisterGateway(ctx context.Context, req *mapper.GatewayPutRequest) (*mapper.GatewayResponse, error) {
+	close(b.started)
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+	}
+	return &mapper.GatewayResponse{}, nil
+}
+
+type testSeeder struct {
+	*shutdown
+	maps   *blockingMaps
+	viewer string
+	// /block requests wait for viewerRelease, then report whether the WAL
+	// still takes appends
+	viewerStarted chan struct{}
+	viewerRelease chan struct{}
+	walOpen       chan bool
+}
+
+// newTestSeeder serves gRPC and a viewer with a gRPC call in flight.
+func newTestSeeder(t *testing.T, timeout time.Duration) *testSeeder {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+
+	waler, err := wal.OpenWAL(dir, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditLog, err := audit.Open(dir, []byte("audit key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := health.New(mapper.Maps_ServiceDesc.ServiceName)
+
+	blocking := &blockingMaps{started: make(chan struct{}), release: make(chan struct{})}
+	server := grpc.NewServer()
+	mapper.RegisterMapsServer(server, blocking)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(lis)
+
+	viewerLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := &testSeeder{
+		maps:          blocking,
+		viewer:        "http://" + viewerLis.Addr().String(),
+		viewerStarted: make(chan struct{}),
+		viewerRelease: make(chan struct{}),
+		walOpen:       make(chan bool, 1),
+	}
+	viewer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/block" {
+			return
+		}
+		close(ts.viewerStarted)
+		<-ts.viewerRelease
+		ts.walOpen <- waler.Append(context.Background(), &walpb.WalRecord{Op: walpb.Operation_OP_DELETE_GATEWAY}) == nil
+	})}
+	go viewer.Serve(viewerLis)
+
+	ts.shutdown = &shutdown{
+		config:  config.Shutdown{Timeout: timeout},
+		checker: checker,
+		grpc:    server,
+		viewer:  viewer,
+		waler:   waler,
+		store:   memstore.NewMemStore(logger),
+		audit:   auditLog,
+		logger:  logger,
+	}
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go mapper.NewMapsClient(conn).RegisterGateway(context.Background(), &mapper.GatewayPutRequest{})
+	<-blocking.started
+	return ts
+}
+
+func TestShutdownOrder(t *testing.T) {
+	s := newTestSeeder(t, time.Minute)
+	go func() {
+		if resp, err := http.Get(s.viewer + "/block"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-s.viewerStarted
+
+	done := make(chan error, 1)
+	go func() { done <- s.run() }()
+
+	// readiness fails first, while gRPC drains the viewer and the WAL stay up
+	deadline := time.Now().Add(5 * time.Second)
+	for s.checker.Serving() || s.checker.Status().Ready {
+		if time.Now().After(deadline) {
+			t.Fatal("readiness still passes")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	resp, err := http.Get(s.viewer)
+	if err != nil {
+		t.Fatalf("viewer stopped before gRPC drained: %v", err)
+	}
+	resp.Body.Close()
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown finished with a gRPC call running: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// then the viewer drains, the WAL is closed only after
+	close(s.maps.release)
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown finished with a viewer request running: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(s.viewerRelease)
+	if walOpen := <-s.walOpen; !walOpen {
+		t.Error("WAL closed while the viewer was draining")
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if _, err := http.Get(s.viewer); err == nil {
+		t.Error("viewer still serving after shutdown")
+	}
+	if err := s.waler.Append(context.Background(), &walpb.WalRecord{}); err == nil {
+		t.Error("WAL still open after shutdown")
+	}
+}
+
+func TestShutdownFailure(t *testing.T) {
+	// the gRPC call never finishes on its own
+	s := newTestSeeder(t, 50*time.Millisecond)
+
+	err := s.run()
+	if err == nil || !strings.HasPrefix(err.Error(), "grpc: ") {
+		t.Fatalf("run() error = %v, want the gRPC step", err)
+	}
+	// the later steps still ran
+	if _, err := http.Get(s.viewer); err == nil {
+		t.Error("viewer still serving after a failed shutdown")
+	}
+	if err := s.waler.Append(context.Background(), &walpb.WalRecord{}); err == nil {
+		t.Error("WAL still open after a failed shutdown")
+	}
+}
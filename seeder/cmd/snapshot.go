@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/odio4u/memstore/seeder/wal"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+)
+
+// backup moves path aside, keeping it for a manual rollback. A missing file
+// is skipped.
+func backup(path string, now time.Time) (string, error) {
+	dst := fmt.Sprintf("%s.bak-%d", path, now.Unix())
+	err := os.Rename(path, dst)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return dst, err
+}
+
+// snapshotCommand implements the `seeder snapshot` commands.
+func snapshotCommand(opts *options, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: seeder snapshot create|restore|inspect")
+	}
+
+	switch args[0] {
+	case "create":
+		fs, opts := opts.flags("snapshot create")
+		keyFile := keyFileFlag(fs)
+		out := fs.String("out", "", "Snapshot file to write, defaults to snapshot-<unix time>.log in the working directory")
+		if err := opts.parse(fs, args[1:]); err != nil {
+			return err
+		}
+
+		kr, err := opts.keyring(*keyFile)
+		if err != nil {
+			return err
+		}
+		store, err := loadStore(opts.dataDir, kr)
+		if err != nil {
+			return err
+		}
+
+		path := *out
+		if path == "" {
+			path = fmt.Sprintf("snapshot-%d.log", time.Now().Unix())
+		}
+		info, err := wal.WriteSnapshot(path, kr, func(emit func(*walpb.WalRecord) error) error {
+			return wal.Dump(store, emit)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		fmt.Printf("snapshot written to %s, %d records\n", info.Path, info.Records)
+
+	case "restore":
+		fs, opts := opts.flags("snapshot restore")
+		keyFile := keyFileFlag(fs)
+		if err := opts.parse(fs, args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New("usage: seeder snapshot restore [flags] <file>")
+		}
+		src := fs.Arg(0)
+
+		lock, err := wal.LockDir(opts.dataDir)
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
+		kr, err := opts.keyring(*keyFile)
+		if err != nil {
+			return err
+		}
+		// read it all before touching the data dir
+		if err := verifyFile(src, kr); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, path := range []string{wal.SnapshotFile(opts.dataDir), wal.File(opts.dataDir)} {
+			moved, err := backup(path, now)
+			if err != nil {
+				return err
+			}
+			if moved != "" {
+				fmt.Printf("%s moved to %s\n", path, moved)
+			}
+		}
+
+		// rewritten rather than copied, so it ends up under the active data key
+		info, err := wal.WriteSnapshot(wal.SnapshotFile(opts.dataDir), kr, func(emit func(*walpb.WalRecord) error) error {
+			_, err := wal.Scan(src, kr, func(e wal.Entry) error {
+				return emit(e.Record)
+			})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore snapshot: %w", err)
+		}
+		fmt.Printf("restored %d records to %s, the WAL starts empty\n", info.Records, info.Path)
+
+	case "inspect":
+		fs, opts := opts.flags("snapshot inspect")
+		keyFile := keyFileFlag(fs)
+		if err := opts.parse(fs, args[1:]); err != nil {
+			return err
+		}
+
+		path := wal.SnapshotFile(opts.dataDir)
+		if fs.NArg() > 0 {
+			path = fs.Arg(0)
+		}
+		kr, err := opts.keyring(*keyFile)
+		if err != nil {
+			return err
+		}
+		return inspectFile(path, kr)
+
+	default:
+		return fmt.Errorf("unknown snapshot command %q", args[0])
+	}
+	return nil
+}
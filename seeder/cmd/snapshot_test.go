@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/odio4u/memstore/seeder/wal"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+)
+
+func appendGateway(t *testing.T, dir, ip string) {
+	t.Helper()
+	w, err := wal.OpenWAL(dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	err = w.Append(context.Background(), &walpb.WalRecord{
+		Op: walpb.Operation_OP_PUT_GATEWAY,
+		Gateway: &walpb.GatewayPutRequest{
+			Region: "eu", GatewayIp: ip, GatewayPort: 7000, VerifiableCredHash: "h1",
+			Capacity: &walpb.Capacity{Cpu: 4},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func gatewayCount(t *testing.T, dir string) int {
+	t.Helper()
+	store, err := loadStore(dir, nil)
+	if err != nil {
+		t.Fatalf("loadStore() error = %v", err)
+	}
+	gateways, _ := store.Export()
+	return len(gateways)
+}
+
+func TestSnapshotCreateRestore(t *testing.T) {
+	dir := t.TempDir()
+	// no seeder-config.yaml in the working directory, the defaults apply
+	t.Chdir(t.TempDir())
+	opts := &options{dataDir: dir}
+	appendGateway(t, dir, "10.0.0.1")
+
+	out := filepath.Join(t.TempDir(), "backup.log")
+	if err := snapshotCommand(opts, []string{"create", "-out", out}); err != nil {
+		t.Fatalf("snapshot create error = %v", err)
+	}
+
+	// changes after the snapshot are lost by the restore
+	appendGateway(t, dir, "10.0.0.2")
+	if n := gatewayCount(t, dir); n != 2 {
+		t.Fatalf("%d gateways before the restore, want 2", n)
+	}
+
+	if err := snapshotCommand(opts, []string{"restore", out}); err != nil {
+		t.Fatalf("snapshot restore error = %v", err)
+	}
+	if n := gatewayCount(t, dir); n != 1 {
+		t.Errorf("%d gateways after the restore, want 1", n)
+	}
+	if _, err := os.Stat(wal.File(dir)); !os.IsNotExist(err) {
+		t.Errorf("the WAL survived the restore: %v", err)
+	}
+	backups, _ := filepath.Glob(wal.File(dir) + ".bak-*")
+	if len(backups) != 1 {
+		t.Errorf("WAL backups = %v, want one", backups)
+	}
+
+	if err := snapshotCommand(opts, []string{"restore", filepath.Join(dir, "missing.log")}); err == nil {
+		t.Error("restoring a missing file succeeded")
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/wal"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+)
+
+// keyring loads the WAL keyring for an offline command from keyFile, or
+// from the config when keyFile is empty. Without either the WAL is taken to
+// be plaintext.
+func (o *options) keyring(keyFile string) (*wal.Keyring, error) {
+	if keyFile == "" {
+		keyFile = o.cfg.WAL.KeyFile
+	}
+	if keyFile == "" {
+		return nil, nil
+	}
+	return wal.LoadKeyring(keyFile, o.dataDir)
+}
+
+// keyFileFlag adds -key-file to an offline command.
+func keyFileFlag(fs *flag.FlagSet) *string {
+	return fs.String("key-file", "", "Key-encryption key file, defaults to WAL.key_file of the config")
+}
+
+// loadStore rebuilds the store from the snapshot and the WAL in dir, like
+// a start does, without opening either for writing.
+func loadStore(dir string, kr *wal.Keyring) (*memstore.MemStore, error) {
+	store := memstore.NewMemStore(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, path := range []string{wal.SnapshotFile(dir), wal.File(dir)} {
+		_, err := wal.Scan(path, kr, func(e wal.Entry) error {
+			err := wal.ApplyRecord(store, e.Record)
+			if errors.Is(err, wal.ErrSkipped) {
+				fmt.Fprintf(os.Stderr, "%s: %s at offset %d: %v\n", path, e.Record.Op, e.Offset, err)
+				return nil
+			}
+			return err
+		})
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return store, nil
+}
+
+func describe(rec *walpb.WalRecord) (region, key, detail string) {
+	switch {
+	case rec.Gateway != nil:
+		return rec.Gateway.Region, rec.Gateway.GatewayId, rec.Gateway.GatewayAddress
+	case rec.Agent != nil:
+		return rec.Agent.Region, rec.Agent.AgentDomain, "gateway " + rec.Agent.GatewayId
+	case rec.GatewayRef != nil:
+		detail := ""
+		if rec.Op == walpb.Operation_OP_SET_GATEWAY_STATE {
+			detail = string(wal.GatewayState(rec.GatewayRef.State))
+		}
+		return rec.GatewayRef.Region, rec.GatewayRef.GatewayId, detail
+	}
+	return "", "", ""
+}
+
+// inspectFile prints one line per record of a WAL formatted file and a
+// count per operation.
+func inspectFile(path string, kr *wal.Keyring) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "OFFSET\tOP\tSIZE\tKEY ID\tREGION\tKEY\tDETAIL")
+
+	counts := make(map[walpb.Operation]int)
+	total := 0
+	good, err := wal.Scan(path, kr, func(e wal.Entry) error {
+		region, key, detail := describe(e.Record)
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%d\t%s\t%s\t%s\n", e.Offset, e.Record.Op, e.Size, e.KeyID, region, key, detail)
+		counts[e.Record.Op]++
+		total++
+		return nil
+	})
+	tw.Flush()
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("\n%s: missing\n", path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: stopped at offset %d after %d records: %w", path, good, total, err)
+	}
+
+	fmt.Printf("\n%s: %d records, %d bytes\n", path, total, good)
+	for i := range len(walpb.Operation_name) {
+		op := walpb.Operation(i)
+		if n := counts[op]; n > 0 {
+			fmt.Printf("  %-24s %d\n", op, n)
+		}
+	}
+	return nil
+}
+
+// verifyFile reads every record of a WAL formatted file. A missing file is
+// fine, the seeder creates it.
+func verifyFile(path string, kr *wal.Keyring) error {
+	records := 0
+	good, err := wal.Scan(path, kr, func(wal.Entry) error {
+		records++
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("%s: missing\n", path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: damaged at offset %d after %d records: %w", path, good, records, err)
+	}
+	fmt.Printf("%s: %d records ok\n", path, records)
+	return nil
+}
+
+// walCommand implements the `seeder wal` commands.
+func walCommand(opts *options, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: seeder wal keygen|rekey|inspect|verify|repair|compact")
+	}
+
+	switch args[0] {
+	case "keygen":
+		if len(args) < 2 {
+			return errors.New("usage: seeder wal keygen <path>")
+		}
+		if err := wal.GenerateKeyFile(args[1]); err != nil {
+			return fmt.Errorf("failed to generate key file: %w", err)
+		}
+		fmt.Printf("key file written to %s\n", args[1])
+
+	case "rekey":
+		fs, opts := opts.flags("wal rekey")
+		keyFile := fs.String("key-file", "", "Current key-encryption key file")
+		newKeyFile := fs.String("new-key-file", "", "Optional new key-encryption key file")
+		if err := opts.parse(fs, args[1:]); err != nil {
+			return err
+		}
+
+		if *keyFile == "" {
+			return errors.New("usage: seeder wal rekey -key-file <path> [-new-key-file <path>]")
+		}
+		if err := wal.Rekey(opts.dataDir, *keyFile, *newKeyFile); err != nil {
+			return fmt.Errorf("rekey failed: %w", err)
+		}
+		fmt.Println("WAL rewritten under a new data key")
+
+	case "inspect":
+		fs, opts := opts.flags("wal inspect")
+		keyFile := keyFileFlag(fs)
+		if err := opts.parse(fs, args[1:]); err != nil {
+			return err
+		}
+
+		kr, err := opts.keyring(*keyFile)
+		if err != nil {
+			return err
+		}
+		return inspectFile(wal.File(opts.dataDir), kr)
+
+	case "verify":
+		fs, opts := opts.flags("wal verify")
+		keyFile := keyFileFlag(fs)
+		if err := opts.parse(fs, args[1:]); err != nil {
+			return err
+		}
+
+		kr, err := opts.keyring(*keyFile)
+		if err != nil {
+			return err
+		}
+		if err := verifyFile(wal.SnapshotFile(opts.dataDir), kr); err != nil {
+			return err
+		}
+		return verifyFile(wal.File(opts.dataDir), kr)
+
+	case "repair":
+		fs, opts := opts.flags("wal repair")
+		keyFile := keyFileFlag(fs)
+		if err := opts.parse(fs, args[1:]); err != nil {
+			return err
+		}
+
+		lock, err := wal.LockDir(opts.dataDir)
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
+		kr, err := opts.keyring(*keyFile)
+		if err != nil {
+			return err
+		}
+		path := wal.File(opts.dataDir)
+		res, err := wal.Repair(path, kr)
+		if err != nil {
+			return fmt.Errorf("repair failed: %w", err)
+		}
+		if res.Backup == "" {
+			fmt.Printf("%s: %d records ok, nothing to repair\n", path, res.Records)
+			return nil
+		}
+		fmt.Printf("%s: kept %d records (%d bytes), dropped %d bytes, original saved as %s\n",
+			path, res.Records, res.Kept, res.Dropped, res.Backup)
+
+	case "compact":
+		fs, opts := opts.flags("wal compact")
+		keyFile := keyFileFlag(fs)
+		if err := opts.parse(fs, args[1:]); err != nil {
+			return err
+		}
+
+		lock, err := wal.LockDir(opts.dataDir)
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
+		kr, err := opts.keyring(*keyFile)
+		if err != nil {
+			return err
+		}
+		store, err := loadStore(opts.dataDir, kr)
+		if err != nil {
+			return fmt.Errorf("cannot compact, run `seeder wal repair` first: %w", err)
+		}
+
+		w, err := wal.OpenWAL(opts.dataDir, kr, nil)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+
+		info, err := w.Snapshot(context.Background(), func(emit func(*walpb.WalRecord) error) error {
+			return wal.Dump(store, emit)
+		})
+		if err != nil {
+			return fmt.Errorf("compact failed: %w", err)
+		}
+		fmt.Printf("WAL folded into %s, %d records\n", info.Path, info.Records)
+
+	default:
+		return fmt.Errorf("unknown wal command %q", args[0])
+	}
+	return nil
+}
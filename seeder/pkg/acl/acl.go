@@ -0,0 +1,52 @@
+package acl
+
+// Wildcard matches any verified subject in Rule.Subject, or any method in
+// Rule.Methods.
+const Wildcard = "*"
+
+// Rule grants a verified subject the listed methods. Methods are the gRPC
+// method names, such as RegisterGateway, and the admin operations of the
+// HTTP API, such as DrainGateway.
+type Rule struct {
+	Subject string   `yaml:"subject"`
+	Methods []string `yaml:"methods"`
+}
+
+// ACL is shared by the gRPC handlers and the HTTP admin API.
+type ACL struct {
+	rules map[string]map[string]bool
+}
+
+// New builds an ACL. Without rules every verified subject may call every
+// method, which is how registrations behaved before ACLs existed.
+func New(rules []Rule) *ACL {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	a := &ACL{rules: make(map[string]map[string]bool, len(rules))}
+	for _, r := range rules {
+		methods := a.rules[r.Subject]
+		if methods == nil {
+			methods = make(map[string]bool, len(r.Methods))
+			a.rules[r.Subject] = methods
+		}
+		for _, m := range r.Methods {
+			methods[m] = true
+		}
+	}
+	return a
+}
+
+// Allow reports whether subject may call method. A nil ACL allows everything.
+func (a *ACL) Allow(subject, method string) bool {
+	if a == nil {
+		return true
+	}
+	for _, s := range []string{subject, Wildcard} {
+		if methods := a.rules[s]; methods[method] || methods[Wildcard] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package acl
+
+import "testing"
+
+func TestAllow(t *testing.T) {
+	a := New([]Rule{
+		{Subject: "alice", Methods: []string{"RegisterAgent", "DrainGateway"}},
+		{Subject: "alice", Methods: []string{"DeleteGateway"}},
+		{Subject: "ops", Methods: []string{Wildcard}},
+		{Subject: Wildcard, Methods: []string{"RegisterGateway"}},
+	})
+	tests := []struct {
+		subject string
+		method  string
+		want    bool
+	}{
+		{"alice", "RegisterAgent", true},
+		{"alice", "DeleteGateway", true},
+		{"alice", "ReassignAgent", false},
+		{"ops", "ReassignAgent", true},
+		{"bob", "RegisterGateway", true},
+		{"bob", "RegisterAgent", false},
+	}
+	for _, tt := range tests {
+		if got := a.Allow(tt.subject, tt.method); got != tt.want {
+			t.Errorf("Allow(%q, %q) = %v, want %v", tt.subject, tt.method, got, tt.want)
+		}
+	}
+
+	if !New(nil).Allow("anyone", "DeleteGateway") {
+		t.Error("an ACL without rules refused a call")
+	}
+}
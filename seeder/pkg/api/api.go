@@ -2,35 +2,140 @@ package api
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"sync"
 
 	"github.com/gorilla/mux"
+	"github.com/odio4u/memstore/seeder/audit"
+	"github.com/odio4u/memstore/seeder/pkg/health"
+	"github.com/odio4u/memstore/seeder/pkg/maps"
 	"github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/metrics"
 )
 
 type Api struct {
 	memstore *memstore.MemStore
+	audit    *audit.Log
+	health   *health.Checker
+	registry *maps.RPCRegistry
+	admin    *maps.Admin
+	logger   *slog.Logger
+	// closing ends the event streams on shutdown
+	closing   chan struct{}
+	closeOnce sync.Once
 }
 
-func NewApi(memstore *memstore.MemStore) *Api {
+func NewApi(memstore *memstore.MemStore, auditLog *audit.Log, checker *health.Checker, registry *maps.RPCRegistry, admin *maps.Admin, logger *slog.Logger) *Api {
 	return &Api{
 		memstore: memstore,
+		audit:    auditLog,
+		health:   checker,
+		registry: registry,
+		admin:    admin,
+		logger:   logger.With("component", "api"),
+		closing:  make(chan struct{}),
 	}
 }
 
+// CloseStreams ends every event stream, which would otherwise keep an HTTP
+// shutdown waiting until its deadline. Clients reconnect and resume.
+func (a *Api) CloseStreams() {
+	a.closeOnce.Do(func() { close(a.closing) })
+}
+
 func SetRoutes(router *mux.Router, api *Api) {
 	router.HandleFunc("/seeder", api.SeederView).Methods("GET")
+	router.HandleFunc("/audit", api.AuditView).Methods("GET")
+	router.HandleFunc("/healthz", api.Healthz).Methods("GET")
+	router.HandleFunc("/readyz", api.Readyz).Methods("GET")
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+	router.Handle("/events", api.requireReady(http.HandlerFunc(api.Events))).Methods("GET")
+	setV1Routes(router, api)
+	setDashboardRoutes(router)
 }
 
 func (a *Api) SeederView(w http.ResponseWriter, r *http.Request) {
-	log.Println(r.URL.Query())
 	region := r.URL.Query().Get("region")
 
 	seederData := a.memstore.GetSeeders(region)
-	response, _ := json.Marshal(seederData)
+	writeJSON(w, http.StatusOK, seederData)
+}
+
+// redactedAuditFields are dropped from the values of audit entries served
+// over HTTP, the log itself keeps them.
+var redactedAuditFields = []string{"VerifiableHash"}
+
+// AuditView pages through the audit log. Reading it is an admin call
+// signed over the after and limit parameters as given.
+func (a *Api) AuditView(w http.ResponseWriter, r *http.Request) {
+	if a.audit == nil {
+		http.Error(w, "audit log disabled", http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	if _, err := a.authorize(r, "ReadAudit", q.Get("after"), q.Get("limit")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var after uint64
+	if v := q.Get("after"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid after", http.StatusBadRequest)
+			return
+		}
+		after = n
+	}
+
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			http.Error(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	entries, err := a.audit.Page(after, limit)
+	if err != nil {
+		a.logger.Error("failed to read audit entries", "after", after, "err", err)
+		http.Error(w, "failed to read audit log", http.StatusInternalServerError)
+		return
+	}
+
+	for _, e := range entries {
+		e.Old, e.New = redact(e.Old), redact(e.New)
+	}
+
+	response, err := json.Marshal(entries)
+	if err != nil {
+		http.Error(w, "failed to encode audit entries", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(200)
 	w.Write(response)
 }
+
+// redact drops redactedAuditFields from a recorded value. Values that are
+// not objects are kept as they are.
+func redact(raw json.RawMessage) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if len(raw) == 0 || json.Unmarshal(raw, &fields) != nil {
+		return raw
+	}
+	for _, name := range redactedAuditFields {
+		delete(fields, name)
+	}
+	redacted, err := json.Marshal(fields)
+	if err != nil {
+		return raw
+	}
+	return redacted
+}
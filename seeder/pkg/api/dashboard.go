@@ -0,0 +1,33 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// dashboardFiles is the single-page dashboard. It only loads its own files,
+// so it works without network access.
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// dashboardCSP keeps every script, style and request on this origin.
+const dashboardCSP = "default-src 'self'; img-src 'self' data:; frame-ancestors 'none'"
+
+func setDashboardRoutes(router *mux.Router) {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+	static := http.StripPrefix("/dashboard/", http.FileServer(http.FS(files)))
+
+	router.PathPrefix("/dashboard/").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", dashboardCSP)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		static.ServeHTTP(w, r)
+	})).Methods("GET", "HEAD")
+	router.Handle("/", http.RedirectHandler("/dashboard/", http.StatusFound)).Methods("GET")
+}
@@ -0,0 +1,384 @@
+// Agni Seeder dashboard. Reads the /v1 API and /seeder, refreshes on
+// /events, and signs admin actions, and reads once signed in, with an
+// issuer key held in memory.
+"use strict";
+
+const views = ["regions", "gateways", "seeders", "storage"];
+const state = { regions: [], region: "", credential: null, expanded: new Set() };
+const gatewayStates = ["active", "draining", "maintenance", "offline"];
+
+// el builds an element; strings become text nodes, never markup.
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) {
+    if (k.startsWith("on")) node.addEventListener(k.slice(2), v);
+    else if (v !== false && v != null) node.setAttribute(k, v === true ? "" : v);
+  }
+  for (const child of children.flat()) {
+    if (child == null) continue;
+    node.append(child instanceof Node ? child : document.createTextNode(String(child)));
+  }
+  return node;
+}
+
+function showError(err) {
+  const box = document.getElementById("error");
+  box.textContent = err ? String(err.message || err) : "";
+  box.hidden = !err;
+}
+
+async function request(method, path, { headers, body } = {}) {
+  const resp = await fetch(path, {
+    method,
+    headers: { ...(body ? { "Content-Type": "application/json" } : {}), ...headers },
+    body: body ? JSON.stringify(body) : undefined,
+  });
+  const data = await resp.json().catch(() => null);
+  if (!resp.ok) {
+    const e = data && data.error;
+    throw new Error(e ? `${e.status}: ${e.message}` : `${resp.status} ${resp.statusText}`);
+  }
+  return data;
+}
+
+// listAll follows next_page_token until the last page.
+async function listAll(path, field) {
+  const items = [];
+  let token = "";
+  do {
+    const sep = path.includes("?") ? "&" : "?";
+    const page = await request("GET", `${path}${sep}page_size=1000&page_token=${encodeURIComponent(token)}`, {
+      headers: await readHeaders(),
+    });
+    items.push(...(page[field] || []));
+    token = page.next_page_token || "";
+  } while (token);
+  return items;
+}
+
+// --- admin credential ---
+
+const pkcs8Prefix = [0x30, 0x2e, 0x02, 0x01, 0x00, 0x30, 0x05, 0x06, 0x03, 0x2b, 0x65, 0x70, 0x04, 0x22, 0x04, 0x20];
+
+function fromBase64(s) {
+  return Uint8Array.from(atob(s.trim()), (c) => c.charCodeAt(0));
+}
+
+function toBase64(buf) {
+  return btoa(String.fromCharCode(...new Uint8Array(buf)));
+}
+
+// importKey accepts a 32 byte seed or a 64 byte Go ed25519.PrivateKey.
+async function importKey(encoded) {
+  const raw = fromBase64(encoded);
+  if (raw.length !== 32 && raw.length !== 64) {
+    throw new Error("private key must be a base64 32 byte seed or 64 byte key");
+  }
+  const der = new Uint8Array([...pkcs8Prefix, ...raw.slice(0, 32)]);
+  return crypto.subtle.importKey("pkcs8", der, { name: "Ed25519" }, false, ["sign"]);
+}
+
+// signedHeaders mirrors identity.SigningPayload: version, method, issuer,
+// subject, timestamp, nonce and the request fields, newline separated.
+// The seeder refuses values holding a newline.
+async function signedHeaders(method, ...fields) {
+  const cred = state.credential;
+  if (!cred) throw new Error("sign in with an admin credential first");
+  if ([cred.issuer, cred.subject, ...fields].some((v) => String(v).includes("\n"))) {
+    throw new Error("signed values must not contain a newline");
+  }
+
+  const timestamp = String(Math.floor(Date.now() / 1000));
+  const nonce = Array.from(crypto.getRandomValues(new Uint8Array(16)), (b) => b.toString(16).padStart(2, "0")).join("");
+  const payload = ["agni-v1", method, cred.issuer, cred.subject, timestamp, nonce, ...fields].join("\n");
+  const signature = await crypto.subtle.sign({ name: "Ed25519" }, cred.key, new TextEncoder().encode(payload));
+
+  return {
+    "X-Agni-Issuer": cred.issuer,
+    "X-Agni-Subject": cred.subject,
+    "X-Agni-Timestamp": timestamp,
+    "X-Agni-Nonce": nonce,
+    "X-Agni-Signature": toBase64(signature),
+  };
+}
+
+// readHeaders signs a registry read once signed in. Seeders without
+// identity issuers serve reads unsigned.
+async function readHeaders() {
+  return state.credential ? signedHeaders("ReadRegistry") : {};
+}
+
+function updateAuth() {
+  const cred = state.credential;
+  document.getElementById("auth-state").textContent = cred
+    ? `Signing as ${cred.subject} (issuer ${cred.issuer}).`
+    : "No credential, actions are disabled.";
+  for (const button of document.querySelectorAll("button.admin")) button.disabled = !cred;
+}
+
+async function admin(action) {
+  try {
+    showError(null);
+    await action();
+    await refresh();
+  } catch (err) {
+    showError(err);
+  }
+}
+
+// --- views ---
+
+async function loadRegions() {
+  state.regions = await listAll("/v1/regions", "regions");
+  if (!state.region && state.regions.length) state.region = state.regions[0].name;
+
+  document.getElementById("regions").replaceChildren(...state.regions.map((r) =>
+    el("tr", {},
+      el("td", {}, el("a", { href: "#gateways", onclick: () => { state.region = r.name; } }, r.name)),
+      el("td", {}, r.gateway_count),
+      el("td", {}, r.agent_count),
+      el("td", {}, r.seeder_count))));
+
+  document.getElementById("gateway-region").replaceChildren(...state.regions.map((r) =>
+    el("option", { value: r.name, selected: r.name === state.region }, r.name)));
+}
+
+function agentRows(region, gateway, agents, gateways) {
+  const targets = gateways.filter((g) => g.gateway_id !== gateway.gateway_id && g.state === "active");
+  return agents.map((a) => {
+    const target = el("select", {}, targets.map((g) => el("option", { value: g.gateway_id }, g.gateway_address)));
+    return el("tr", { class: "agents" },
+      el("td", { colspan: 2 }, a.agent_domain),
+      el("td", { class: "id", colspan: 5 }, a.agent_id),
+      el("td", { colspan: 2 },
+        target,
+        el("button", {
+          class: "admin",
+          disabled: !targets.length,
+          onclick: () => admin(async () => {
+            const gatewayID = target.value;
+            await request("POST", `/v1/agents/${encodeURIComponent(a.agent_domain)}/reassign?region=${encodeURIComponent(region)}`, {
+              headers: await signedHeaders("ReassignAgent", region, a.agent_domain, gatewayID),
+              body: { gateway_id: gatewayID },
+            });
+          }),
+        }, "Reassign")));
+  });
+}
+
+async function loadGateways() {
+  const region = state.region;
+  if (!region) {
+    document.getElementById("gateways").replaceChildren();
+    return;
+  }
+
+  const q = `region=${encodeURIComponent(region)}`;
+  const [gateways, agents] = await Promise.all([
+    listAll(`/v1/gateways?${q}`, "gateways"),
+    listAll(`/v1/agents?${q}`, "agents"),
+  ]);
+
+  const byGateway = new Map();
+  for (const a of agents) {
+    if (!byGateway.has(a.gateway_id)) byGateway.set(a.gateway_id, []);
+    byGateway.get(a.gateway_id).push(a);
+  }
+
+  const rows = [];
+  for (const g of gateways) {
+    const assigned = byGateway.get(g.gateway_id) || [];
+    const path = `/v1/gateways/${encodeURIComponent(g.gateway_id)}?${q}`;
+    const expanded = state.expanded.has(g.gateway_id);
+    const cap = g.capacity || {};
+    const next = el("select", {}, gatewayStates.map((s) => el("option", { value: s, selected: s === g.state }, s)));
+
+    rows.push(el("tr", {},
+      el("td", { class: "id", title: g.gateway_id }, g.gateway_id),
+      el("td", {}, g.gateway_address),
+      el("td", { class: `state-${g.state}` }, g.state),
+      el("td", {}, cap.cpu), el("td", {}, cap.memory), el("td", {}, cap.storage), el("td", {}, cap.bandwidth),
+      el("td", {}, el("button", {
+        type: "button",
+        disabled: !assigned.length,
+        onclick: () => {
+          if (expanded) state.expanded.delete(g.gateway_id);
+          else state.expanded.add(g.gateway_id);
+          loadGateways().catch(showError);
+        },
+      }, `${assigned.length} ${expanded ? "▾" : "▸"}`)),
+      el("td", {},
+        el("button", {
+          class: "admin",
+          disabled: g.state !== "active",
+          onclick: () => admin(async () => {
+            const path = `/v1/gateways/${encodeURIComponent(g.gateway_id)}/drain?${q}`;
+            await request("POST", path, { headers: await signedHeaders("DrainGateway", region, g.gateway_id) });
+          }),
+        }, "Drain"),
+        next,
+        el("button", {
+          class: "admin",
+          onclick: () => admin(async () => {
+            const path = `/v1/gateways/${encodeURIComponent(g.gateway_id)}/state?${q}`;
+            await request("POST", path, {
+              headers: await signedHeaders("SetGatewayState", region, g.gateway_id, next.value),
+              body: { state: next.value },
+            });
+          }),
+        }, "Set state"),
+        el("button", {
+          class: "admin",
+          disabled: assigned.length > 0,
+          title: assigned.length ? "reassign its agents first" : "",
+          onclick: () => admin(async () => {
+            if (!confirm(`Delete gateway ${g.gateway_address}?`)) return;
+            await request("DELETE", path, { headers: await signedHeaders("DeleteGateway", region, g.gateway_id) });
+          }),
+        }, "Delete"))));
+
+    if (expanded) rows.push(...agentRows(region, g, assigned, gateways));
+  }
+  document.getElementById("gateways").replaceChildren(...rows);
+}
+
+async function loadSeeders() {
+  const lists = await Promise.all(state.regions.map((r) => request("GET", `/seeder?region=${encodeURIComponent(r.name)}`)));
+  document.getElementById("seeders").replaceChildren(...lists.flat().filter(Boolean).map((s) =>
+    el("tr", {},
+      el("td", {}, s.Name),
+      el("td", {}, s.Region),
+      el("td", {}, `${s.SeedIP}:${s.SeedPort}`),
+      el("td", {}, s.Dns),
+      el("td", { class: "id", title: s.VerifiableHash }, s.VerifiableHash))));
+}
+
+async function loadStorage() {
+  const st = await request("GET", "/v1/wal", { headers: await readHeaders() });
+  const snap = st.snapshot;
+  const rows = [
+    ["WAL file", st.path],
+    ["WAL size", `${st.size_bytes} bytes`],
+    ["Appended since start", st.appended_records],
+    ["Encrypted", st.encrypted ? `yes, key ${st.active_key_id}` : "no"],
+    ["Last error", st.last_error || "none"],
+    ["Snapshot", snap ? snap.path : "none"],
+  ];
+  if (snap) {
+    rows.push(["Snapshot time", new Date(snap.time).toLocaleString()]);
+    rows.push(["Snapshot size", `${snap.size_bytes} bytes, ${snap.records} records`]);
+  }
+  document.getElementById("wal").replaceChildren(...rows.flatMap(([k, v]) => [el("dt", {}, k), el("dd", {}, v)]));
+}
+
+async function refresh() {
+  const view = current();
+  await loadRegions();
+  if (view === "gateways") await loadGateways();
+  if (view === "seeders") await loadSeeders();
+  if (view === "storage") await loadStorage();
+  updateAuth();
+}
+
+function current() {
+  const name = location.hash.slice(1);
+  return views.includes(name) ? name : "regions";
+}
+
+function route() {
+  const view = current();
+  for (const name of views) document.getElementById(`view-${name}`).hidden = name !== view;
+  for (const a of document.querySelectorAll("nav a")) a.classList.toggle("active", a.hash === `#${view}`);
+  refresh().then(() => showError(null), showError);
+}
+
+// live refreshes on registry changes, batching bursts into one reload.
+// EventSource cannot send the signed headers, so the stream is read with
+// fetch and reopened with a fresh signature and Last-Event-ID.
+function live() {
+  const badge = document.getElementById("live");
+  let pending = null;
+  let lastID = "";
+
+  const reload = () => {
+    if (pending) return;
+    pending = setTimeout(() => {
+      pending = null;
+      refresh().catch(showError);
+    }, 500);
+  };
+
+  const connect = async () => {
+    const headers = await readHeaders();
+    if (lastID) headers["Last-Event-ID"] = lastID;
+    const resp = await fetch("/events", { headers });
+    if (!resp.ok) throw new Error(`${resp.status} ${resp.statusText}`);
+    badge.textContent = "live";
+    badge.classList.add("on");
+
+    const reader = resp.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buffered = "";
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) return;
+      buffered += value;
+      const events = buffered.split("\n\n");
+      buffered = events.pop();
+      for (const ev of events) {
+        const id = ev.split("\n").find((line) => line.startsWith("id: "));
+        if (id) lastID = id.slice(4);
+        if (!ev.startsWith(":")) reload();
+      }
+    }
+  };
+
+  const run = () => connect()
+    .catch(() => {})
+    .then(() => {
+      badge.textContent = "offline";
+      badge.classList.remove("on");
+      setTimeout(run, 3000);
+    });
+  run();
+}
+
+document.getElementById("auth-toggle").addEventListener("click", () => {
+  const auth = document.getElementById("auth");
+  auth.hidden = !auth.hidden;
+});
+
+document.getElementById("auth-form").addEventListener("submit", async (e) => {
+  e.preventDefault();
+  const form = e.target;
+  try {
+    if (!window.isSecureContext || !crypto.subtle) throw new Error("signing needs HTTPS or localhost");
+    state.credential = {
+      issuer: form.issuer.value.trim(),
+      subject: form.subject.value.trim(),
+      key: await importKey(form.key.value),
+    };
+    form.key.value = "";
+    showError(null);
+  } catch (err) {
+    showError(err);
+  }
+  updateAuth();
+});
+
+document.getElementById("auth-clear").addEventListener("click", () => {
+  state.credential = null;
+  updateAuth();
+});
+
+document.getElementById("gateway-region").addEventListener("change", (e) => {
+  state.region = e.target.value;
+  loadGateways().catch(showError);
+});
+
+document.getElementById("snapshot").addEventListener("click", () => admin(async () => {
+  await request("POST", "/v1/snapshots", { headers: await signedHeaders("CreateSnapshot") });
+}));
+
+window.addEventListener("hashchange", route);
+route();
+live();
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestDashboard(t *testing.T) {
+	router := mux.NewRouter()
+	setDashboardRoutes(router)
+
+	tests := []struct {
+		path        string
+		code        int
+		contentType string
+	}{
+		{"/dashboard/", 200, "text/html"},
+		{"/dashboard/app.js", 200, "text/javascript"},
+		{"/dashboard/style.css", 200, "text/css"},
+		{"/dashboard/missing.js", 404, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d", tt.path, rec.Code, tt.code)
+			continue
+		}
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.contentType) {
+			t.Errorf("%s: content type = %q, want %s", tt.path, got, tt.contentType)
+		}
+		if got := rec.Header().Get("Content-Security-Policy"); got != dashboardCSP {
+			t.Errorf("%s: CSP = %q", tt.path, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/dashboard/" {
+		t.Errorf("/ = %d to %q, want a redirect to the dashboard", rec.Code, rec.Header().Get("Location"))
+	}
+}
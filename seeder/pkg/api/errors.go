@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	rpccode "google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// httpStatus follows the mapping grpc-gateway uses, so a code means the
+// same thing on both transports.
+var httpStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.Aborted:            http.StatusConflict,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Canceled:           499,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+}
+
+var jsonOptions = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+type errorBody struct {
+	Error errorStatus `json:"error"`
+}
+
+type errorStatus struct {
+	Code    int               `json:"code"`
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	response, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, rpcerr.Internal("failed to encode response: "+err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
+
+func writeProto(w http.ResponseWriter, code int, m proto.Message) {
+	response, err := jsonOptions.Marshal(m)
+	if err != nil {
+		writeError(w, rpcerr.Internal("failed to encode response: "+err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
+
+// statusOf is the status err travels as. Catalogue errors and gRPC
+// statuses keep theirs, request bodies that cannot be read or decoded are
+// the caller's fault and everything else is internal.
+func statusOf(err error) *status.Status {
+	var e *rpcerr.Error
+	if errors.As(err, &e) {
+		return e.GRPCStatus()
+	}
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+
+	var (
+		tooLarge  *http.MaxBytesError
+		syntax    *json.SyntaxError
+		fieldType *json.UnmarshalTypeError
+	)
+	if errors.As(err, &tooLarge) || errors.As(err, &syntax) || errors.As(err, &fieldType) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return rpcerr.InvalidArgument("invalid request body: " + err.Error()).GRPCStatus()
+	}
+	return rpcerr.Internal(err.Error()).GRPCStatus()
+}
+
+// writeError renders err as a JSON error body. Details are the same
+// google.rpc messages a gRPC client finds in the status.
+func writeError(w http.ResponseWriter, err error) {
+	st := statusOf(err)
+
+	code, ok := httpStatus[st.Code()]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+
+	body := errorBody{Error: errorStatus{
+		Code:    code,
+		Status:  rpccode.Code(st.Code()).String(),
+		Message: st.Message(),
+	}}
+	for _, detail := range st.Proto().Details {
+		raw, err := protojson.Marshal(detail)
+		if err == nil {
+			body.Error.Details = append(body.Error.Details, raw)
+		}
+	}
+
+	response, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/odio4u/memstore/seeder/pkg/maps"
+	"github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+)
+
+const keepaliveInterval = 15 * time.Second
+
+type eventBody struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`
+	Resource string          `json:"resource"`
+	Region   string          `json:"region"`
+	Key      string          `json:"key"`
+	Time     time.Time       `json:"time"`
+	Gateway  json.RawMessage `json:"gateway,omitempty"`
+	Agent    json.RawMessage `json:"agent,omitempty"`
+}
+
+type eventFilter struct {
+	region    string
+	resources map[memstore.Resource]bool
+}
+
+func (f eventFilter) match(c memstore.Change) bool {
+	if f.region != "" && c.Region != f.region {
+		return false
+	}
+	return len(f.resources) == 0 || f.resources[c.Resource]
+}
+
+func parseEventFilter(r *http.Request) (eventFilter, error) {
+	q := r.URL.Query()
+	filter := eventFilter{region: q.Get("region")}
+
+	if v := q.Get("resource"); v != "" {
+		filter.resources = make(map[memstore.Resource]bool)
+		for _, name := range strings.Split(v, ",") {
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "gateway":
+				filter.resources[memstore.ResourceGateway] = true
+			case "agent":
+				filter.resources[memstore.ResourceAgent] = true
+			default:
+				return eventFilter{}, rpcerr.InvalidArgument("invalid resource filter", rpcerr.Field{
+					Name:        "resource",
+					Description: "resource must be gateway, agent or both comma separated",
+				})
+			}
+		}
+	}
+	return filter, nil
+}
+
+func eventID(epoch int64, id uint64) string {
+	return fmt.Sprintf("%d-%d", epoch, id)
+}
+
+// lastEventID reads Last-Event-ID, or last_event_id for clients that
+// cannot set headers.
+func lastEventID(r *http.Request) string {
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		return v
+	}
+	return r.URL.Query().Get("last_event_id")
+}
+
+// resumePoint returns the change to resume after. ok is false when there is
+// nothing to resume from this run of the store.
+func (a *Api) resumePoint(r *http.Request) (after uint64, ok bool, err error) {
+	v := lastEventID(r)
+	if v == "" {
+		return 0, false, nil
+	}
+
+	epoch, id, found := strings.Cut(v, "-")
+	e, eerr := strconv.ParseInt(epoch, 10, 64)
+	n, nerr := strconv.ParseUint(id, 10, 64)
+	if !found || eerr != nil || nerr != nil {
+		return 0, false, rpcerr.InvalidArgument("invalid last event id", rpcerr.Field{
+			Name:        "Last-Event-ID",
+			Description: "Last-Event-ID must be an id sent by this endpoint",
+		})
+	}
+	if e != a.memstore.ChangeEpoch() {
+		return 0, false, nil
+	}
+	return n, true, nil
+}
+
+// Events streams registry changes as Server-Sent Events. A client that
+// resumes after the history it needs was dropped, or after a restart, gets
+// a reset event and should list the registry again. Once identity issuers
+// are configured the stream is opened with a credential signed as
+// maps.ReadMethod.
+func (a *Api) Events(w http.ResponseWriter, r *http.Request) {
+	if err := a.authorizeRead(r); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	after, resume, err := a.resumePoint(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// the stream outlives the server write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		writeError(w, rpcerr.Internal("streaming not supported: "+err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	epoch := a.memstore.ChangeEpoch()
+	// a client that asked to resume and cannot is told to start over
+	reset := !resume && lastEventID(r) != ""
+	if !resume {
+		after = a.memstore.LastChange()
+	}
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		changes, wait, ok := a.memstore.ChangesSince(after)
+		if !ok {
+			after = a.memstore.LastChange()
+			reset = true
+			continue
+		}
+
+		if reset {
+			if _, err := fmt.Fprintf(w, "event: reset\nid: %s\ndata: {}\n\n", eventID(epoch, after)); err != nil {
+				return
+			}
+			reset = false
+		}
+		for _, c := range changes {
+			after = c.ID
+			if !filter.match(c) {
+				continue
+			}
+			if err := writeEvent(w, c); err != nil {
+				a.logger.Debug("event stream closed", "err", err)
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.closing:
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case <-wait:
+		}
+	}
+}
+
+// writeEvent names the event after the resource and kind, gateway.put or
+// agent.delete, so EventSource clients can listen for the ones they want.
+func writeEvent(w http.ResponseWriter, c memstore.Change) error {
+	body := eventBody{
+		ID:       eventID(c.Epoch, c.ID),
+		Type:     string(c.Kind),
+		Resource: strings.ToLower(string(c.Resource)),
+		Region:   c.Region,
+		Key:      c.Key,
+		Time:     c.Time,
+	}
+
+	var err error
+	switch {
+	case c.Gateway != nil:
+		body.Gateway, err = jsonOptions.Marshal(maps.GatewayMessage(c.Gateway))
+	case c.Agent != nil:
+		body.Agent, err = jsonOptions.Marshal(maps.AgentMessage(c.Agent))
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s.%s\ndata: %s\n\n", body.ID, body.Resource, body.Type, data)
+	return err
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/odio4u/memstore/seeder/pkg/maps"
+	"github.com/odio4u/memstore/seeder/pkg/memstore"
+)
+
+// changeHistory is the number of changes the store keeps to resume from.
+const changeHistory = 10000
+
+type event struct {
+	id   string
+	name string
+	data string
+}
+
+// openEvents connects to /events and returns the events as they arrive.
+func openEvents(t *testing.T, s *testServer, query, lastEventID string) <-chan event {
+	t.Helper()
+	srv := httptest.NewServer(s.router)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/events"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	s.sign(req, "alice", maps.ReadMethod)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	events := make(chan event, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		var ev event
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ": ")
+			switch field {
+			case "id":
+				ev.id = value
+			case "event":
+				ev.name = value
+			case "data":
+				ev.data = value
+			case "":
+				if ev.name != "" {
+					events <- ev
+				}
+				ev = event{}
+			}
+		}
+	}()
+	return events
+}
+
+func next(t *testing.T, events <-chan event) event {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("event stream closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event within 5s")
+	}
+	return event{}
+}
+
+func TestEventsResume(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	epoch := s.store.ChangeEpoch()
+
+	// g1, g2 and a.example.com are changes 1 to 3
+	events := openEvents(t, s, "?resource=agent,gateway&region=eu", eventID(epoch, 1))
+	for _, want := range []string{"gateway.put", "agent.put"} {
+		if ev := next(t, events); ev.name != want {
+			t.Fatalf("resumed with %s %s, want %s", ev.id, ev.name, want)
+		}
+	}
+
+	// changes in other regions are filtered out
+	if _, _, err := s.store.AddGateway(ctx, "us", &memstore.GatewayData{GatewayID: "g9", GatewayIP: "10.0.0.9", GatewayPort: 7000}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := s.store.AddAgent(ctx, "eu", &memstore.AgentData{AgentDomain: "b.example.com", GatewayID: "g2", VerifiableHash: "h1"}, false); err != nil {
+		t.Fatal(err)
+	}
+	ev := next(t, events)
+	if ev.name != "agent.put" || ev.id != eventID(epoch, 5) {
+		t.Fatalf("live event = %s %s, want agent.put %s", ev.id, ev.name, eventID(epoch, 5))
+	}
+	var body eventBody
+	if err := json.Unmarshal([]byte(ev.data), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ID != ev.id || body.Type != "put" || body.Resource != "agent" || body.Key != "b.example.com" || len(body.Agent) == 0 {
+		t.Errorf("event body = %+v", body)
+	}
+	if strings.Contains(string(body.Agent), `"h1"`) {
+		t.Errorf("event agent = %s, want no credential hash", body.Agent)
+	}
+}
+
+func TestEventsReset(t *testing.T) {
+	s := newTestServer(t)
+	epoch := s.store.ChangeEpoch()
+
+	// a client from an earlier run starts over
+	events := openEvents(t, s, "", eventID(epoch-1, 2))
+	if ev := next(t, events); ev.name != "reset" || ev.id != eventID(epoch, 3) {
+		t.Errorf("first event = %s %s, want reset at %s", ev.id, ev.name, eventID(epoch, 3))
+	}
+
+	// so does one whose history was dropped from the ring
+	for i := range changeHistory {
+		_, _, err := s.store.AddGateway(context.Background(), "us", &memstore.GatewayData{GatewayID: fmt.Sprint(i % 2), GatewayIP: "10.0.0.9", GatewayPort: 7000})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := s.store.LastChange()
+	events = openEvents(t, s, "", eventID(epoch, 1))
+	if ev := next(t, events); ev.name != "reset" || ev.id != eventID(epoch, last) {
+		t.Errorf("first event = %s %s, want reset at %s", ev.id, ev.name, eventID(epoch, last))
+	}
+}
+
+func TestEventsBadRequest(t *testing.T) {
+	s := newTestServer(t)
+	for _, path := range []string{"/events?resource=seeder", "/events?last_event_id=yesterday"} {
+		rec := s.do(t, read(path))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", path, rec.Code)
+		}
+	}
+}
+
+func TestEventsAuth(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		name string
+		req  request
+		code int
+	}{
+		{"unsigned", request{method: "GET", path: "/events"}, 401},
+		{"signed for another method", request{method: "GET", path: "/events", signer: "alice", op: "ReadAudit"}, 401},
+		{"denied by the acl", request{method: "GET", path: "/events", signer: "bob", op: maps.ReadMethod}, 403},
+	}
+	for _, tt := range tests {
+		if rec := s.do(t, tt.req); rec.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.code)
+		}
+	}
+}
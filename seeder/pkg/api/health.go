@@ -0,0 +1,31 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Healthz answers as long as the process serves HTTP. The body still lists
+// gate and check state, so a full disk shows up without failing liveness.
+func (a *Api) Healthz(w http.ResponseWriter, r *http.Request) {
+	response, _ := json.Marshal(a.health.Status())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// Readyz fails until the WAL is replayed and while any check fails.
+func (a *Api) Readyz(w http.ResponseWriter, r *http.Request) {
+	st := a.health.Refresh()
+	response, _ := json.Marshal(st)
+
+	code := http.StatusOK
+	if !st.Ready {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
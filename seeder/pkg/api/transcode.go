@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxTranscodeBody bounds a JSON request, gRPC messages are far smaller.
+const maxTranscodeBody = 1 << 20
+
+// unmarshalOptions skip unknown fields like grpc-gateway does, so clients
+// built against a newer schema keep working.
+var unmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// MountService serves every unary method of desc as POST <router>/<Method>
+// with a protojson body. Calls run the generated gRPC method handler under
+// interceptors, the same chain the gRPC server uses, so validation, auth,
+// limits, metrics and errors are shared by both transports.
+func MountService(router *mux.Router, desc *grpc.ServiceDesc, srv any, interceptors ...grpc.UnaryServerInterceptor) {
+	interceptor := chainUnary(interceptors)
+	for _, method := range desc.Methods {
+		router.Handle("/"+method.MethodName, transcode(srv, method.Handler, interceptor)).Methods("POST")
+	}
+}
+
+// WithoutMethods returns a copy of desc leaving out the methods named by
+// their full name, /<service>/<method>.
+func WithoutMethods(desc *grpc.ServiceDesc, fullNames ...string) *grpc.ServiceDesc {
+	trimmed := *desc
+	trimmed.Methods = nil
+	for _, method := range desc.Methods {
+		if !slices.Contains(fullNames, "/"+desc.ServiceName+"/"+method.MethodName) {
+			trimmed.Methods = append(trimmed.Methods, method)
+		}
+	}
+	return &trimmed
+}
+
+func transcode(srv any, handler grpc.MethodHandler, interceptor grpc.UnaryServerInterceptor) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTranscodeBody))
+		if err != nil {
+			writeError(w, rpcerr.InvalidArgument("failed to read request body: "+err.Error()))
+			return
+		}
+
+		dec := func(v any) error {
+			if len(body) == 0 {
+				return nil
+			}
+			if err := unmarshalOptions.Unmarshal(body, v.(proto.Message)); err != nil {
+				return rpcerr.InvalidArgument("invalid request body: " + err.Error())
+			}
+			return nil
+		}
+
+		resp, err := handler(srv, incomingContext(r), dec, interceptor)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeProto(w, http.StatusOK, resp.(proto.Message))
+	})
+}
+
+// incomingContext gives the handlers what a gRPC call would carry: the
+// request headers as metadata, so X-Agni-* credentials, x-request-id and
+// traceparent work unchanged, and the caller as the peer.
+func incomingContext(r *http.Request) context.Context {
+	md := make(metadata.MD, len(r.Header))
+	for key, values := range r.Header {
+		md[strings.ToLower(key)] = values
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+
+	p := &peer.Peer{Addr: remoteAddr(r.RemoteAddr)}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+	}
+	return peer.NewContext(ctx, p)
+}
+
+func remoteAddr(addr string) net.Addr {
+	if tcp, err := net.ResolveTCPAddr("tcp", addr); err == nil {
+		return tcp
+	}
+	return stringAddr(addr)
+}
+
+type stringAddr string
+
+func (a stringAddr) Network() string { return "tcp" }
+func (a stringAddr) String() string  { return string(a) }
+
+// chainUnary nests interceptors the way grpc.ChainUnaryInterceptor does,
+// the first one is the outermost.
+func chainUnary(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	mapper "github.com/odio4u/agni-schema/maps"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// fakeMaps records the context and request of the last RegisterGateway.
+type fakeMaps struct {
+	mapper.UnimplementedMapsServer
+	ctx context.Context
+	req *mapper.GatewayPutRequest
+}
+
+func (f *fakeMaps) RegisterGateway(ctx context.Context, req *mapper.GatewayPutRequest) (*mapper.GatewayResponse, error) {
+	f.ctx, f.req = ctx, req
+	if req.GatewayIp == "" {
+		return nil, rpcerr.InvalidArgument("gateway_ip is required")
+	}
+	return &mapper.GatewayResponse{GatewayId: "g1", GatewayIp: req.GatewayIp}, nil
+}
+
+func newTranscoder(srv mapper.MapsServer, interceptors ...grpc.UnaryServerInterceptor) *mux.Router {
+	router := mux.NewRouter()
+	MountService(router.PathPrefix("/v1/maps").Subrouter(), &mapper.Maps_ServiceDesc, srv, interceptors...)
+	return router
+}
+
+func post(router http.Handler, method, body string, setup func(r *http.Request)) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/v1/maps/"+method, strings.NewReader(body))
+	if setup != nil {
+		setup(r)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestTranscodeInterceptors(t *testing.T) {
+	var calls []string
+	record := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			calls = append(calls, name+" "+info.FullMethod)
+			return handler(ctx, req)
+		}
+	}
+	srv := &fakeMaps{}
+	router := newTranscoder(srv, record("outer"), record("inner"))
+
+	rec := post(router, "RegisterGateway", `{"gateway_ip":"10.0.0.1","unknown_field":1}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		GatewayID string `json:"gateway_id"`
+		GatewayIP string `json:"gateway_ip"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.GatewayID != "g1" || resp.GatewayIP != "10.0.0.1" {
+		t.Errorf("response = %s, %v", rec.Body.String(), err)
+	}
+	want := []string{"outer /maps.Maps/RegisterGateway", "inner /maps.Maps/RegisterGateway"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("interceptors ran %v, want %v", calls, want)
+	}
+
+	// an interceptor refusing the call keeps the handler from running
+	srv.req = nil
+	deny := func(context.Context, any, *grpc.UnaryServerInfo, grpc.UnaryHandler) (any, error) {
+		return nil, rpcerr.Unauthenticated("missing signed credential")
+	}
+	rec = post(newTranscoder(srv, deny), "RegisterGateway", `{"gateway_ip":"10.0.0.1"}`, nil)
+	if rec.Code != http.StatusUnauthorized || srv.req != nil {
+		t.Errorf("denied call status = %d, handler ran %v", rec.Code, srv.req != nil)
+	}
+}
+
+func TestTranscodeContext(t *testing.T) {
+	srv := &fakeMaps{}
+	router := newTranscoder(srv)
+	state := &tls.ConnectionState{ServerName: "seeder.example.com"}
+
+	rec := post(router, "RegisterGateway", `{"gateway_ip":"10.0.0.1"}`, func(r *http.Request) {
+		r.RemoteAddr = "10.0.0.9:5000"
+		r.TLS = state
+		r.Header.Set("X-Agni-Subject", "alice")
+		r.Header.Set("X-Request-Id", "req-1")
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	md, _ := metadata.FromIncomingContext(srv.ctx)
+	if got := md.Get("x-agni-subject"); !slices.Equal(got, []string{"alice"}) {
+		t.Errorf("x-agni-subject = %v", got)
+	}
+	if got := md.Get("x-request-id"); !slices.Equal(got, []string{"req-1"}) {
+		t.Errorf("x-request-id = %v", got)
+	}
+	p, ok := peer.FromContext(srv.ctx)
+	if !ok || p.Addr.String() != "10.0.0.9:5000" {
+		t.Fatalf("peer = %v", p)
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || info.State.ServerName != "seeder.example.com" {
+		t.Errorf("peer auth info = %v, want the request's TLS state", p.AuthInfo)
+	}
+}
+
+func TestTranscodeErrors(t *testing.T) {
+	router := newTranscoder(&fakeMaps{})
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+		status string
+	}{
+		{"invalid json", "RegisterGateway", `{"gateway_ip":`, 400, "INVALID_ARGUMENT"},
+		{"wrong field type", "RegisterGateway", `{"gateway_ip":7}`, 400, "INVALID_ARGUMENT"},
+		{"body too large", "RegisterGateway", `{"gateway_ip":"` + strings.Repeat("x", maxTranscodeBody) + `"}`, 400, "INVALID_ARGUMENT"},
+		{"handler error", "RegisterGateway", `{}`, 400, "INVALID_ARGUMENT"},
+		{"unimplemented", "RegisterAgent", `{}`, 501, "UNIMPLEMENTED"},
+	}
+	for _, tt := range tests {
+		rec := post(router, tt.method, tt.body, nil)
+		if rec.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, rec.Code, tt.code, rec.Body.String())
+			continue
+		}
+		if got := decodeError(t, rec); got.Status != tt.status {
+			t.Errorf("%s: error = %+v, want %s", tt.name, got, tt.status)
+		}
+	}
+
+	if rec := post(router, "NoSuchMethod", `{}`, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown method status = %d, want 404", rec.Code)
+	}
+}
+
+// Without viewer TLS the writes are left out and only the reads answer.
+func TestWithoutMethods(t *testing.T) {
+	desc := WithoutMethods(&mapper.Maps_ServiceDesc,
+		mapper.Maps_RegisterGateway_FullMethodName,
+		mapper.Maps_RegisterAgent_FullMethodName,
+	)
+	if len(desc.Methods) != len(mapper.Maps_ServiceDesc.Methods)-2 {
+		t.Errorf("methods = %d, want %d", len(desc.Methods), len(mapper.Maps_ServiceDesc.Methods)-2)
+	}
+
+	router := mux.NewRouter()
+	MountService(router.PathPrefix("/v1/maps").Subrouter(), desc, &fakeMaps{})
+	for _, method := range []string{"RegisterGateway", "RegisterAgent"} {
+		if rec := post(router, method, `{"gateway_ip":"10.0.0.1"}`, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("%s status = %d, want 404", method, rec.Code)
+		}
+	}
+	// the reads stay, fakeMaps does not implement them
+	if rec := post(router, "ResolveGatewayForAgent", `{}`, nil); rec.Code != http.StatusNotImplemented {
+		t.Errorf("ResolveGatewayForAgent status = %d, want 501", rec.Code)
+	}
+}
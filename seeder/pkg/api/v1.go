@@ -0,0 +1,357 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+	"github.com/odio4u/memstore/seeder/pkg/maps"
+	"github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	registrypb "github.com/odio4u/memstore/seeder/proto"
+)
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+// setV1Routes mounts the versioned API. Reads go through the Registry
+// service handlers with the request headers as metadata, so both
+// transports return the same data and errors and check the same
+// credentials.
+func setV1Routes(router *mux.Router, api *Api) {
+	v1 := router.PathPrefix("/v1").Subrouter()
+	v1.HandleFunc("/openapi.yaml", api.OpenAPI).Methods("GET")
+
+	ready := v1.NewRoute().Subrouter()
+	ready.Use(api.requireReady)
+
+	ready.HandleFunc("/regions", api.ListRegions).Methods("GET")
+	ready.HandleFunc("/regions/{region}", api.GetRegion).Methods("GET")
+	ready.HandleFunc("/gateways", api.ListGateways).Methods("GET")
+	ready.HandleFunc("/gateways/{gateway_id}", api.GetGateway).Methods("GET")
+	ready.HandleFunc("/gateways/{gateway_id}", api.DeleteGateway).Methods("DELETE")
+	ready.HandleFunc("/gateways/{gateway_id}/drain", api.DrainGateway).Methods("POST")
+	ready.HandleFunc("/gateways/{gateway_id}/state", api.SetGatewayState).Methods("POST")
+	ready.HandleFunc("/agents", api.ListAgents).Methods("GET")
+	ready.HandleFunc("/agents/{agent_domain}", api.GetAgent).Methods("GET")
+	ready.HandleFunc("/agents/{agent_domain}/reassign", api.ReassignAgent).Methods("POST")
+	ready.HandleFunc("/snapshots", api.CreateSnapshot).Methods("POST")
+	ready.HandleFunc("/wal", api.WALStats).Methods("GET")
+	ready.HandleFunc("/config/reload", api.ReloadConfig).Methods("POST")
+}
+
+// requireReady answers UNAVAILABLE until the WAL is replayed, and to
+// changes while a health check such as the WAL fails, like the gRPC
+// readiness interceptor.
+func (a *Api) requireReady(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.health.Started() {
+			writeError(w, rpcerr.Unavailable("seeder is not ready yet"))
+			return
+		}
+		if r.Method != http.MethodGet && !a.health.Serving() {
+			writeError(w, rpcerr.Unavailable("seeder cannot persist changes right now"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize checks the signed credential in the X-Agni-* headers. The
+// signature covers method and fields exactly like a maps registration.
+func (a *Api) authorize(r *http.Request, method string, fields ...string) (maps.Caller, error) {
+	cred, err := identity.FromHeader(r.Header)
+	if err != nil {
+		return maps.Caller{}, rpcerr.Unauthenticated(err.Error())
+	}
+	subject, err := a.admin.Authorize(cred, method, fields...)
+	if err != nil {
+		return maps.Caller{}, err
+	}
+	return maps.Caller{Subject: subject, Peer: r.RemoteAddr}, nil
+}
+
+// authorizeRead checks the X-Agni-* credential of a read the Registry
+// service does not serve, the same way it checks its own.
+func (a *Api) authorizeRead(r *http.Request) error {
+	return a.admin.Policy.AuthorizeRead(incomingContext(r))
+}
+
+func pageParams(r *http.Request) (int32, string, error) {
+	q := r.URL.Query()
+
+	var size int32
+	if v := q.Get("page_size"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return 0, "", rpcerr.InvalidArgument("invalid page size", rpcerr.Field{
+				Name:        "page_size",
+				Description: "page_size must be a number",
+			})
+		}
+		size = int32(n)
+	}
+	return size, q.Get("page_token"), nil
+}
+
+func (a *Api) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPI)
+}
+
+func (a *Api) ListRegions(w http.ResponseWriter, r *http.Request) {
+	size, token, err := pageParams(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := a.registry.ListRegions(incomingContext(r), &registrypb.ListRegionsRequest{
+		PageSize:  size,
+		PageToken: token,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeProto(w, http.StatusOK, resp)
+}
+
+func (a *Api) GetRegion(w http.ResponseWriter, r *http.Request) {
+	resp, err := a.registry.GetRegion(incomingContext(r), &registrypb.GetRegionRequest{
+		Name: mux.Vars(r)["region"],
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeProto(w, http.StatusOK, resp)
+}
+
+func (a *Api) ListGateways(w http.ResponseWriter, r *http.Request) {
+	size, token, err := pageParams(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	q := r.URL.Query()
+	resp, err := a.registry.ListGateways(incomingContext(r), &registrypb.ListGatewaysRequest{
+		Region:    q.Get("region"),
+		GatewayIp: q.Get("gateway_ip"),
+		PageSize:  size,
+		PageToken: token,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeProto(w, http.StatusOK, resp)
+}
+
+func (a *Api) GetGateway(w http.ResponseWriter, r *http.Request) {
+	resp, err := a.registry.GetGateway(incomingContext(r), &registrypb.GetGatewayRequest{
+		Region:    r.URL.Query().Get("region"),
+		GatewayId: mux.Vars(r)["gateway_id"],
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeProto(w, http.StatusOK, resp)
+}
+
+func (a *Api) DeleteGateway(w http.ResponseWriter, r *http.Request) {
+	region := r.URL.Query().Get("region")
+	gatewayID := mux.Vars(r)["gateway_id"]
+
+	caller, err := a.authorize(r, "DeleteGateway", region, gatewayID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := a.admin.DeleteGateway(r.Context(), caller, region, gatewayID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeProto(w, http.StatusOK, resp)
+}
+
+func (a *Api) DrainGateway(w http.ResponseWriter, r *http.Request) {
+	region := r.URL.Query().Get("region")
+	gatewayID := mux.Vars(r)["gateway_id"]
+
+	caller, err := a.authorize(r, "DrainGateway", region, gatewayID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := a.admin.DrainGateway(r.Context(), caller, region, gatewayID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeProto(w, http.StatusOK, resp)
+}
+
+type gatewayStateRequest struct {
+	State string `json:"state"`
+}
+
+func (a *Api) SetGatewayState(w http.ResponseWriter, r *http.Request) {
+	region := r.URL.Query().Get("region")
+	gatewayID := mux.Vars(r)["gateway_id"]
+
+	var req gatewayStateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeError(w, rpcerr.InvalidArgument("invalid request body: "+err.Error()))
+		return
+	}
+	state, err := memstore.ParseGatewayState(req.State)
+	if err != nil {
+		writeError(w, rpcerr.InvalidArgument("invalid gateway state request", rpcerr.Field{
+			Name:        "state",
+			Description: err.Error(),
+		}))
+		return
+	}
+
+	caller, err := a.authorize(r, "SetGatewayState", region, gatewayID, req.State)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := a.admin.SetGatewayState(r.Context(), caller, region, gatewayID, state)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeProto(w, http.StatusOK, resp)
+}
+
+func (a *Api) ListAgents(w http.ResponseWriter, r *http.Request) {
+	size, token, err := pageParams(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	q := r.URL.Query()
+	resp, err := a.registry.ListAgents(incomingContext(r), &registrypb.ListAgentsRequest{
+		Region:             q.Get("region"),
+		GatewayId:          q.Get("gateway_id"),
+		VerifiableCredHash: q.Get("verifiable_cred_hash"),
+		DomainPrefix:       q.Get("domain_prefix"),
+		PageSize:           size,
+		PageToken:          token,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeProto(w, http.StatusOK, resp)
+}
+
+func (a *Api) GetAgent(w http.ResponseWriter, r *http.Request) {
+	resp, err := a.registry.GetAgent(incomingContext(r), &registrypb.GetAgentRequest{
+		Region:      r.URL.Query().Get("region"),
+		AgentDomain: mux.Vars(r)["agent_domain"],
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeProto(w, http.StatusOK, resp)
+}
+
+type reassignRequest struct {
+	GatewayID string `json:"gateway_id"`
+}
+
+func (a *Api) ReassignAgent(w http.ResponseWriter, r *http.Request) {
+	region := r.URL.Query().Get("region")
+	agentDomain := mux.Vars(r)["agent_domain"]
+
+	var req reassignRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeError(w, rpcerr.InvalidArgument("invalid request body: "+err.Error()))
+		return
+	}
+
+	var fields []rpcerr.Field
+	if region == "" {
+		fields = append(fields, rpcerr.Field{Name: "region", Description: "region is required"})
+	}
+	if req.GatewayID == "" {
+		fields = append(fields, rpcerr.Field{Name: "gateway_id", Description: "gateway_id is required"})
+	}
+	if len(fields) > 0 {
+		writeError(w, rpcerr.InvalidArgument("invalid reassign request", fields...))
+		return
+	}
+
+	caller, err := a.authorize(r, "ReassignAgent", region, agentDomain, req.GatewayID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	resp, err := a.admin.ReassignAgent(r.Context(), caller, region, agentDomain, req.GatewayID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeProto(w, http.StatusOK, resp)
+}
+
+func (a *Api) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	caller, err := a.authorize(r, "CreateSnapshot")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	info, err := a.admin.Snapshot(r.Context(), caller)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, info)
+}
+
+func (a *Api) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	caller, err := a.authorize(r, "ReloadConfig")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result, err := a.admin.ReloadConfig(r.Context(), caller)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (a *Api) WALStats(w http.ResponseWriter, r *http.Request) {
+	if err := a.authorizeRead(r); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	st, err := a.admin.WALStats()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
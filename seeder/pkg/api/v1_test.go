@@ -0,0 +1,422 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	mapper "github.com/odio4u/agni-schema/maps"
+	"github.com/odio4u/memstore/seeder/pkg/acl"
+	"github.com/odio4u/memstore/seeder/pkg/health"
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+	"github.com/odio4u/memstore/seeder/pkg/maps"
+	"github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	"github.com/odio4u/memstore/seeder/wal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+type testServer struct {
+	router  *mux.Router
+	store   *memstore.MemStore
+	checker *health.Checker
+	policy  *maps.Policy
+	priv    ed25519.PrivateKey
+	nonce   int
+}
+
+// newTestServer serves a store holding gateways g1 and g2 in eu, with the
+// agent a.example.com on g1. alice may call every method, bob none.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+
+	store := memstore.NewMemStore(logger)
+	for _, id := range []string{"g1", "g2"} {
+		_, _, err := store.AddGateway(ctx, "eu", &memstore.GatewayData{GatewayID: id, GatewayIP: "10.0.0.1", GatewayPort: 7000})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, _, err := store.AddAgent(ctx, "eu", &memstore.AgentData{AgentDomain: "a.example.com", GatewayID: "g1", VerifiableHash: "h1"}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := wal.OpenWALPath(filepath.Join(t.TempDir(), "wal.log"), nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	store.SetJournal(w.Journal())
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := identity.NewVerifier(map[string]string{"ops": base64.StdEncoding.EncodeToString(pub)}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checker := health.New()
+	checker.AddGate(health.GateWALReplay)
+	checker.Open(health.GateWALReplay)
+
+	policy := maps.NewPolicy(verifier, acl.New([]acl.Rule{{Subject: "alice", Methods: []string{acl.Wildcard}}}))
+	admin := &maps.Admin{
+		MemStore: store,
+		WALer:    w,
+		Policy:   policy,
+		Logger:   logger,
+	}
+	router := mux.NewRouter()
+	SetRoutes(router, NewApi(store, nil, checker, &maps.RPCRegistry{MemStore: store, Policy: policy}, admin, logger))
+	return &testServer{router: router, store: store, checker: checker, policy: policy, priv: priv}
+}
+
+type request struct {
+	method string
+	path   string
+	body   string
+	// signer, when set, signs the request for op over fields
+	signer string
+	op     string
+	fields []string
+}
+
+func (s *testServer) do(t *testing.T, req request) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+	if req.signer != "" {
+		s.sign(r, req.signer, req.op, req.fields...)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, r)
+	return rec
+}
+
+// read is a registry read signed by alice.
+func read(path string) request {
+	return request{method: "GET", path: path, signer: "alice", op: maps.ReadMethod}
+}
+
+// sign sets the X-Agni-* headers of a credential for op over fields.
+func (s *testServer) sign(r *http.Request, signer, op string, fields ...string) {
+	s.nonce++
+	cred := &identity.Credential{
+		Issuer:    "ops",
+		Subject:   signer,
+		Timestamp: time.Now().Unix(),
+		Nonce:     fmt.Sprintf("n%d", s.nonce),
+	}
+	identity.Sign(s.priv, cred, op, fields...)
+	r.Header.Set(identity.MDIssuer, cred.Issuer)
+	r.Header.Set(identity.MDSubject, cred.Subject)
+	r.Header.Set(identity.MDTimestamp, strconv.FormatInt(cred.Timestamp, 10))
+	r.Header.Set(identity.MDNonce, cred.Nonce)
+	r.Header.Set(identity.MDSignature, base64.StdEncoding.EncodeToString(cred.Signature))
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) errorStatus {
+	t.Helper()
+	var body errorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body %q: %v", rec.Body.String(), err)
+	}
+	return body.Error
+}
+
+func TestV1Status(t *testing.T) {
+	s := newTestServer(t)
+
+	// steps run in order against the same store
+	steps := []struct {
+		name   string
+		req    request
+		code   int
+		status string
+	}{
+		{"regions", read("/v1/regions"), 200, ""},
+		{"region", read("/v1/regions/eu"), 200, ""},
+		{"unknown region", read("/v1/regions/sa"), 404, "NOT_FOUND"},
+		{"gateways", read("/v1/gateways?region=eu&page_size=1"), 200, ""},
+		{"bad page size", read("/v1/gateways?page_size=ten"), 400, "INVALID_ARGUMENT"},
+		{"page size too big", read("/v1/gateways?page_size=5000"), 400, "INVALID_ARGUMENT"},
+		{"bad page token", read("/v1/agents?page_token=bm9wZQ"), 400, "INVALID_ARGUMENT"},
+		{"gateway", read("/v1/gateways/g1"), 200, ""},
+		{"unknown gateway", read("/v1/gateways/g9"), 404, "NOT_FOUND"},
+		{"agent", read("/v1/agents/a.example.com?region=eu"), 200, ""},
+		{"agent without region", read("/v1/agents/a.example.com"), 400, "INVALID_ARGUMENT"},
+		{"wal", read("/v1/wal"), 200, ""},
+		{"unsigned read", request{method: "GET", path: "/v1/agents?region=eu"}, 401, "UNAUTHENTICATED"},
+		{"unsigned wal", request{method: "GET", path: "/v1/wal"}, 401, "UNAUTHENTICATED"},
+		{
+			name:   "read denied by the acl",
+			req:    request{method: "GET", path: "/v1/gateways/g1", signer: "bob", op: maps.ReadMethod},
+			code:   403,
+			status: "PERMISSION_DENIED",
+		},
+		{
+			name:   "unsigned delete",
+			req:    request{method: "DELETE", path: "/v1/gateways/g2?region=eu"},
+			code:   401,
+			status: "UNAUTHENTICATED",
+		},
+		{
+			name:   "signed for another method",
+			req:    request{method: "DELETE", path: "/v1/gateways/g2?region=eu", signer: "alice", op: "DrainGateway", fields: []string{"eu", "g2"}},
+			code:   401,
+			status: "UNAUTHENTICATED",
+		},
+		{
+			name:   "denied by the acl",
+			req:    request{method: "DELETE", path: "/v1/gateways/g2?region=eu", signer: "bob", op: "DeleteGateway", fields: []string{"eu", "g2"}},
+			code:   403,
+			status: "PERMISSION_DENIED",
+		},
+		{
+			name:   "delete gateway with agents",
+			req:    request{method: "DELETE", path: "/v1/gateways/g1?region=eu", signer: "alice", op: "DeleteGateway", fields: []string{"eu", "g1"}},
+			code:   400,
+			status: "FAILED_PRECONDITION",
+		},
+		{
+			name: "drain",
+			req:  request{method: "POST", path: "/v1/gateways/g2/drain?region=eu", signer: "alice", op: "DrainGateway", fields: []string{"eu", "g2"}},
+			code: 200,
+		},
+		{
+			name:   "reassign without a gateway",
+			req:    request{method: "POST", path: "/v1/agents/a.example.com/reassign?region=eu", body: `{}`},
+			code:   400,
+			status: "INVALID_ARGUMENT",
+		},
+		{
+			name:   "reassign with a bad body",
+			req:    request{method: "POST", path: "/v1/agents/a.example.com/reassign?region=eu", body: `{"gateway_id":`},
+			code:   400,
+			status: "INVALID_ARGUMENT",
+		},
+		{
+			name:   "reassign onto a draining gateway",
+			req:    request{method: "POST", path: "/v1/agents/a.example.com/reassign?region=eu", body: `{"gateway_id":"g2"}`, signer: "alice", op: "ReassignAgent", fields: []string{"eu", "a.example.com", "g2"}},
+			code:   400,
+			status: "FAILED_PRECONDITION",
+		},
+		{
+			name: "delete drained gateway",
+			req:  request{method: "DELETE", path: "/v1/gateways/g2?region=eu", signer: "alice", op: "DeleteGateway", fields: []string{"eu", "g2"}},
+			code: 200,
+		},
+		{
+			name:   "delete twice",
+			req:    request{method: "DELETE", path: "/v1/gateways/g2?region=eu", signer: "alice", op: "DeleteGateway", fields: []string{"eu", "g2"}},
+			code:   404,
+			status: "NOT_FOUND",
+		},
+		{
+			name: "snapshot",
+			req:  request{method: "POST", path: "/v1/snapshots", signer: "alice", op: "CreateSnapshot"},
+			code: 201,
+		},
+	}
+	for _, step := range steps {
+		rec := s.do(t, step.req)
+		if rec.Code != step.code {
+			t.Errorf("%s: status = %d, want %d: %s", step.name, rec.Code, step.code, rec.Body.String())
+			continue
+		}
+		if step.status == "" {
+			continue
+		}
+		if got := decodeError(t, rec); got.Status != step.status || got.Code != step.code {
+			t.Errorf("%s: error = %+v, want %s", step.name, got, step.status)
+		}
+	}
+}
+
+// Credential hashes prove ownership of a gateway or agent, reads never
+// return them.
+func TestV1HidesCredentialHash(t *testing.T) {
+	s := newTestServer(t)
+	for _, path := range []string{"/v1/agents?region=eu", "/v1/agents/a.example.com?region=eu", "/v1/gateways?region=eu"} {
+		rec := s.do(t, read(path))
+		if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"h1"`) {
+			t.Errorf("%s = %d %s, want no credential hash", path, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+// Without identity issuers reads are open, like registrations, and admin
+// operations are refused.
+func TestV1WithoutIssuers(t *testing.T) {
+	s := newTestServer(t)
+	s.policy.Set(nil, nil)
+
+	for _, path := range []string{"/v1/regions", "/v1/agents?region=eu", "/v1/wal"} {
+		if rec := s.do(t, request{method: "GET", path: path}); rec.Code != http.StatusOK {
+			t.Errorf("%s status = %d, want 200", path, rec.Code)
+		}
+	}
+	rec := s.do(t, request{method: "POST", path: "/v1/snapshots", signer: "alice", op: "CreateSnapshot"})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("snapshot status = %d, want 403", rec.Code)
+	}
+}
+
+func TestV1NotReady(t *testing.T) {
+	s := newTestServer(t)
+	s.checker.AddGate("peers")
+
+	rec := s.do(t, request{method: "GET", path: "/v1/regions"})
+	if rec.Code != http.StatusServiceUnavailable || decodeError(t, rec).Status != "UNAVAILABLE" {
+		t.Errorf("status = %d %s, want 503 UNAVAILABLE", rec.Code, rec.Body.String())
+	}
+	// the document is served regardless
+	if rec := s.do(t, request{method: "GET", path: "/v1/openapi.yaml"}); rec.Code != http.StatusOK {
+		t.Errorf("openapi.yaml status = %d", rec.Code)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    int
+		status  string
+		message string
+		details []string
+	}{
+		{
+			name:    "field violations",
+			err:     rpcerr.InvalidArgument("invalid reassign request", rpcerr.Field{Name: "region", Description: "region is required"}),
+			code:    400,
+			status:  "INVALID_ARGUMENT",
+			message: "invalid reassign request",
+			details: []string{"type.googleapis.com/google.rpc.BadRequest"},
+		},
+		{
+			name:    "retry hint",
+			err:     rpcerr.Exhausted("slow down", time.Second),
+			code:    429,
+			status:  "RESOURCE_EXHAUSTED",
+			message: "slow down",
+			details: []string{"type.googleapis.com/google.rpc.RetryInfo"},
+		},
+		{
+			name:    "unreadable body",
+			err:     io.ErrUnexpectedEOF,
+			code:    400,
+			status:  "INVALID_ARGUMENT",
+			message: "invalid request body: unexpected EOF",
+		},
+		{
+			name:    "grpc status",
+			err:     status.Error(codes.Unimplemented, "method not implemented"),
+			code:    501,
+			status:  "UNIMPLEMENTED",
+			message: "method not implemented",
+		},
+		{
+			name:    "unknown error",
+			err:     errors.New("disk on fire"),
+			code:    500,
+			status:  "INTERNAL",
+			message: "disk on fire",
+		},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		writeError(rec, tt.err)
+		if rec.Code != tt.code || rec.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: status = %d %q", tt.name, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		got := decodeError(t, rec)
+		if got.Code != tt.code || got.Status != tt.status || got.Message != tt.message {
+			t.Errorf("%s: error = %+v, want %d %s", tt.name, got, tt.code, tt.status)
+		}
+		var types []string
+		for _, raw := range got.Details {
+			var detail struct {
+				Type string `json:"@type"`
+			}
+			json.Unmarshal(raw, &detail)
+			types = append(types, detail.Type)
+		}
+		if !slices.Equal(types, tt.details) {
+			t.Errorf("%s: details = %v, want %v", tt.name, types, tt.details)
+		}
+	}
+}
+
+// Every /v1 route is documented and every documented operation is routed.
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]yaml.Node `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(openAPI, &doc); err != nil {
+		t.Fatal(err)
+	}
+	var documented []string
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			if method != "parameters" {
+				documented = append(documented, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	slices.Sort(documented)
+
+	var routed []string
+	router := mux.NewRouter()
+	setV1Routes(router, &Api{})
+	MountService(router.PathPrefix("/v1/maps").Subrouter(), &mapper.Maps_ServiceDesc, nil)
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(path, "/v1/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		// the maps methods are documented as one operation
+		if strings.HasPrefix(path, "/v1/maps/") {
+			path = "/v1/maps/{method}"
+		}
+		for _, method := range methods {
+			if op := method + " " + path; !slices.Contains(routed, op) {
+				routed = append(routed, op)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(routed)
+	if len(routed) == 0 {
+		t.Fatal("found no /v1 routes")
+	}
+
+	if !slices.Equal(documented, routed) {
+		t.Errorf("documented operations %v\nrouted operations %v", documented, routed)
+	}
+}
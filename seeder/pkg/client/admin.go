@@ -0,0 +1,283 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	"github.com/odio4u/memstore/seeder/audit"
+	registrypb "github.com/odio4u/memstore/seeder/proto"
+	rpccode "google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var ErrNoViewer = errors.New("viewer address is required for this call")
+
+// Seeder is a seeder announced in a region, as the /seeder view lists it.
+type Seeder struct {
+	SeederID       string
+	Name           string
+	Dns            string
+	SeedIP         string
+	SeedPort       string
+	Region         string
+	VerifiableHash string
+}
+
+// Status is the health of the connected seeder and the regions it knows.
+type Status struct {
+	// Services maps each gRPC service to its serving status
+	Services map[string]string `json:"services"`
+	Regions  []RegionStatus    `json:"regions"`
+}
+
+type RegionStatus struct {
+	Name     string   `json:"name"`
+	Gateways int32    `json:"gateways"`
+	Agents   int32    `json:"agents"`
+	Seeders  []Seeder `json:"seeders,omitempty"`
+}
+
+// httpError turns an admin API error body back into a status error.
+func httpError(resp *http.Response) error {
+	var body struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(data, &body); err != nil || body.Error.Status == "" {
+		return status.Errorf(codes.Unknown, "%s: %s", resp.Status, data)
+	}
+	return status.Error(codes.Code(rpccode.Code_value[body.Error.Status]), body.Error.Message)
+}
+
+// viewerOrder returns the viewers starting at the next in turn, so calls
+// spread over the seeders and fail over to the others.
+func (c *Client) viewerOrder() []string {
+	n := len(c.viewers)
+	start := int(c.next.Add(1)) % n
+	return append(c.viewers[start:n:n], c.viewers[:start]...)
+}
+
+// dialError is a request that never reached the seeder, safe to send to
+// another one whatever the method.
+func dialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// do sends a request to a viewer port. method and fields are signed when
+// method is set, with a fresh credential on every attempt. GETs are retried
+// on any failure and UNAVAILABLE, everything else only when the seeder
+// could not be reached.
+func (c *Client) do(ctx context.Context, verb, path string, query url.Values, method string, fields ...string) (*http.Response, error) {
+	return c.send(ctx, verb, path, query, nil, method, fields...)
+}
+
+// send is do with a JSON request body, nil for none.
+func (c *Client) send(ctx context.Context, verb, path string, query url.Values, body []byte, method string, fields ...string) (*http.Response, error) {
+	if len(c.viewers) == 0 {
+		return nil, ErrNoViewer
+	}
+
+	viewers := c.viewerOrder()
+	var lastErr error
+	for attempt := range c.attempts {
+		if attempt > 0 {
+			if err := sleep(ctx, backoff(attempt)); err != nil {
+				return nil, lastErr
+			}
+		}
+
+		target := viewers[attempt%len(viewers)] + path
+		if len(query) > 0 {
+			target += "?" + query.Encode()
+		}
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, verb, target, reader)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if method != "" {
+			if err := c.signer.signHeader(req.Header, method, fields...); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.http.Do(req)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		retry := dialError(err) || verb == http.MethodGet && (err != nil || resp.StatusCode == http.StatusServiceUnavailable)
+		if err == nil {
+			err = httpError(resp)
+			resp.Body.Close()
+		}
+		if !retry || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (c *Client) gatewayCall(ctx context.Context, verb, path string, body []byte, method, region, gatewayID string, fields ...string) (*registrypb.Gateway, error) {
+	query := url.Values{}
+	if region != "" {
+		query.Set("region", region)
+	}
+	resp, err := c.send(ctx, verb, path, query, body, method, append([]string{region, gatewayID}, fields...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	gateway := &registrypb.Gateway{}
+	if err := decodeProto(resp.Body, gateway); err != nil {
+		return nil, err
+	}
+	return gateway, nil
+}
+
+// DrainGateway stops new agents from being placed on the gateway. Region
+// may be empty when the gateway id is unique.
+func (c *Client) DrainGateway(ctx context.Context, region, gatewayID string) (*registrypb.Gateway, error) {
+	return c.gatewayCall(ctx, http.MethodPost, "/v1/gateways/"+url.PathEscape(gatewayID)+"/drain", nil, "DrainGateway", region, gatewayID)
+}
+
+// SetGatewayState moves the gateway to active, draining, maintenance or
+// offline.
+func (c *Client) SetGatewayState(ctx context.Context, region, gatewayID, state string) (*registrypb.Gateway, error) {
+	body, err := json.Marshal(map[string]string{"state": state})
+	if err != nil {
+		return nil, err
+	}
+	return c.gatewayCall(ctx, http.MethodPost, "/v1/gateways/"+url.PathEscape(gatewayID)+"/state", body, "SetGatewayState", region, gatewayID, state)
+}
+
+func (c *Client) DeleteGateway(ctx context.Context, region, gatewayID string) (*registrypb.Gateway, error) {
+	return c.gatewayCall(ctx, http.MethodDelete, "/v1/gateways/"+url.PathEscape(gatewayID), nil, "DeleteGateway", region, gatewayID)
+}
+
+// ReloadResult lists the config keys a reload changed.
+type ReloadResult struct {
+	Applied []string `json:"applied"`
+	// Ignored need a restart of the seeder
+	Ignored []string `json:"ignored"`
+}
+
+// ReloadConfig makes the seeder re-read its config like SIGHUP does. With
+// several seeders only the first one answering reloads.
+func (c *Client) ReloadConfig(ctx context.Context) (*ReloadResult, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/v1/config/reload", nil, "ReloadConfig")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &ReloadResult{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("invalid reload result: %w", err)
+	}
+	return result, nil
+}
+
+// Audit returns up to limit audit log entries after the sequence number
+// after, without credential hashes. Every seeder keeps its own log, so
+// page through one by giving the client only its viewer.
+func (c *Client) Audit(ctx context.Context, after uint64, limit int) ([]*audit.Entry, error) {
+	query := url.Values{
+		"after": {strconv.FormatUint(after, 10)},
+		"limit": {strconv.Itoa(limit)},
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/audit", query, "ReadAudit", query.Get("after"), query.Get("limit"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var entries []*audit.Entry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("invalid audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+// Seeders lists the seeders announced in region.
+func (c *Client) Seeders(ctx context.Context, region string) ([]Seeder, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/seeder", url.Values{"region": {region}}, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var seeders []Seeder
+	if err := json.NewDecoder(resp.Body).Decode(&seeders); err != nil {
+		return nil, fmt.Errorf("invalid seeder list: %w", err)
+	}
+	return seeders, nil
+}
+
+// Status checks every gRPC service and counts the regions. Seeders are
+// listed only when the viewer address is known.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	st := &Status{Services: make(map[string]string)}
+	for _, service := range []string{"", mapper.Maps_ServiceDesc.ServiceName, registrypb.Registry_ServiceDesc.ServiceName} {
+		resp, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return nil, err
+		}
+		name := service
+		if name == "" {
+			name = "server"
+		}
+		st.Services[name] = resp.Status.String()
+	}
+
+	regions, err := c.ListRegions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, region := range regions {
+		rs := RegionStatus{
+			Name:     region.Name,
+			Gateways: region.GatewayCount,
+			Agents:   region.AgentCount,
+		}
+		if len(c.viewers) > 0 {
+			if rs.Seeders, err = c.Seeders(ctx, region.Name); err != nil {
+				return nil, err
+			}
+		}
+		st.Regions = append(st.Regions, rs)
+	}
+	return st, nil
+}
+
+func decodeProto(r io.Reader, m proto.Message) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, m)
+}
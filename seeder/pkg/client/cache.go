@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	"google.golang.org/protobuf/proto"
+)
+
+type cacheKey struct {
+	region string
+	domain string
+}
+
+type cacheEntry struct {
+	resp    *mapper.AgentResponse
+	expires time.Time
+}
+
+// resolveCache holds ResolveAgent answers while the change stream that
+// invalidates them is connected. Without the stream nothing is served from
+// it, an entry could be stale without anyone noticing.
+type resolveCache struct {
+	ttl time.Duration
+
+	mu   sync.Mutex
+	live bool
+	// gen counts invalidations, an answer fetched across one is not stored
+	gen     uint64
+	entries map[cacheKey]cacheEntry
+}
+
+func newResolveCache(ttl time.Duration) *resolveCache {
+	return &resolveCache{
+		ttl:     ttl,
+		entries: make(map[cacheKey]cacheEntry),
+	}
+}
+
+func (rc *resolveCache) get(region, domain string) (*mapper.AgentResponse, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if !rc.live {
+		return nil, false
+	}
+	key := cacheKey{region, domain}
+	e, ok := rc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(rc.entries, key)
+		return nil, false
+	}
+	return proto.Clone(e.resp).(*mapper.AgentResponse), true
+}
+
+// generation is taken before a lookup and handed to put.
+func (rc *resolveCache) generation() uint64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.gen
+}
+
+func (rc *resolveCache) put(region, domain string, resp *mapper.AgentResponse, gen uint64) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if !rc.live || gen != rc.gen {
+		return
+	}
+	rc.entries[cacheKey{region, domain}] = cacheEntry{
+		resp:    proto.Clone(resp).(*mapper.AgentResponse),
+		expires: time.Now().Add(rc.ttl),
+	}
+}
+
+// setLive starts or stops serving from the cache. Both ways it starts
+// empty, changes may have been missed in between.
+func (rc *resolveCache) setLive(live bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.live = live
+	rc.gen++
+	clear(rc.entries)
+}
+
+// invalidate drops the entries ev may have changed: the names the agent
+// answered or its domain matches, since it may now be the most specific
+// match, or every agent on a changed gateway.
+func (rc *resolveCache) invalidate(ev Event) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.gen++
+	switch ev.Resource {
+	case "agent":
+		for key, e := range rc.entries {
+			if key.region == ev.Region && (e.resp.AgentDomain == ev.Key || domainMatches(ev.Key, key.domain)) {
+				delete(rc.entries, key)
+			}
+		}
+	case "gateway":
+		for key, e := range rc.entries {
+			if key.region == ev.Region && e.resp.GatewayId == ev.Key {
+				delete(rc.entries, key)
+			}
+		}
+	default:
+		// reset, or an event this client does not know
+		clear(rc.entries)
+	}
+}
+
+// domainMatches reports whether the agent domain pattern routes name: the
+// name itself, *.name one label below it, .name the name and all below it.
+func domainMatches(pattern, name string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	switch {
+	case strings.HasPrefix(pattern, "*."):
+		label, found := strings.CutSuffix(name, pattern[1:])
+		return found && label != "" && !strings.Contains(label, ".")
+	case strings.HasPrefix(pattern, "."):
+		return name == pattern[1:] || strings.HasSuffix(name, pattern)
+	}
+	return name == pattern
+}
+
+// invalidateLoop keeps a change stream open for the cache, reconnecting
+// with backoff, until ctx is done.
+func (c *Client) invalidateLoop(ctx context.Context) {
+	var last string
+	for attempt := 0; ; attempt++ {
+		c.Watch(ctx, WatchOptions{
+			LastEventID: last,
+			Connected: func() {
+				attempt = 0
+				c.cache.setLive(true)
+			},
+		}, func(ev Event) error {
+			last = ev.ID
+			c.cache.invalidate(ev)
+			return nil
+		})
+		c.cache.setLive(false)
+
+		if sleep(ctx, backoff(attempt)) != nil {
+			return
+		}
+	}
+}
+
+// resolveCached serves ResolveAgent from the cache when it can.
+func (c *Client) resolveCached(region, agentDomain string, resolve func() (*mapper.AgentResponse, error)) (*mapper.AgentResponse, error) {
+	if resp, ok := c.cache.get(region, agentDomain); ok {
+		return resp, nil
+	}
+	gen := c.cache.generation()
+	resp, err := resolve()
+	if err != nil {
+		return nil, err
+	}
+	c.cache.put(region, agentDomain, resp, gen)
+	return resp, nil
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+)
+
+func TestDomainMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"api.example.com", "api.example.com", true},
+		{"api.example.com", "API.example.com.", true},
+		{"api.example.com", "web.example.com", false},
+		{"*.example.com", "api.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "a.api.example.com", false},
+		{"*.example.com", "apiexample.com", false},
+		{".example.com", "example.com", true},
+		{".example.com", "a.api.example.com", true},
+		{".example.com", "badexample.com", false},
+	}
+	for _, tt := range tests {
+		if got := domainMatches(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("domainMatches(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+// newTestCache is live and holds, in eu, api.example.com answered by
+// *.example.com and a.example.org on g1 and b.example.org on g2, and
+// api.example.com in us on g1.
+func newTestCache() *resolveCache {
+	rc := newResolveCache(time.Minute)
+	rc.setLive(true)
+	gen := rc.generation()
+	rc.put("eu", "api.example.com", &mapper.AgentResponse{AgentDomain: "*.example.com", GatewayId: "g1"}, gen)
+	rc.put("eu", "a.example.org", &mapper.AgentResponse{AgentDomain: "a.example.org", GatewayId: "g1"}, gen)
+	rc.put("eu", "b.example.org", &mapper.AgentResponse{AgentDomain: "b.example.org", GatewayId: "g2"}, gen)
+	rc.put("us", "api.example.com", &mapper.AgentResponse{AgentDomain: "api.example.com", GatewayId: "g1"}, gen)
+	return rc
+}
+
+func cached(rc *resolveCache) map[cacheKey]bool {
+	keys := make(map[cacheKey]bool)
+	for key := range rc.entries {
+		keys[key] = true
+	}
+	return keys
+}
+
+func TestInvalidate(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   Event
+		gone []cacheKey
+	}{
+		{
+			name: "agent that answered",
+			ev:   Event{Resource: "agent", Region: "eu", Key: "*.example.com"},
+			gone: []cacheKey{{"eu", "api.example.com"}},
+		},
+		{
+			// api.example.com may now be answered by the new exact claim
+			name: "agent matching a cached name",
+			ev:   Event{Resource: "agent", Region: "eu", Key: "api.example.com"},
+			gone: []cacheKey{{"eu", "api.example.com"}},
+		},
+		{
+			name: "suffix agent",
+			ev:   Event{Resource: "agent", Region: "eu", Key: ".example.org"},
+			gone: []cacheKey{{"eu", "a.example.org"}, {"eu", "b.example.org"}},
+		},
+		{
+			name: "unrelated agent",
+			ev:   Event{Resource: "agent", Region: "eu", Key: "c.example.org"},
+		},
+		{
+			name: "gateway",
+			ev:   Event{Resource: "gateway", Region: "eu", Key: "g1"},
+			gone: []cacheKey{{"eu", "api.example.com"}, {"eu", "a.example.org"}},
+		},
+		{
+			name: "reset",
+			ev:   Event{Type: "reset"},
+			gone: []cacheKey{{"eu", "api.example.com"}, {"eu", "a.example.org"}, {"eu", "b.example.org"}, {"us", "api.example.com"}},
+		},
+	}
+	for _, tt := range tests {
+		rc := newTestCache()
+		want := cached(rc)
+		for _, key := range tt.gone {
+			delete(want, key)
+		}
+
+		rc.invalidate(tt.ev)
+		if got := cached(rc); !maps.Equal(got, want) {
+			t.Errorf("%s: cache holds %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestResolveCacheGenerations(t *testing.T) {
+	rc := newResolveCache(time.Minute)
+	resp := &mapper.AgentResponse{AgentDomain: "api.example.com", GatewayId: "g1"}
+
+	rc.put("eu", "api.example.com", resp, rc.generation())
+	if _, ok := rc.get("eu", "api.example.com"); ok {
+		t.Fatal("served from the cache without a change stream")
+	}
+
+	rc.setLive(true)
+	// an answer fetched across an invalidation may be stale
+	gen := rc.generation()
+	rc.invalidate(Event{Resource: "gateway", Region: "eu", Key: "g2"})
+	rc.put("eu", "api.example.com", resp, gen)
+	if _, ok := rc.get("eu", "api.example.com"); ok {
+		t.Fatal("stored an answer fetched across an invalidation")
+	}
+
+	rc.put("eu", "api.example.com", resp, rc.generation())
+	got, ok := rc.get("eu", "api.example.com")
+	if !ok || got.GatewayId != "g1" {
+		t.Fatalf("get() = %v, %v", got, ok)
+	}
+	got.GatewayId = "changed"
+	if again, _ := rc.get("eu", "api.example.com"); again.GatewayId != "g1" {
+		t.Error("get() returned the cached message, not a copy")
+	}
+
+	// losing the stream empties the cache
+	rc.setLive(false)
+	rc.setLive(true)
+	if _, ok := rc.get("eu", "api.example.com"); ok {
+		t.Error("entry survived a reconnect")
+	}
+
+	rc.ttl = -time.Second
+	rc.put("eu", "api.example.com", resp, rc.generation())
+	if _, ok := rc.get("eu", "api.example.com"); ok {
+		t.Error("served an expired entry")
+	}
+}
@@ -0,0 +1,403 @@
+// Package client talks to one or more seeders over gRPC, verifying them by
+// a pinned certificate fingerprint or a CA, and to their viewer ports for
+// the admin API and change events. Calls are balanced round robin over
+// the serving seeders and idempotent calls are retried with backoff.
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	registrypb "github.com/odio4u/memstore/seeder/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/health" // client side health checking
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+var ErrFingerprintMismatch = errors.New("server certificate does not match a pinned fingerprint")
+
+// Endpoint is one seeder.
+type Endpoint struct {
+	// Address is the gRPC host:port
+	Address string
+	// Viewer is the base URL of the viewer port, http://host:9000
+	Viewer string
+	// Fingerprint is the SHA256 of the certificate in hex, as printed by
+	// `seeder fingerprint`. It is only trusted for Address and Viewer.
+	Fingerprint string
+}
+
+type Config struct {
+	// Address, Viewer and Fingerprint describe a single seeder, they are
+	// added in front of Seeders
+	Address     string
+	Viewer      string
+	Fingerprint string
+	// Seeders are balanced round robin, a seeder that is down or not
+	// serving is skipped until it recovers
+	Seeders []Endpoint
+	// CAFile verifies the seeder certificates against a CA instead of, or
+	// on top of, the fingerprints
+	CAFile string
+	// CertFile and KeyFile hold the client certificate for mutual TLS,
+	// optional
+	CertFile string
+	KeyFile  string
+	// Signer signs registrations, registry reads and admin calls, nil sends
+	// them unsigned
+	Signer *Signer
+	// Timeout bounds every HTTP request except Watch, defaults to 15s
+	Timeout time.Duration
+	// Attempts bounds the tries of an idempotent call, defaults to 4
+	Attempts int
+	// CacheTTL enables the ResolveAgent cache. Entries are dropped on the
+	// matching change event, and the cache is bypassed while the event
+	// stream is down. Needs a viewer.
+	CacheTTL time.Duration
+}
+
+type Client struct {
+	conn     *grpc.ClientConn
+	maps     mapper.MapsClient
+	registry registrypb.RegistryClient
+	health   healthpb.HealthClient
+
+	http     *http.Client
+	stream   *http.Client
+	viewers  []string
+	next     atomic.Uint32
+	signer   *Signer
+	attempts int
+
+	cache  *resolveCache
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// idempotent are the methods gRPC retries, registrations are not among
+// them because a signed credential is only accepted once.
+var idempotent = []string{
+	`{"service": "seeder.registry.Registry"}`,
+	`{"service": "grpc.health.v1.Health"}`,
+	`{"service": "maps.Maps", "method": "ResolveGatewayForAgent"}`,
+	`{"service": "maps.Maps", "method": "ResolveGatewayForProxy"}`,
+}
+
+func serviceConfig(attempts int) string {
+	return fmt.Sprintf(`{
+	"loadBalancingConfig": [{"round_robin": {}}],
+	"healthCheckConfig": {"serviceName": ""},
+	"methodConfig": [{
+		"name": [%s],
+		"retryPolicy": {
+			"maxAttempts": %d,
+			"initialBackoff": "0.1s",
+			"maxBackoff": "2s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`, strings.Join(idempotent, ", "), attempts)
+}
+
+// PinnedTLS returns a client TLS config accepting a server certificate
+// whose SHA256 is one of fingerprints. The seeder certificate is self
+// signed, so without roots the pin replaces chain verification; with roots
+// the chain is verified as well.
+func PinnedTLS(fingerprints []string, roots *x509.CertPool, certs ...tls.Certificate) (*tls.Config, error) {
+	var pins [][]byte
+	for _, fp := range fingerprints {
+		pin, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(fp), ":", ""))
+		if err != nil || len(pin) != sha256.Size {
+			return nil, fmt.Errorf("fingerprint %q must be %d hex encoded bytes", fp, sha256.Size)
+		}
+		pins = append(pins, pin)
+	}
+	if len(pins) == 0 && roots == nil {
+		return nil, errors.New("a fingerprint or a CA is required to verify the seeder")
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: certs,
+		RootCAs:      roots,
+	}
+	if len(pins) == 0 {
+		return config, nil
+	}
+
+	// VerifyConnection checks the chain itself when there are roots, the
+	// pin alone is enough for the self signed certificate
+	config.InsecureSkipVerify = true
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return ErrFingerprintMismatch
+		}
+		leaf := cs.PeerCertificates[0]
+		if roots != nil {
+			opts := x509.VerifyOptions{Roots: roots, DNSName: cs.ServerName, Intermediates: x509.NewCertPool()}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			if _, err := leaf.Verify(opts); err != nil {
+				return err
+			}
+		}
+		sum := sha256.Sum256(leaf.Raw)
+		for _, pin := range pins {
+			if subtle.ConstantTimeCompare(sum[:], pin) == 1 {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: got %x", ErrFingerprintMismatch, sum)
+	}
+	return config, nil
+}
+
+// endpointCreds hands every seeder's handshake the TLS config pinning its
+// own fingerprint. Addresses carry their host:port as server name, so the
+// authority a handshake gets tells the seeders apart even on one host.
+type endpointCreds struct {
+	credentials.TransportCredentials
+	byAddr map[string]credentials.TransportCredentials
+}
+
+func (c *endpointCreds) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	creds, ok := c.byAddr[authority]
+	if !ok {
+		conn.Close()
+		return nil, nil, fmt.Errorf("%w: %s is not a configured seeder", ErrFingerprintMismatch, authority)
+	}
+	return creds.ClientHandshake(ctx, authority, conn)
+}
+
+func (c *endpointCreds) Clone() credentials.TransportCredentials {
+	return &endpointCreds{TransportCredentials: c.TransportCredentials.Clone(), byAddr: maps.Clone(c.byAddr)}
+}
+
+// dialViewerTLS connects to a viewer with the TLS config of the seeder
+// serving it, looked up by the host:port dialed.
+func dialViewerTLS(byAddr map[string]*tls.Config) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		config, ok := byAddr[addr]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not a configured viewer", ErrFingerprintMismatch, addr)
+		}
+		raw, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		config = config.Clone()
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+		config.NextProtos = []string{"h2", "http/1.1"}
+		conn := tls.Client(raw, config)
+		if err := conn.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// viewerHostPort is the address a viewer URL is dialed at.
+func viewerHostPort(viewer string) (string, error) {
+	u, err := url.Parse(viewer)
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+func loadRoots(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: no certificates found", path)
+	}
+	return roots, nil
+}
+
+// Dial connects to the seeders. Connections are established lazily, a
+// wrong fingerprint shows up as an error of the first call.
+func Dial(config Config) (*Client, error) {
+	endpoints := config.Seeders
+	if config.Address != "" {
+		endpoints = append([]Endpoint{{Address: config.Address, Viewer: config.Viewer, Fingerprint: config.Fingerprint}}, endpoints...)
+	}
+	if len(endpoints) == 0 {
+		return nil, errors.New("seeder address is required")
+	}
+
+	var certs []tls.Certificate
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	roots, err := loadRoots(config.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA: %w", err)
+	}
+
+	// every seeder is held to its own fingerprint, a pin of one seeder
+	// must not vouch for a certificate served at another address
+	var viewers []string
+	var addrs []resolver.Address
+	creds := &endpointCreds{byAddr: make(map[string]credentials.TransportCredentials)}
+	viewerTLS := make(map[string]*tls.Config)
+	for _, e := range endpoints {
+		if _, _, err := net.SplitHostPort(e.Address); err != nil {
+			return nil, fmt.Errorf("invalid seeder address %q: %w", e.Address, err)
+		}
+		var fingerprints []string
+		if e.Fingerprint != "" {
+			fingerprints = append(fingerprints, e.Fingerprint)
+		}
+		tlsConfig, err := PinnedTLS(fingerprints, roots, certs...)
+		if err != nil {
+			return nil, fmt.Errorf("seeder %s: %w", e.Address, err)
+		}
+		creds.byAddr[e.Address] = credentials.NewTLS(tlsConfig)
+		if creds.TransportCredentials == nil {
+			creds.TransportCredentials = creds.byAddr[e.Address]
+		}
+
+		if e.Viewer != "" {
+			viewer := strings.TrimSuffix(e.Viewer, "/")
+			addr, err := viewerHostPort(viewer)
+			if err != nil {
+				return nil, fmt.Errorf("invalid viewer %q: %w", e.Viewer, err)
+			}
+			viewers = append(viewers, viewer)
+			viewerTLS[addr] = tlsConfig
+		}
+		// the handshake strips the port, the chain is verified against the
+		// seeder's host rather than the dial target
+		addrs = append(addrs, resolver.Address{Addr: e.Address, ServerName: e.Address})
+	}
+
+	attempts := config.Attempts
+	if attempts <= 0 {
+		attempts = 4
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+
+	r := manual.NewBuilderWithScheme("seeder")
+	r.InitialState(resolver.State{Addresses: addrs})
+	conn, err := grpc.NewClient(r.Scheme()+":///seeders",
+		grpc.WithResolvers(r),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(serviceConfig(attempts)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// the viewer serves the same certificate when viewer_tls is set
+	transport := &http.Transport{
+		DialTLSContext:    dialViewerTLS(viewerTLS),
+		ForceAttemptHTTP2: true,
+	}
+
+	c := &Client{
+		conn:     conn,
+		maps:     mapper.NewMapsClient(conn),
+		registry: registrypb.NewRegistryClient(conn),
+		health:   healthpb.NewHealthClient(conn),
+		http:     &http.Client{Transport: transport, Timeout: timeout},
+		stream:   &http.Client{Transport: transport},
+		viewers:  viewers,
+		signer:   config.Signer,
+		attempts: attempts,
+	}
+
+	if config.CacheTTL > 0 {
+		if len(viewers) == 0 {
+			conn.Close()
+			return nil, fmt.Errorf("the resolve cache needs a viewer: %w", ErrNoViewer)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		c.cache = newResolveCache(config.CacheTTL)
+		c.cancel = cancel
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.invalidateLoop(ctx)
+		}()
+	}
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		c.wg.Wait()
+	}
+	c.http.CloseIdleConnections()
+	return c.conn.Close()
+}
+
+// Maps and Registry give access to the raw service clients.
+func (c *Client) Maps() mapper.MapsClient {
+	return c.maps
+}
+
+func (c *Client) Registry() registrypb.RegistryClient {
+	return c.registry
+}
+
+// backoff is exponential from 100ms up to 2s with full jitter.
+func backoff(attempt int) time.Duration {
+	d := min(100*time.Millisecond<<min(attempt, 5), 2*time.Second)
+	return time.Duration(rand.Int64N(int64(d))) + 1
+}
+
+// sleep waits d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
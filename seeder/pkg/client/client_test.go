@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func fingerprint(srv *httptest.Server) string {
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPinnedTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	get := func(pin string) error {
+		config, err := PinnedTLS([]string{pin}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// httptest serves TLS 1.3
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		resp, err := c.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(fingerprint(srv)); err != nil {
+		t.Errorf("GET with the right pin error = %v", err)
+	}
+	other := sha256.Sum256([]byte("another certificate"))
+	if err := get(hex.EncodeToString(other[:])); !errors.Is(err, ErrFingerprintMismatch) {
+		t.Errorf("GET with another pin error = %v, want %v", err, ErrFingerprintMismatch)
+	}
+
+	for _, pins := range [][]string{nil, {""}, {"zz"}, {"abcd"}} {
+		if _, err := PinnedTLS(pins, nil); err == nil {
+			t.Errorf("PinnedTLS(%q) succeeded", pins)
+		}
+	}
+}
+
+func TestAdminCall(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := identity.NewVerifier(map[string]string{"ops": base64.StdEncoding.EncodeToString(pub)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/gateways/g1/drain" || r.URL.Query().Get("region") != "eu" {
+			t.Errorf("request = %s %s", r.Method, r.URL)
+		}
+		cred, err := identity.FromHeader(r.Header)
+		if err == nil {
+			_, err = verifier.Verify(cred, "DrainGateway", "eu", "g1")
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":401,"status":"UNAUTHENTICATED","message":"bad signature"}}`))
+			return
+		}
+		w.Write([]byte(`{"gatewayId":"g1","region":"eu","someFutureField":true}`))
+	}))
+	defer srv.Close()
+
+	dial := func(signer *Signer) *Client {
+		c, err := Dial(Config{Address: "127.0.0.1:1", Viewer: srv.URL + "/", Fingerprint: fingerprint(srv), Signer: signer})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+	ctx := context.Background()
+
+	gateway, err := dial(&Signer{Issuer: "ops", Subject: "alice", Key: priv}).DrainGateway(ctx, "eu", "g1")
+	if err != nil {
+		t.Fatalf("signed DrainGateway() error = %v", err)
+	}
+	if gateway.GatewayId != "g1" || gateway.Region != "eu" {
+		t.Errorf("DrainGateway() = %v", gateway)
+	}
+
+	_, err = dial(nil).DrainGateway(ctx, "eu", "g1")
+	if st := status.Convert(err); st.Code() != codes.Unauthenticated || st.Message() != "bad signature" {
+		t.Errorf("unsigned DrainGateway() error = %v, want the server's status", err)
+	}
+
+	noViewer, err := Dial(Config{Address: "127.0.0.1:1", Fingerprint: fingerprint(srv)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer noViewer.Close()
+	if _, err := noViewer.DrainGateway(ctx, "eu", "g1"); !errors.Is(err, ErrNoViewer) {
+		t.Errorf("DrainGateway() without a viewer error = %v, want %v", err, ErrNoViewer)
+	}
+}
+
+func TestViewerFailover(t *testing.T) {
+	var flakyCalls atomic.Int32
+	flaky := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flakyCalls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":{"code":503,"status":"UNAVAILABLE","message":"replaying"}}`))
+	}))
+	defer flaky.Close()
+	up := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"SeederID":"s1","Region":"eu"}]`))
+	}))
+	defer up.Close()
+	down := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	endpoint := func(srv *httptest.Server) Endpoint {
+		return Endpoint{Address: "127.0.0.1:1", Viewer: srv.URL, Fingerprint: fingerprint(srv)}
+	}
+	c, err := Dial(Config{Seeders: []Endpoint{endpoint(down), endpoint(flaky), endpoint(up)}, Attempts: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	// every start in the rotation reaches the seeder that is up
+	for range 3 {
+		seeders, err := c.Seeders(ctx, "eu")
+		if err != nil || len(seeders) != 1 || seeders[0].SeederID != "s1" {
+			t.Fatalf("Seeders() = %v, %v", seeders, err)
+		}
+	}
+
+	// a write that reached a seeder is not sent again
+	c, err = Dial(Config{Seeders: []Endpoint{endpoint(flaky)}, Attempts: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	flakyCalls.Store(0)
+	_, err = c.DrainGateway(ctx, "eu", "g1")
+	if status.Code(err) != codes.Unavailable || flakyCalls.Load() != 1 {
+		t.Errorf("DrainGateway() = %v after %d calls, want UNAVAILABLE after 1", err, flakyCalls.Load())
+	}
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	registrypb "github.com/odio4u/memstore/seeder/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// readMethod is the method registry reads are signed as, maps.ReadMethod
+// on the seeder.
+const readMethod = "ReadRegistry"
+
+// inBandCodes reverses the seeder's mapping of statuses onto the in-band
+// error codes, so callers check status.Code either way.
+var inBandCodes = map[mapper.ErrorCode]codes.Code{
+	mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT: codes.InvalidArgument,
+	mapper.ErrorCode_ERROR_CODE_NOT_FOUND:        codes.NotFound,
+	mapper.ErrorCode_ERROR_CODE_ALREADY_EXISTS:   codes.AlreadyExists,
+	mapper.ErrorCode_ERROR_CODE_UNAVAILABLE:      codes.Unavailable,
+	mapper.ErrorCode_ERROR_CODE_INTERNAL:         codes.Internal,
+	mapper.ErrorCode_ERROR_CODE_UNAUTHORIZED:     codes.PermissionDenied,
+}
+
+// inBandError returns the response Error of a seeder running without
+// status_errors as a status error.
+func inBandError(e *mapper.Error) error {
+	if e == nil {
+		return nil
+	}
+	code, ok := inBandCodes[e.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+	return status.Error(code, e.Message)
+}
+
+func (c *Client) RegisterGateway(ctx context.Context, req *mapper.GatewayPutRequest) (*mapper.GatewayResponse, error) {
+	ctx, err := c.signer.signContext(ctx, "RegisterGateway",
+		req.VerifiableCredHash, req.GatewayIp, fmt.Sprint(req.GatewayPort), req.Region,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.maps.RegisterGateway(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, inBandError(resp.Error)
+}
+
+func (c *Client) RegisterAgent(ctx context.Context, req *mapper.AgentConnectionRequest) (*mapper.AgentResponse, error) {
+	ctx, err := c.signer.signContext(ctx, "RegisterAgent",
+		req.VerifiableCredHash, req.AgentDomain, req.GatewayId, req.Region,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.maps.RegisterAgent(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, inBandError(resp.Error)
+}
+
+// ResolveAgent returns the gateway serving agentDomain in region, from
+// the cache when Config.CacheTTL is set.
+func (c *Client) ResolveAgent(ctx context.Context, region, agentDomain string) (*mapper.AgentResponse, error) {
+	resolve := func() (*mapper.AgentResponse, error) {
+		resp, err := c.maps.ResolveGatewayForProxy(ctx, &mapper.ProxyMapping{
+			Region:      region,
+			AgentDomain: agentDomain,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp, inBandError(resp.Error)
+	}
+
+	if c.cache == nil {
+		return resolve()
+	}
+	return c.resolveCached(region, agentDomain, resolve)
+}
+
+// ResolveGateways returns the gateways the seeder offers a new agent in
+// region.
+func (c *Client) ResolveGateways(ctx context.Context, region string) ([]*mapper.GatewayResponse, error) {
+	resp, err := c.maps.ResolveGatewayForAgent(ctx, &mapper.GatewayHandshake{Region: region})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Gateways, inBandError(resp.Error)
+}
+
+// ListRegions follows the pages to the end.
+func (c *Client) ListRegions(ctx context.Context) ([]*registrypb.Region, error) {
+	var regions []*registrypb.Region
+	req := &registrypb.ListRegionsRequest{}
+	for {
+		// every page needs its own nonce
+		readCtx, err := c.signer.signContext(ctx, readMethod)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.registry.ListRegions(readCtx, req)
+		if err != nil {
+			return nil, err
+		}
+		regions = append(regions, resp.Regions...)
+		if resp.NextPageToken == "" {
+			return regions, nil
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+// ListGateways follows the pages to the end. req carries the filters, its
+// page token is overwritten.
+func (c *Client) ListGateways(ctx context.Context, req *registrypb.ListGatewaysRequest) ([]*registrypb.Gateway, error) {
+	var gateways []*registrypb.Gateway
+	req.PageToken = ""
+	for {
+		// every page needs its own nonce
+		readCtx, err := c.signer.signContext(ctx, readMethod)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.registry.ListGateways(readCtx, req)
+		if err != nil {
+			return nil, err
+		}
+		gateways = append(gateways, resp.Gateways...)
+		if resp.NextPageToken == "" {
+			return gateways, nil
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+// ListAgents follows the pages to the end. req carries the filters, its
+// page token is overwritten.
+func (c *Client) ListAgents(ctx context.Context, req *registrypb.ListAgentsRequest) ([]*registrypb.Agent, error) {
+	var agents []*registrypb.Agent
+	req.PageToken = ""
+	for {
+		// every page needs its own nonce
+		readCtx, err := c.signer.signContext(ctx, readMethod)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.registry.ListAgents(readCtx, req)
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, resp.Agents...)
+		if resp.NextPageToken == "" {
+			return agents, nil
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+func (c *Client) GetGateway(ctx context.Context, region, gatewayID string) (*registrypb.Gateway, error) {
+	ctx, err := c.signer.signContext(ctx, readMethod)
+	if err != nil {
+		return nil, err
+	}
+	return c.registry.GetGateway(ctx, &registrypb.GetGatewayRequest{
+		Region:    region,
+		GatewayId: gatewayID,
+	})
+}
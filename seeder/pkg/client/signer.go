@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+)
+
+// Signer issues the signed credentials the seeder checks on registrations
+// and admin calls when Identity.issuers is configured.
+type Signer struct {
+	Issuer  string
+	Subject string
+	Key     ed25519.PrivateKey
+}
+
+// LoadKey reads a base64 Ed25519 key from path, either the 32 byte seed or
+// the 64 byte private key.
+func LoadKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid key encoding: %w", path, err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("%s: key must be a %d byte seed or a %d byte private key", path, ed25519.SeedSize, ed25519.PrivateKeySize)
+}
+
+// credential signs method and fields with a fresh timestamp and nonce.
+// A nil signer returns nil.
+func (s *Signer) credential(method string, fields ...string) (*identity.Credential, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	cred := &identity.Credential{
+		Issuer:    s.Issuer,
+		Subject:   s.Subject,
+		Timestamp: time.Now().Unix(),
+		Nonce:     hex.EncodeToString(nonce),
+	}
+	identity.Sign(s.Key, cred, method, fields...)
+	return cred, nil
+}
+
+// signContext attaches a credential for method to the outgoing metadata.
+func (s *Signer) signContext(ctx context.Context, method string, fields ...string) (context.Context, error) {
+	cred, err := s.credential(method, fields...)
+	if err != nil || cred == nil {
+		return ctx, err
+	}
+	return identity.AppendToContext(ctx, cred), nil
+}
+
+// signHeader sets the X-Agni-* headers the admin API reads.
+func (s *Signer) signHeader(h http.Header, method string, fields ...string) error {
+	cred, err := s.credential(method, fields...)
+	if err != nil || cred == nil {
+		return err
+	}
+	h.Set(identity.MDIssuer, cred.Issuer)
+	h.Set(identity.MDSubject, cred.Subject)
+	h.Set(identity.MDTimestamp, strconv.FormatInt(cred.Timestamp, 10))
+	h.Set(identity.MDNonce, cred.Nonce)
+	h.Set(identity.MDSignature, base64.StdEncoding.EncodeToString(cred.Signature))
+	return nil
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	registrypb "github.com/odio4u/memstore/seeder/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// EventReset tells the watcher the changes since its last event are gone,
+// after a seeder restart or when it fell too far behind. It should list
+// the registry again.
+const EventReset = "reset"
+
+// Event is one registry change from /events. Name is resource.type, like
+// gateway.put, or reset.
+type Event struct {
+	ID       string
+	Name     string
+	Type     string
+	Resource string
+	Region   string
+	Key      string
+	Time     time.Time
+	Gateway  *registrypb.Gateway
+	Agent    *registrypb.Agent
+}
+
+type WatchOptions struct {
+	Region string
+	// Resources limits the stream to gateway, agent or both
+	Resources []string
+	// LastEventID resumes after an event of an earlier watch
+	LastEventID string
+	// Connected is called once the stream is open, before any event
+	Connected func()
+}
+
+type eventData struct {
+	Type     string          `json:"type"`
+	Resource string          `json:"resource"`
+	Region   string          `json:"region"`
+	Key      string          `json:"key"`
+	Time     time.Time       `json:"time"`
+	Gateway  json.RawMessage `json:"gateway"`
+	Agent    json.RawMessage `json:"agent"`
+}
+
+func parseEvent(id, name string, data []byte) (Event, error) {
+	ev := Event{ID: id, Name: name}
+	if name == EventReset {
+		return ev, nil
+	}
+
+	var body eventData
+	if err := json.Unmarshal(data, &body); err != nil {
+		return ev, fmt.Errorf("invalid event %s: %w", id, err)
+	}
+	ev.Type, ev.Resource, ev.Region, ev.Key, ev.Time = body.Type, body.Resource, body.Region, body.Key, body.Time
+
+	unmarshal := protojson.UnmarshalOptions{DiscardUnknown: true}
+	if len(body.Gateway) > 0 {
+		ev.Gateway = &registrypb.Gateway{}
+		if err := unmarshal.Unmarshal(body.Gateway, ev.Gateway); err != nil {
+			return ev, fmt.Errorf("invalid event %s: %w", id, err)
+		}
+	}
+	if len(body.Agent) > 0 {
+		ev.Agent = &registrypb.Agent{}
+		if err := unmarshal.Unmarshal(body.Agent, ev.Agent); err != nil {
+			return ev, fmt.Errorf("invalid event %s: %w", id, err)
+		}
+	}
+	return ev, nil
+}
+
+// openEvents connects to the first viewer that accepts the stream.
+func (c *Client) openEvents(ctx context.Context, opts WatchOptions) (*http.Response, error) {
+	query := url.Values{}
+	if opts.Region != "" {
+		query.Set("region", opts.Region)
+	}
+	if len(opts.Resources) > 0 {
+		query.Set("resource", strings.Join(opts.Resources, ","))
+	}
+
+	var lastErr error
+	for _, viewer := range c.viewerOrder() {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, viewer+"/events?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		if opts.LastEventID != "" {
+			req.Header.Set("Last-Event-ID", opts.LastEventID)
+		}
+		if err := c.signer.signHeader(req.Header, readMethod); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.stream.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		lastErr = httpError(resp)
+		resp.Body.Close()
+		// a bad filter fails on every seeder
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			return nil, lastErr
+		}
+	}
+	return nil, lastErr
+}
+
+// Watch calls fn for every change until ctx is done, fn fails or the
+// stream ends. It connects to the first viewer that answers; resume a
+// broken watch with the ID of the last event seen. Event ids are per
+// seeder, resuming on another one starts with a reset.
+func (c *Client) Watch(ctx context.Context, opts WatchOptions, fn func(Event) error) error {
+	if len(c.viewers) == 0 {
+		return ErrNoViewer
+	}
+
+	resp, err := c.openEvents(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if opts.Connected != nil {
+		opts.Connected()
+	}
+
+	var id, name string
+	var data []byte
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// a blank line ends the event, comments are keepalives
+			if name != "" {
+				ev, err := parseEvent(id, name, data)
+				if err != nil {
+					return err
+				}
+				if err := fn(ev); err != nil {
+					return err
+				}
+			}
+			id, name, data = "", "", nil
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			id = value
+		case "event":
+			name = value
+		case "data":
+			data = append(data, value...)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return scanner.Err()
+}
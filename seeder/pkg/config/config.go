@@ -0,0 +1,263 @@
+// Package config is the seeder configuration: the YAML file over the
+// defaults, then SEEDER_* environment variables, then -set flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/odio4u/memstore/seeder/pkg/acl"
+	"github.com/odio4u/memstore/seeder/pkg/diag"
+	"github.com/odio4u/memstore/seeder/pkg/limits"
+	"github.com/odio4u/memstore/seeder/pkg/logging"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/tracing"
+	"gopkg.in/yaml.v3"
+)
+
+// The server certificate and key gen-cert writes to the data directory.
+const (
+	CertFile    = "server.pem"
+	CertKeyFile = "server-key.pem"
+)
+
+// WAL sync modes.
+const (
+	SyncAlways   = "always"
+	SyncInterval = "interval"
+	SyncNone     = "none"
+)
+
+// Port is a TCP port. The file may give it as a number or a string.
+type Port int
+
+func (p *Port) UnmarshalYAML(node *yaml.Node) error {
+	n, err := strconv.Atoi(node.Value)
+	if node.Kind != yaml.ScalarNode || err != nil {
+		return fmt.Errorf("line %d: %q is not a port number", node.Line, node.Value)
+	}
+	*p = Port(n)
+	return nil
+}
+
+func (p Port) String() string {
+	return strconv.Itoa(int(p))
+}
+
+type Seeder struct {
+	IP     string `yaml:"ip"`
+	Port   Port   `yaml:"port"`
+	Dns    string `yaml:"dns"`
+	Name   string `yaml:"name"`
+	Viewer Port   `yaml:"viewer"`
+	Region string `yaml:"region"`
+	// StatusErrors switches maps handlers from in-band errors to gRPC statuses
+	StatusErrors bool `yaml:"status_errors"`
+	// ViewerTLS serves the viewer port over HTTPS with the server certificate
+	ViewerTLS bool `yaml:"viewer_tls"`
+}
+
+type Issuer struct {
+	ID        string `yaml:"id"`
+	PublicKey string `yaml:"public_key"`
+}
+
+type Identity struct {
+	MaxSkew time.Duration `yaml:"max_skew"`
+	Issuers []Issuer      `yaml:"issuers"`
+	// ACL limits verified subjects to methods, empty allows them all
+	ACL []acl.Rule `yaml:"acl"`
+}
+
+type WAL struct {
+	// KeyFile holds the key-encryption key, empty keeps the WAL in plaintext
+	KeyFile string `yaml:"key_file"`
+	// Sync is when appends reach the disk: always fsyncs each one before
+	// it is acknowledged, interval every SyncInterval, none leaves it to
+	// the OS
+	Sync         string        `yaml:"sync"`
+	SyncInterval time.Duration `yaml:"sync_interval"`
+}
+
+type Audit struct {
+	// KeyFile holds the key of the audit log MACs, empty generates one in
+	// the data dir
+	KeyFile string `yaml:"key_file"`
+}
+
+type Snapshot struct {
+	// Interval folds the WAL into the snapshot periodically, 0 disables it
+	Interval time.Duration `yaml:"interval"`
+	// MinRecords skips a periodic snapshot until this many records were
+	// appended since the last one
+	MinRecords uint64 `yaml:"min_records"`
+	// OnShutdown takes a last snapshot once the servers are stopped, so the
+	// next start has no WAL to replay
+	OnShutdown bool `yaml:"on_shutdown"`
+}
+
+type Shutdown struct {
+	// Delay keeps serving after readiness fails, for load balancers to
+	// stop sending calls
+	Delay time.Duration `yaml:"delay"`
+	// Timeout bounds draining the gRPC and HTTP servers, calls still
+	// running are cancelled
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type TLS struct {
+	// CertFile and KeyFile default to the gen-cert files in the data dir
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientAuth is none, request or require. A client certificate that
+	// verifies against ClientCA names the caller in logs and limits; without
+	// ClientCA no certificate is asked for.
+	ClientAuth string `yaml:"client_auth"`
+	ClientCA   string `yaml:"client_ca"`
+}
+
+type Limits struct {
+	PerIdentity     limits.Rate            `yaml:"per_identity"`
+	PerMethod       map[string]limits.Rate `yaml:"per_method"`
+	memstore.Quotas `yaml:",inline"`
+}
+
+type Placement struct {
+	// Strategy orders the gateways offered to new agents: rank,
+	// round_robin or random
+	Strategy string `yaml:"strategy"`
+}
+
+// Peer is another seeder, listed with this one to clients.
+type Peer struct {
+	// ID defaults to Name
+	ID     string `yaml:"id"`
+	Name   string `yaml:"name"`
+	IP     string `yaml:"ip"`
+	Dns    string `yaml:"dns"`
+	Port   Port   `yaml:"port"`
+	Region string `yaml:"region"`
+	// Fingerprint is the SHA256 of the peer's server certificate
+	Fingerprint string `yaml:"fingerprint"`
+}
+
+type Cluster struct {
+	// ID names this seeder in the seeder list, it defaults to Seeder.name
+	ID    string `yaml:"id"`
+	Peers []Peer `yaml:"peers"`
+}
+
+type Config struct {
+	Version string `yaml:"version"`
+	// DataDir holds the certificates, WAL, snapshot, keyring and audit log
+	DataDir   string                   `yaml:"data_dir"`
+	Seeder    Seeder                   `yaml:"Seeder"`
+	Identity  Identity                 `yaml:"Identity"`
+	WAL       WAL                      `yaml:"WAL"`
+	Snapshot  Snapshot                 `yaml:"Snapshot"`
+	Audit     Audit                    `yaml:"Audit"`
+	Shutdown  Shutdown                 `yaml:"Shutdown"`
+	TLS       TLS                      `yaml:"TLS"`
+	Limits    Limits                   `yaml:"Limits"`
+	Placement Placement                `yaml:"Placement"`
+	Rebalance memstore.RebalanceConfig `yaml:"Rebalance"`
+	Logging   logging.Config           `yaml:"Logging"`
+	Tracing   tracing.Config           `yaml:"Tracing"`
+	Debug     diag.Config              `yaml:"Debug"`
+	Cluster   Cluster                  `yaml:"Cluster"`
+}
+
+// Default is the config of an empty file.
+func Default() *Config {
+	return &Config{
+		DataDir: ".",
+		Seeder: Seeder{
+			Port:   50051,
+			Viewer: 9000,
+		},
+		WAL: WAL{
+			Sync:         SyncInterval,
+			SyncInterval: time.Second,
+		},
+		Snapshot: Snapshot{
+			MinRecords: 1,
+		},
+		Shutdown: Shutdown{
+			Timeout: 30 * time.Second,
+		},
+		TLS: TLS{
+			ClientAuth: "request",
+		},
+		Placement: Placement{
+			Strategy: string(memstore.StrategyRank),
+		},
+		Rebalance: memstore.RebalanceConfig{
+			Rate: 10,
+		},
+		Logging: logging.Config{
+			Level:  "info",
+			Format: "text",
+		},
+	}
+}
+
+// Load reads path over the defaults and applies the SEEDER_* environment.
+// An empty path only takes the defaults and the environment. With strict
+// set, keys and SEEDER_* variables the Config does not know are errors
+// instead of being ignored.
+func Load(path string, strict bool) (*Config, error) {
+	config := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(strict)
+		if err := dec.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+	}
+
+	if err := config.applyEnv(os.Environ(), strict); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Path is name inside the data directory.
+func (c *Config) Path(name string) string {
+	return filepath.Join(c.DataDir, name)
+}
+
+// CertPath is the server certificate, TLS.cert_file or the one in the data
+// directory.
+func (c *Config) CertPath() string {
+	if c.TLS.CertFile != "" {
+		return c.TLS.CertFile
+	}
+	return c.Path(CertFile)
+}
+
+func (c *Config) CertKeyPath() string {
+	if c.TLS.KeyFile != "" {
+		return c.TLS.KeyFile
+	}
+	return c.Path(CertKeyFile)
+}
+
+// SeederID is this seeder's id in the seeder list.
+func (c *Config) SeederID() string {
+	if c.Cluster.ID != "" {
+		return c.Cluster.ID
+	}
+	return c.Seeder.Name
+}
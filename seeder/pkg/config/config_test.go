@@ -0,0 +1,207 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "seeder-config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+Seeder:
+  name: seeder
+  port: "7000"
+  colour: blue
+WAL:
+  sync: always
+`)
+	t.Setenv("SEEDER_REGION", "eu")
+	t.Setenv("SEEDER_WAL_SYNC_INTERVAL", "5s")
+
+	config, err := Load(path, false)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	// file over the defaults, the environment over the file
+	if config.Seeder.Name != "seeder" || config.Seeder.Port != 7000 || config.Seeder.Viewer != 9000 {
+		t.Errorf("Load() Seeder = %+v", config.Seeder)
+	}
+	if config.Seeder.Region != "eu" || config.WAL.Sync != SyncAlways || config.WAL.SyncInterval != 5*time.Second {
+		t.Errorf("Load() = %+v, %+v, want the environment applied", config.Seeder, config.WAL)
+	}
+
+	if _, err := Load(path, true); err == nil || !strings.Contains(err.Error(), "colour") {
+		t.Errorf("strict Load() error = %v, want the unknown key", err)
+	}
+	if _, err := Load(writeConfig(t, "Seeder:\n  port: http\n"), false); err == nil {
+		t.Error("Load() with a port that is not a number succeeded")
+	}
+
+	config, err = Load("", false)
+	if err != nil || config.DataDir != "." || config.WAL.Sync != SyncInterval {
+		t.Errorf("Load() without a file = %+v, %v, want the defaults", config, err)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		env    string
+		strict bool
+		err    string
+	}{
+		{"SEEDER_PORT=7000", true, ""},
+		{"SEEDER_LOGGING_LEVEL=debug", true, ""},
+		{"SEEDER_PORT=http", false, "invalid SEEDER_PORT"},
+		{"SEEDER_SNAPSHOT_INTERVAL=often", false, "invalid SEEDER_SNAPSHOT_INTERVAL"},
+		{"SEEDER_COLOUR=blue", false, ""},
+		{"SEEDER_COLOUR=blue", true, "unknown environment variable SEEDER_COLOUR"},
+		{"OTHER_PORT=1", true, ""},
+	}
+	for _, tt := range tests {
+		err := Default().applyEnv([]string{tt.env}, tt.strict)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("applyEnv(%s, strict %v) error = %v, want %q", tt.env, tt.strict, err, tt.err)
+		}
+	}
+
+	config := Default()
+	if err := config.applyEnv([]string{"SEEDER_PORT=7000", "SEEDER_LOGGING_LEVEL=debug"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if config.Seeder.Port != 7000 || config.Logging.Level != "debug" {
+		t.Errorf("applyEnv() = %+v, %+v", config.Seeder, config.Logging)
+	}
+}
+
+func TestSet(t *testing.T) {
+	config := Default()
+	for _, assignment := range []string{
+		"Seeder.port=7000",
+		"logging.LEVEL=debug",
+		"WAL.sync_interval=250ms",
+		"Seeder.status_errors=true",
+		"Limits.max_agents_per_gateway=10",
+		"Limits.per_identity.rate=2.5",
+	} {
+		if err := config.Set(assignment); err != nil {
+			t.Errorf("Set(%s) error = %v", assignment, err)
+		}
+	}
+	if config.Seeder.Port != 7000 || config.Logging.Level != "debug" || config.WAL.SyncInterval != 250*time.Millisecond ||
+		!config.Seeder.StatusErrors || config.Limits.MaxAgentsPerGateway != 10 || config.Limits.PerIdentity.Rate != 2.5 {
+		t.Errorf("Set() left %+v", config)
+	}
+
+	for _, assignment := range []string{
+		"Seeder.port",
+		"Seeder.port=http",
+		"Seeder.colour=blue",
+		"WAL.sync_interval=1",
+		// lists of structs are only set in the file
+		"Identity.issuers=ops",
+	} {
+		if err := config.Set(assignment); err == nil {
+			t.Errorf("Set(%s) succeeded", assignment)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	valid := func() *Config {
+		config := Default()
+		config.DataDir = dir
+		config.Seeder.Name = "seeder"
+		config.Seeder.Region = "eu"
+		return config
+	}
+
+	errs := valid().Validate()
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "gen-cert") {
+		t.Errorf("Validate() without certificates = %v, want both missing", errs)
+	}
+	for _, name := range []string{CertFile, CertKeyFile} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if errs := valid().Validate(); len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no problems", errs)
+	}
+
+	tests := []struct {
+		name   string
+		change func(*Config)
+		want   string
+	}{
+		{"name", func(c *Config) { c.Seeder.Name = "" }, "Seeder.name"},
+		{"ip", func(c *Config) { c.Seeder.IP = "localhost" }, "Seeder.ip"},
+		{"port", func(c *Config) { c.Seeder.Port = 70000 }, "Seeder.port"},
+		{"same ports", func(c *Config) { c.Seeder.Viewer = c.Seeder.Port }, "Seeder.viewer"},
+		{"issuer", func(c *Config) { c.Identity.Issuers = []Issuer{{ID: "ops"}} }, "Identity.issuers[0]"},
+		{"issuer key encoding", func(c *Config) {
+			c.Identity.Issuers = []Issuer{{ID: "ops", PublicKey: "not base64"}}
+		}, "Identity.issuers[0].public_key"},
+		{"issuer key size", func(c *Config) {
+			c.Identity.Issuers = []Issuer{{ID: "ops", PublicKey: "AAAAAAAAAAAAAAAAAAAAAA=="}}
+		}, "Identity.issuers[0].public_key"},
+		{"key file", func(c *Config) { c.WAL.KeyFile = filepath.Join(dir, "missing.kek") }, "WAL.key_file"},
+		{"sync", func(c *Config) { c.WAL.Sync = "sometimes" }, "WAL.sync"},
+		{"sync interval", func(c *Config) { c.WAL.SyncInterval = 0 }, "WAL.sync_interval"},
+		{"client auth", func(c *Config) { c.TLS.ClientAuth = "maybe" }, "TLS.client_auth"},
+		{"tls pair", func(c *Config) { c.TLS.CertFile = filepath.Join(dir, CertFile) }, "TLS.cert_file and TLS.key_file"},
+		{"limits", func(c *Config) { c.Limits.PerIdentity.Burst = -1 }, "Limits.per_identity"},
+		{"logging", func(c *Config) { c.Logging.Format = "xml" }, "Logging"},
+		{"debug", func(c *Config) { c.Debug.Listen = "6060" }, "Debug.listen"},
+		{"debug remote", func(c *Config) { c.Debug.Listen = "10.0.0.1:6060" }, "Debug.listen"},
+		{"debug all interfaces", func(c *Config) { c.Debug.Listen = ":6060" }, "Debug.listen"},
+		{"peer", func(c *Config) {
+			c.Cluster.Peers = []Peer{{Name: "s2", Region: "eu", IP: "10.0.0.2", Port: 50051, Fingerprint: "ab"}}
+		}, "Cluster.peers[0].fingerprint"},
+		{"peer id", func(c *Config) {
+			c.Cluster.Peers = []Peer{{Name: "seeder", Region: "eu", IP: "10.0.0.2", Port: 50051}}
+		}, `Cluster.peers[0]: id "seeder" is taken`},
+	}
+	for _, tt := range tests {
+		config := valid()
+		tt.change(config)
+		errs := config.Validate()
+		if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), tt.want) {
+			t.Errorf("%s: Validate() = %v, want one problem about %s", tt.name, errs, tt.want)
+		}
+	}
+
+	accepted := []struct {
+		name   string
+		change func(*Config)
+	}{
+		{"issuer", func(c *Config) {
+			c.Identity.Issuers = []Issuer{{ID: "ops", PublicKey: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}
+		}},
+		{"debug loopback", func(c *Config) { c.Debug.Listen = "127.0.0.1:6060" }},
+		{"debug localhost", func(c *Config) { c.Debug.Listen = "localhost:6060" }},
+		{"debug ipv6 loopback", func(c *Config) { c.Debug.Listen = "[::1]:6060" }},
+		{"debug remote allowed", func(c *Config) {
+			c.Debug.Listen = "10.0.0.1:6060"
+			c.Debug.AllowRemote = true
+		}},
+	}
+	for _, tt := range accepted {
+		config := valid()
+		tt.change(config)
+		if errs := config.Validate(); len(errs) != 0 {
+			t.Errorf("%s: Validate() = %v, want no problems", tt.name, errs)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix starts every environment override.
+const EnvPrefix = "SEEDER_"
+
+// setting is one scalar of the Config, named by its path of YAML keys.
+// Lists of issuers, rules and peers and the per-method limits are only
+// set in the file.
+type setting struct {
+	path  []string
+	value reflect.Value
+}
+
+// key is the path as -set takes it, like WAL.sync.
+func (s setting) key() string {
+	return strings.Join(s.path, ".")
+}
+
+// env is the variable overriding s: SEEDER_ and the upper-cased path. The
+// Seeder section adds no segment, its port is SEEDER_PORT.
+func (s setting) env() string {
+	path := s.path
+	if len(path) > 1 && path[0] == "Seeder" {
+		path = path[1:]
+	}
+	return EnvPrefix + strings.ToUpper(strings.Join(path, "_"))
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (c *Config) settings() []setting {
+	var out []setting
+	collect(reflect.ValueOf(c).Elem(), nil, &out)
+	return out
+}
+
+func collect(v reflect.Value, path []string, out *[]setting) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, flags, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		value := v.Field(i)
+		if flags == "inline" {
+			collect(value, path, out)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		p := append(slices.Clone(path), name)
+		switch {
+		case value.Kind() == reflect.Struct:
+			collect(value, p, out)
+		case scalar(value.Type()):
+			*out = append(*out, setting{path: p, value: value})
+		}
+	}
+}
+
+func scalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// parse sets v from its text form. Durations are like 30s, lists are comma
+// separated.
+func parse(v reflect.Value, text string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(text, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list).Convert(v.Type()))
+	}
+	return nil
+}
+
+// applyEnv sets every setting with a variable in environ, KEY=value pairs
+// as os.Environ returns them.
+func (c *Config) applyEnv(environ []string, strict bool) error {
+	byEnv := make(map[string]setting)
+	for _, s := range c.settings() {
+		byEnv[s.env()] = s
+	}
+
+	for _, kv := range environ {
+		name, text, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		s, ok := byEnv[name]
+		if !ok {
+			if strict {
+				return fmt.Errorf("unknown environment variable %s", name)
+			}
+			continue
+		}
+		if err := parse(s.value, text); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Set applies a key=value override, the key being the YAML path like
+// Seeder.port or logging.level in any case.
+func (c *Config) Set(assignment string) error {
+	key, text, ok := strings.Cut(assignment, "=")
+	if !ok {
+		return fmt.Errorf("override %q is not key=value", assignment)
+	}
+	for _, s := range c.settings() {
+		if strings.EqualFold(s.key(), key) {
+			if err := parse(s.value, text); err != nil {
+				return fmt.Errorf("invalid %s: %w", s.key(), err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown config key %q", key)
+}
+
+// Keys lists every key Set and the environment take, with its variable.
+func (c *Config) Keys() [][2]string {
+	var keys [][2]string
+	for _, s := range c.settings() {
+		keys = append(keys, [2]string{s.key(), s.env()})
+	}
+	return keys
+}
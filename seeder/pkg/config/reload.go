@@ -0,0 +1,64 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// reloadable are the keys a running seeder applies on reload, with every
+// key under them. A change anywhere else needs a restart.
+var reloadable = []string{
+	"Identity",
+	"Limits",
+	"Placement",
+	"Logging.level",
+}
+
+// Reloadable reports whether a change of key applies without a restart.
+func Reloadable(key string) bool {
+	for _, r := range reloadable {
+		if key == r || strings.HasPrefix(key, r+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Changed lists the keys that differ between c and other, down to the keys
+// of a section like Seeder.port or Identity.acl.
+func (c *Config) Changed(other *Config) []string {
+	var keys []string
+	changed(reflect.ValueOf(c).Elem(), reflect.ValueOf(other).Elem(), "", 2, &keys)
+	return keys
+}
+
+func changed(a, b reflect.Value, prefix string, depth int, keys *[]string) {
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, flags, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		x, y := a.Field(i), b.Field(i)
+		if flags == "inline" {
+			changed(x, y, prefix, depth, keys)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		key := prefix + name
+		switch {
+		case reflect.DeepEqual(x.Interface(), y.Interface()):
+		case depth > 1 && x.Kind() == reflect.Struct:
+			changed(x, y, key+".", depth-1, keys)
+		default:
+			*keys = append(*keys, key)
+		}
+	}
+}
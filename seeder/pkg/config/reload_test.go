@@ -0,0 +1,124 @@
+package config
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/odio4u/memstore/seeder/pkg/acl"
+)
+
+func TestChanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   []string
+	}{
+		{
+			name:   "nothing",
+			change: func(*Config) {},
+		},
+		{
+			name:   "top level key",
+			change: func(c *Config) { c.DataDir = "/var/lib/other" },
+			want:   []string{"data_dir"},
+		},
+		{
+			name: "section keys",
+			change: func(c *Config) {
+				c.Seeder.Port++
+				c.Logging.Level = "debug"
+				c.Logging.Format = "json"
+			},
+			want: []string{"Seeder.port", "Logging.level", "Logging.format"},
+		},
+		{
+			name:   "inline quota",
+			change: func(c *Config) { c.Limits.MaxAgentsPerGateway = 10 },
+			want:   []string{"Limits.max_agents_per_gateway"},
+		},
+		{
+			name: "nested struct stops at its section key",
+			change: func(c *Config) {
+				c.Limits.PerIdentity.Burst = 7
+			},
+			want: []string{"Limits.per_identity"},
+		},
+		{
+			name: "slices",
+			change: func(c *Config) {
+				c.Identity.ACL = append(c.Identity.ACL, acl.Rule{})
+				c.Cluster.Peers = append(c.Cluster.Peers, Peer{Name: "seed-2"})
+			},
+			want: []string{"Identity.acl", "Cluster.peers"},
+		},
+	}
+	for _, tt := range tests {
+		next := Default()
+		tt.change(next)
+		if got := Default().Changed(next); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Changed() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReloadable(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"Identity", true},
+		{"Identity.acl", true},
+		{"Identity.issuers", true},
+		{"Limits.per_method", true},
+		{"Limits.max_agents_per_gateway", true},
+		{"Placement.strategy", true},
+		{"Logging.level", true},
+		{"Logging.format", false},
+		{"Logging", false},
+		{"Seeder.port", false},
+		{"WAL.sync", false},
+		{"data_dir", false},
+		// prefixes only match whole keys
+		{"IdentityX", false},
+		{"Logging.levels", false},
+	}
+	for _, tt := range tests {
+		if got := Reloadable(tt.key); got != tt.want {
+			t.Errorf("Reloadable(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+// A reload applies what changed since the last reload and warns about what
+// changed since startup and needs a restart, like cmd/reload.go does.
+func TestReloadAppliedIgnored(t *testing.T) {
+	started := Default()
+	current := Default()
+	if err := current.Set("Logging.level=debug"); err != nil {
+		t.Fatal(err)
+	}
+	next := Default()
+	for _, set := range []string{"Logging.level=debug", "Placement.strategy=random", "Seeder.port=9091", "WAL.sync=none"} {
+		if err := next.Set(set); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var applied, ignored []string
+	for _, key := range current.Changed(next) {
+		if Reloadable(key) {
+			applied = append(applied, key)
+		}
+	}
+	for _, key := range started.Changed(next) {
+		if !Reloadable(key) {
+			ignored = append(ignored, key)
+		}
+	}
+	if want := []string{"Placement.strategy"}; !slices.Equal(applied, want) {
+		t.Errorf("applied = %v, want %v", applied, want)
+	}
+	if want := []string{"Seeder.port", "WAL.sync"}; !slices.Equal(ignored, want) {
+		t.Errorf("ignored = %v, want %v", ignored, want)
+	}
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+	"github.com/odio4u/memstore/seeder/pkg/logging"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+)
+
+func validPort(name string, port Port) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("%s: %d is not a port number, use 1-65535", name, port)
+	}
+	return nil
+}
+
+func validFingerprint(name, fingerprint string) error {
+	raw, err := hex.DecodeString(fingerprint)
+	if err != nil || len(raw) != 32 {
+		return fmt.Errorf("%s: %q is not a hex SHA256 fingerprint", name, fingerprint)
+	}
+	return nil
+}
+
+// loopback reports whether host only accepts local connections. An empty
+// host listens on every interface.
+func loopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Validate reports every problem that would stop or misconfigure a run, so
+// they can be fixed in one go. It also checks that the files the config
+// names exist.
+func (c *Config) Validate() []error {
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.DataDir == "" {
+		check(errors.New("data_dir is required"))
+	} else if info, err := os.Stat(c.DataDir); err == nil && !info.IsDir() {
+		check(fmt.Errorf("data_dir: %s is not a directory", c.DataDir))
+	}
+
+	if c.Seeder.Name == "" {
+		check(errors.New("Seeder.name is required, it is the certificate common name"))
+	}
+	if c.Seeder.Region == "" {
+		check(errors.New("Seeder.region is required"))
+	}
+	if c.Seeder.IP != "" && net.ParseIP(c.Seeder.IP) == nil {
+		check(fmt.Errorf("Seeder.ip: %q is not an IP address", c.Seeder.IP))
+	}
+	check(validPort("Seeder.port", c.Seeder.Port))
+	check(validPort("Seeder.viewer", c.Seeder.Viewer))
+	if c.Seeder.Port == c.Seeder.Viewer {
+		check(fmt.Errorf("Seeder.viewer: %d is the gRPC port too", c.Seeder.Viewer))
+	}
+
+	if c.Identity.MaxSkew < 0 {
+		check(errors.New("Identity.max_skew must not be negative"))
+	}
+	for i, issuer := range c.Identity.Issuers {
+		if issuer.ID == "" || issuer.PublicKey == "" {
+			check(fmt.Errorf("Identity.issuers[%d] needs an id and a public_key", i))
+			continue
+		}
+		if _, err := identity.ParsePublicKey(issuer.PublicKey); err != nil {
+			check(fmt.Errorf("Identity.issuers[%d].public_key: %w", i, err))
+		}
+	}
+	for i, rule := range c.Identity.ACL {
+		if rule.Subject == "" || len(rule.Methods) == 0 {
+			check(fmt.Errorf("Identity.acl[%d] needs a subject and methods", i))
+		}
+	}
+
+	if c.WAL.KeyFile != "" {
+		if _, err := os.Stat(c.WAL.KeyFile); err != nil {
+			check(fmt.Errorf("WAL.key_file: %w", err))
+		}
+	}
+	if c.Audit.KeyFile != "" {
+		if _, err := os.Stat(c.Audit.KeyFile); err != nil {
+			check(fmt.Errorf("Audit.key_file: %w", err))
+		}
+	}
+	switch c.WAL.Sync {
+	case SyncAlways, SyncNone:
+	case SyncInterval:
+		if c.WAL.SyncInterval <= 0 {
+			check(errors.New("WAL.sync_interval must be positive with sync: interval"))
+		}
+	default:
+		check(fmt.Errorf("WAL.sync: unknown mode %q, use always, interval or none", c.WAL.Sync))
+	}
+
+	if c.Snapshot.Interval < 0 {
+		check(errors.New("Snapshot.interval must not be negative"))
+	}
+	if c.Shutdown.Delay < 0 {
+		check(errors.New("Shutdown.delay must not be negative"))
+	}
+	if c.Shutdown.Timeout <= 0 {
+		check(errors.New("Shutdown.timeout must be positive"))
+	}
+
+	switch c.TLS.ClientAuth {
+	case "none", "request", "require":
+	default:
+		check(fmt.Errorf("TLS.client_auth: unknown mode %q, use none, request or require", c.TLS.ClientAuth))
+	}
+	if c.TLS.ClientAuth == "require" && c.TLS.ClientCA == "" {
+		check(errors.New("TLS.client_auth: require needs TLS.client_ca to verify the certificates against"))
+	}
+	if c.TLS.ClientCA != "" {
+		if _, err := os.Stat(c.TLS.ClientCA); err != nil {
+			check(fmt.Errorf("TLS.client_ca: %w", err))
+		}
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		check(errors.New("TLS.cert_file and TLS.key_file go together"))
+	}
+	for _, path := range []string{c.CertPath(), c.CertKeyPath()} {
+		if _, err := os.Stat(path); err != nil {
+			check(fmt.Errorf("TLS: %s is missing, run `seeder gen-cert` or set TLS.cert_file and TLS.key_file", path))
+		}
+	}
+
+	if c.Limits.PerIdentity.Rate < 0 || c.Limits.PerIdentity.Burst < 0 {
+		check(errors.New("Limits.per_identity must not be negative"))
+	}
+	for method, r := range c.Limits.PerMethod {
+		if r.Rate < 0 || r.Burst < 0 {
+			check(fmt.Errorf("Limits.per_method.%s must not be negative", method))
+		}
+	}
+	q := c.Limits.Quotas
+	if q.MaxGatewaysPerRegion < 0 || q.MaxAgentsPerGateway < 0 || q.MaxAgentsPerCredential < 0 {
+		check(errors.New("Limits quotas must not be negative"))
+	}
+
+	if _, err := memstore.ParseStrategy(c.Placement.Strategy); err != nil {
+		check(fmt.Errorf("Placement.strategy: %w", err))
+	}
+	if c.Rebalance.Interval < 0 {
+		check(errors.New("Rebalance.interval must not be negative"))
+	}
+	if c.Rebalance.Interval > 0 && c.Rebalance.Rate <= 0 {
+		check(errors.New("Rebalance.rate must be positive when the rebalancer runs"))
+	}
+	if c.Rebalance.Burst < 0 {
+		check(errors.New("Rebalance.burst must not be negative"))
+	}
+	if c.Rebalance.Overload != 0 && c.Rebalance.Overload < 1 {
+		check(fmt.Errorf("Rebalance.overload: %g would move agents off every gateway, use 0 or at least 1", c.Rebalance.Overload))
+	}
+
+	if _, _, err := logging.New(io.Discard, c.Logging); err != nil {
+		check(fmt.Errorf("Logging: %w", err))
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		check(fmt.Errorf("Tracing: %w", err))
+	}
+	if c.Debug.Listen != "" {
+		host, _, err := net.SplitHostPort(c.Debug.Listen)
+		if err != nil {
+			check(fmt.Errorf("Debug.listen: %w", err))
+		} else if !c.Debug.AllowRemote && !loopback(host) {
+			check(fmt.Errorf("Debug.listen: %q is not a loopback address and the debug listener has no authentication, set Debug.allow_remote to serve it there", c.Debug.Listen))
+		}
+	}
+
+	ids := map[string]bool{c.SeederID(): true}
+	for i, peer := range c.Cluster.Peers {
+		name := fmt.Sprintf("Cluster.peers[%d]", i)
+		if peer.Name == "" || peer.Region == "" {
+			check(fmt.Errorf("%s needs a name and a region", name))
+		}
+		if peer.IP == "" && peer.Dns == "" {
+			check(fmt.Errorf("%s needs an ip or a dns name", name))
+		}
+		if peer.IP != "" && net.ParseIP(peer.IP) == nil {
+			check(fmt.Errorf("%s.ip: %q is not an IP address", name, peer.IP))
+		}
+		check(validPort(name+".port", peer.Port))
+		if peer.Fingerprint != "" {
+			check(validFingerprint(name+".fingerprint", peer.Fingerprint))
+		}
+
+		id := peer.ID
+		if id == "" {
+			id = peer.Name
+		}
+		if ids[id] {
+			check(fmt.Errorf("%s: id %q is taken", name, id))
+		}
+		ids[id] = true
+	}
+	return errs
+}
@@ -0,0 +1,161 @@
+package diag
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/mux"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+)
+
+type Config struct {
+	// Listen is the debug listener address, empty disables it. It has no
+	// authentication, so it must be on loopback unless AllowRemote is set.
+	Listen string `yaml:"listen"`
+	// AllowRemote accepts a Listen address off loopback, such as a private
+	// interface.
+	AllowRemote bool `yaml:"allow_remote"`
+	// MutexProfileFraction samples 1/n mutex contention events, 0 disables
+	MutexProfileFraction int `yaml:"mutex_profile_fraction"`
+	// BlockProfileRate samples one blocking event per n nanoseconds blocked,
+	// 0 disables
+	BlockProfileRate int `yaml:"block_profile_rate"`
+}
+
+// Profiling holds the runtime profiling rates. The runtime cannot report
+// the block rate back, so it is kept here.
+type Profiling struct {
+	mu                   sync.Mutex
+	MutexProfileFraction int `json:"mutex_profile_fraction"`
+	BlockProfileRate     int `json:"block_profile_rate"`
+}
+
+func (p *Profiling) set(mutex, block int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	runtime.SetMutexProfileFraction(mutex)
+	runtime.SetBlockProfileRate(block)
+	p.MutexProfileFraction = mutex
+	p.BlockProfileRate = block
+}
+
+type Server struct {
+	listen    string
+	server    *http.Server
+	store     *memstore.MemStore
+	profiling *Profiling
+	logger    *slog.Logger
+}
+
+// New applies the configured profiling rates and returns the debug handlers.
+func New(config Config, store *memstore.MemStore, logger *slog.Logger) *Server {
+	s := &Server{
+		listen:    config.Listen,
+		store:     store,
+		profiling: &Profiling{},
+		logger:    logger.With("component", "debug"),
+	}
+	s.profiling.set(config.MutexProfileFraction, config.BlockProfileRate)
+	// no write timeout, CPU profiles and traces stream for as long as asked
+	s.server = &http.Server{Addr: s.listen, Handler: s.Handler()}
+	return s
+}
+
+// Handler serves net/http/pprof under /debug/pprof/, profiling toggles on
+// /debug/profiling and store internals on /debug/store.
+func (s *Server) Handler() http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	// goroutine, heap, mutex, block and the other named profiles
+	router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+
+	router.HandleFunc("/debug/profiling", s.GetProfiling).Methods("GET")
+	router.HandleFunc("/debug/profiling", s.SetProfiling).Methods("POST")
+	router.HandleFunc("/debug/store", s.Store).Methods("GET")
+	return router
+}
+
+// Serve blocks serving the debug handlers on the configured address.
+func (s *Server) Serve() error {
+	host, _, err := net.SplitHostPort(s.listen)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		s.logger.Warn("debug listener is not on loopback, it has no authentication", "addr", s.listen)
+	}
+
+	s.logger.Info("debug server listening", "addr", s.listen)
+	return s.server.ListenAndServe()
+}
+
+// Shutdown stops the listener. A profile still streaming is cut off at the
+// deadline of ctx.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.server.Close()
+		return err
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	response, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+func (s *Server) GetProfiling(w http.ResponseWriter, r *http.Request) {
+	s.profiling.mu.Lock()
+	defer s.profiling.mu.Unlock()
+	writeJSON(w, s.profiling)
+}
+
+// SetProfiling changes the rates given as mutex_profile_fraction and
+// block_profile_rate query parameters, leaving the others as they are.
+func (s *Server) SetProfiling(w http.ResponseWriter, r *http.Request) {
+	s.profiling.mu.Lock()
+	mutex, block := s.profiling.MutexProfileFraction, s.profiling.BlockProfileRate
+	s.profiling.mu.Unlock()
+
+	q := r.URL.Query()
+	for name, target := range map[string]*int{
+		"mutex_profile_fraction": &mutex,
+		"block_profile_rate":     &block,
+	} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, name+" must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+		*target = n
+	}
+
+	s.profiling.set(mutex, block)
+	s.logger.Info("profiling rates changed", "mutex_profile_fraction", mutex, "block_profile_rate", block)
+	s.GetProfiling(w, r)
+}
+
+func (s *Server) Store(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.store.Debug())
+}
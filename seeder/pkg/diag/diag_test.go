@@ -0,0 +1,85 @@
+package diag
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := memstore.NewMemStore(logger)
+	_, _, err := store.AddGateway(context.Background(), "eu", &memstore.GatewayData{GatewayID: "g1", GatewayIP: "10.0.0.1", GatewayPort: 7000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{}, store, logger)
+	t.Cleanup(func() { s.profiling.set(0, 0) })
+	return s
+}
+
+func serve(s *Server, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestProfiling(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		query string
+		code  int
+		mutex int
+		block int
+	}{
+		{"mutex_profile_fraction=5", 200, 5, 0},
+		{"block_profile_rate=1000", 200, 5, 1000},
+		{"mutex_profile_fraction=0&block_profile_rate=0", 200, 0, 0},
+		{"mutex_profile_fraction=-1", 400, 0, 0},
+		{"block_profile_rate=often", 400, 0, 0},
+	}
+	for _, tt := range tests {
+		rec := serve(s, "POST", "/debug/profiling?"+tt.query)
+		if rec.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d", tt.query, rec.Code, tt.code)
+		}
+
+		var got struct {
+			Mutex int `json:"mutex_profile_fraction"`
+			Block int `json:"block_profile_rate"`
+		}
+		if err := json.Unmarshal(serve(s, "GET", "/debug/profiling").Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Mutex != tt.mutex || got.Block != tt.block {
+			t.Errorf("%s: rates = %+v, want %d and %d", tt.query, got, tt.mutex, tt.block)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := serve(s, "GET", "/debug/store")
+	var st memstore.StoreDebug
+	if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil {
+		t.Fatalf("store stats %q: %v", rec.Body.String(), err)
+	}
+	if len(st.Regions) != 1 || st.Regions[0].Gateways != 1 || st.Regions[0].Ranked != 1 || st.Changes.Last != 1 {
+		t.Errorf("store stats = %+v", st)
+	}
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/cmdline"} {
+		if rec := serve(s, "GET", path); rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"path"
+	"slices"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// GateWALReplay stays closed until the WAL has been replayed into the store.
+const GateWALReplay = "wal-replay"
+
+// GateShutdown is closed by Shutdown and never opens again.
+const GateShutdown = "shutdown"
+
+// Checker combines startup gates with live checks such as WAL health, and
+// mirrors the result into the standard gRPC health service.
+type Checker struct {
+	mu       sync.RWMutex
+	gates    map[string]bool
+	checks   map[string]func() error
+	server   *grpchealth.Server
+	services []string
+	// started is set once every gate is open, serving once the checks pass
+	// too
+	started bool
+	serving bool
+}
+
+type Status struct {
+	Ready bool `json:"ready"`
+	// Started is true once every gate is open, reads are served from then
+	// on even while a check fails
+	Started bool              `json:"started"`
+	Gates   map[string]bool   `json:"gates"`
+	Checks  map[string]string `json:"checks"`
+}
+
+// New reports NOT_SERVING for the overall server and every named service
+// until all gates are opened.
+func New(services ...string) *Checker {
+	c := &Checker{
+		gates:    make(map[string]bool),
+		checks:   make(map[string]func() error),
+		server:   grpchealth.NewServer(),
+		services: append([]string{""}, services...),
+	}
+	c.apply(false, false)
+	return c
+}
+
+func (c *Checker) Server() healthpb.HealthServer {
+	return c.server
+}
+
+// AddGate registers a startup step that must finish before serving.
+func (c *Checker) AddGate(name string) {
+	c.mu.Lock()
+	c.gates[name] = false
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+func (c *Checker) Open(name string) {
+	c.mu.Lock()
+	c.gates[name] = true
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+// AddCheck registers a live check, a non nil error marks the server unready.
+func (c *Checker) AddCheck(name string, check func() error) {
+	c.mu.Lock()
+	c.checks[name] = check
+	c.mu.Unlock()
+	c.Refresh()
+}
+
+func (c *Checker) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.evaluate()
+}
+
+// evaluate runs the gates and checks, c.mu must be held.
+func (c *Checker) evaluate() Status {
+	st := Status{
+		Started: true,
+		Gates:   make(map[string]bool, len(c.gates)),
+		Checks:  make(map[string]string, len(c.checks)),
+	}
+	for name, open := range c.gates {
+		st.Gates[name] = open
+		st.Started = st.Started && open
+	}
+	st.Ready = st.Started
+	for name, check := range c.checks {
+		if err := check(); err != nil {
+			st.Checks[name] = describe(err)
+			st.Ready = false
+			continue
+		}
+		st.Checks[name] = "ok"
+	}
+	return st
+}
+
+// Refresh re-evaluates gates and checks and updates the gRPC health status.
+// Both happen under c.mu, so a Shutdown cannot land between them and be
+// undone by an evaluation from before it.
+func (c *Checker) Refresh() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.evaluate()
+	c.apply(st.Started, st.Ready)
+	return st
+}
+
+// Shutdown flips every service to NOT_SERVING for good. Readiness fails
+// and new calls are refused from here on, calls already running finish.
+func (c *Checker) Shutdown() {
+	c.mu.Lock()
+	c.gates[GateShutdown] = false
+	c.started = false
+	c.serving = false
+	c.mu.Unlock()
+	c.server.Shutdown()
+}
+
+// Watch refreshes periodically so failing checks are picked up without a
+// request hitting the HTTP endpoints.
+func (c *Checker) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Refresh()
+		}
+	}
+}
+
+// apply records an evaluation, c.mu must be held.
+func (c *Checker) apply(started, ready bool) {
+	c.started = started
+	c.serving = ready
+
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if ready {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	for _, service := range c.services {
+		c.server.SetServingStatus(service, status)
+	}
+}
+
+// Serving reports whether the last evaluation found the server ready.
+func (c *Checker) Serving() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.serving
+}
+
+// Started reports whether the last evaluation found every gate open.
+func (c *Checker) Started() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.started
+}
+
+// UnaryServerInterceptor rejects calls with UNAVAILABLE until every gate is
+// open, so nobody reads a half replayed store. The writes, full method
+// names, are also rejected while a check fails, reads go on. Health and
+// reflection calls always pass.
+func (c *Checker) UnaryServerInterceptor(writes ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if alwaysAllowed(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		if !c.Started() {
+			return nil, rpcerr.Unavailable("seeder is not ready yet")
+		}
+		if !c.Serving() && slices.Contains(writes, info.FullMethod) {
+			return nil, rpcerr.Unavailable("seeder cannot persist changes right now")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func alwaysAllowed(fullMethod string) bool {
+	service := path.Dir(fullMethod)
+	return service == "/"+healthpb.Health_ServiceDesc.ServiceName ||
+		service == "/grpc.reflection.v1.ServerReflection" ||
+		service == "/grpc.reflection.v1alpha.ServerReflection"
+}
+
+func describe(err error) string {
+	if errors.Is(err, syscall.ENOSPC) {
+		return "disk full: " + err.Error()
+	}
+	return err.Error()
+}
+
+// Pending returns the gates that are still closed, in order.
+func (st Status) Pending() []string {
+	var pending []string
+	for name, open := range st.Gates {
+		if !open {
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
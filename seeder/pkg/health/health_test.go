@@ -0,0 +1,159 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	readMethod  = "/maps.Maps/ResolveGatewayForProxy"
+	writeMethod = "/maps.Maps/RegisterAgent"
+	checkMethod = "/grpc.health.v1.Health/Check"
+)
+
+func call(c *Checker, method string) codes.Code {
+	interceptor := c.UnaryServerInterceptor(writeMethod)
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+		func(context.Context, any) (any, error) { return nil, nil })
+	return status.Code(err)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	c := New("maps.Maps")
+	c.AddGate(GateWALReplay)
+	var walErr error
+	c.AddCheck("wal", func() error { return walErr })
+
+	steps := []struct {
+		name  string
+		step  func()
+		read  codes.Code
+		write codes.Code
+		grpc  healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{
+			name:  "replaying",
+			step:  func() {},
+			read:  codes.Unavailable,
+			write: codes.Unavailable,
+			grpc:  healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+		{
+			name:  "replayed",
+			step:  func() { c.Open(GateWALReplay) },
+			read:  codes.OK,
+			write: codes.OK,
+			grpc:  healthpb.HealthCheckResponse_SERVING,
+		},
+		{
+			name:  "wal failing",
+			step:  func() { walErr = errors.New("no space left on device"); c.Refresh() },
+			read:  codes.OK,
+			write: codes.Unavailable,
+			grpc:  healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+		{
+			name:  "wal recovered",
+			step:  func() { walErr = nil; c.Refresh() },
+			read:  codes.OK,
+			write: codes.OK,
+			grpc:  healthpb.HealthCheckResponse_SERVING,
+		},
+		{
+			name:  "shut down",
+			step:  c.Shutdown,
+			read:  codes.Unavailable,
+			write: codes.Unavailable,
+			grpc:  healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+	}
+	for _, s := range steps {
+		s.step()
+		if got := call(c, readMethod); got != s.read {
+			t.Errorf("%s: read = %v, want %v", s.name, got, s.read)
+		}
+		if got := call(c, writeMethod); got != s.write {
+			t.Errorf("%s: write = %v, want %v", s.name, got, s.write)
+		}
+		if got := call(c, checkMethod); got != codes.OK {
+			t.Errorf("%s: health check = %v, want OK", s.name, got)
+		}
+		resp, err := c.Server().Check(context.Background(), &healthpb.HealthCheckRequest{Service: "maps.Maps"})
+		if err != nil {
+			t.Fatalf("%s: Check() error = %v", s.name, err)
+		}
+		if resp.Status != s.grpc {
+			t.Errorf("%s: health = %v, want %v", s.name, resp.Status, s.grpc)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	c := New()
+	c.AddGate(GateWALReplay)
+	c.AddGate("peers")
+	c.AddCheck("wal", func() error { return errors.New("wal closed") })
+
+	st := c.Status()
+	if st.Ready {
+		t.Error("ready with closed gates and a failing check")
+	}
+	if want := []string{"peers", GateWALReplay}; !slices.Equal(st.Pending(), want) {
+		t.Errorf("Pending() = %v, want %v", st.Pending(), want)
+	}
+	if st.Checks["wal"] != "wal closed" {
+		t.Errorf("wal check = %q", st.Checks["wal"])
+	}
+}
+
+func TestShutdownDuringRefresh(t *testing.T) {
+	c := New()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var blocking atomic.Bool
+	c.AddCheck("slow", func() error {
+		if blocking.Load() {
+			entered <- struct{}{}
+			<-release
+		}
+		return nil
+	})
+	if !c.Serving() {
+		t.Fatal("not serving before Shutdown")
+	}
+
+	blocking.Store(true)
+	refreshed := make(chan struct{})
+	go func() {
+		c.Refresh()
+		close(refreshed)
+	}()
+	<-entered
+	blocking.Store(false)
+	shut := make(chan struct{})
+	go func() {
+		c.Shutdown()
+		close(shut)
+	}()
+	// give Shutdown time to queue behind the running Refresh
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	<-refreshed
+	<-shut
+
+	if c.Serving() || c.Started() {
+		t.Errorf("Serving() = %v, Started() = %v after Shutdown, want false", c.Serving(), c.Started())
+	}
+	if code := call(c, readMethod); code != codes.Unavailable {
+		t.Errorf("read after Shutdown = %v, want %v", code, codes.Unavailable)
+	}
+}
@@ -0,0 +1,229 @@
+package identity
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// metadata keys carrying the signed credential on every registration call
+const (
+	MDIssuer    = "x-agni-issuer"
+	MDSubject   = "x-agni-subject"
+	MDTimestamp = "x-agni-timestamp"
+	MDNonce     = "x-agni-nonce"
+	MDSignature = "x-agni-signature"
+
+	signingVersion = "agni-v1"
+)
+
+var (
+	ErrMissingCredential = errors.New("missing signed credential")
+	ErrUnknownIssuer     = errors.New("unknown credential issuer")
+	ErrBadSignature      = errors.New("credential signature mismatch")
+	ErrExpired           = errors.New("credential timestamp outside allowed skew")
+	ErrReplay            = errors.New("credential nonce already used")
+	ErrNewline           = errors.New("signed values must not contain a newline")
+)
+
+type Credential struct {
+	Issuer    string
+	Subject   string
+	Timestamp int64
+	Nonce     string
+	Signature []byte
+}
+
+type Verifier struct {
+	mu      sync.Mutex
+	issuers map[string]ed25519.PublicKey
+	maxSkew time.Duration
+	nonces  map[string]time.Time
+	now     func() time.Time
+}
+
+// NewVerifier builds a verifier from issuer id -> base64 ed25519 public key.
+// ParsePublicKey decodes a base64 Ed25519 issuer key.
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+func NewVerifier(issuers map[string]string, maxSkew time.Duration) (*Verifier, error) {
+	if maxSkew <= 0 {
+		maxSkew = 5 * time.Minute
+	}
+	keys := make(map[string]ed25519.PublicKey, len(issuers))
+	for id, encoded := range issuers {
+		key, err := ParsePublicKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("issuer %s: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return &Verifier{
+		issuers: keys,
+		maxSkew: maxSkew,
+		nonces:  make(map[string]time.Time),
+		now:     time.Now,
+	}, nil
+}
+
+// FromContext reads the credential from the incoming gRPC metadata.
+func FromContext(ctx context.Context) (*Credential, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, ErrMissingCredential
+	}
+
+	return parse(func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	})
+}
+
+// FromHeader reads the credential from HTTP headers named like the
+// metadata keys, X-Agni-Issuer and so on.
+func FromHeader(h http.Header) (*Credential, error) {
+	return parse(h.Get)
+}
+
+func parse(get func(key string) string) (*Credential, error) {
+	cred := &Credential{
+		Issuer:  get(MDIssuer),
+		Subject: get(MDSubject),
+		Nonce:   get(MDNonce),
+	}
+	if cred.Issuer == "" || cred.Subject == "" || cred.Nonce == "" {
+		return nil, ErrMissingCredential
+	}
+
+	ts, err := strconv.ParseInt(get(MDTimestamp), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid timestamp", ErrMissingCredential)
+	}
+	cred.Timestamp = ts
+
+	sig, err := base64.StdEncoding.DecodeString(get(MDSignature))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: invalid signature", ErrMissingCredential)
+	}
+	cred.Signature = sig
+
+	return cred, nil
+}
+
+// Verify checks the credential signature over method and request fields,
+// the timestamp skew and nonce reuse. It returns the verified subject.
+func (v *Verifier) Verify(cred *Credential, method string, fields ...string) (string, error) {
+	key, ok := v.issuers[cred.Issuer]
+	if !ok {
+		return "", ErrUnknownIssuer
+	}
+
+	now := v.now()
+	issued := time.Unix(cred.Timestamp, 0)
+	if issued.Before(now.Add(-v.maxSkew)) || issued.After(now.Add(v.maxSkew)) {
+		return "", ErrExpired
+	}
+
+	payload, err := signingPayload(cred, method, fields...)
+	if err != nil {
+		return "", err
+	}
+	if !ed25519.Verify(key, payload, cred.Signature) {
+		return "", ErrBadSignature
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	nonceKey := cred.Issuer + "|" + cred.Nonce
+	if _, used := v.nonces[nonceKey]; used {
+		return "", ErrReplay
+	}
+	v.sweep(now)
+	// a nonce only has to be remembered for as long as its timestamp is accepted
+	v.nonces[nonceKey] = issued.Add(v.maxSkew)
+
+	return cred.Subject, nil
+}
+
+// KeepNonces carries over the nonces old has seen, so a credential used
+// before a config reload cannot be replayed after it.
+func (v *Verifier) KeepNonces(old *Verifier) {
+	old.mu.Lock()
+	defer old.mu.Unlock()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	maps.Copy(v.nonces, old.nonces)
+}
+
+func (v *Verifier) sweep(now time.Time) {
+	for k, expires := range v.nonces {
+		if now.After(expires) {
+			delete(v.nonces, k)
+		}
+	}
+}
+
+// Sign fills the signature of cred for the given method and request fields.
+func Sign(priv ed25519.PrivateKey, cred *Credential, method string, fields ...string) {
+	cred.Signature = ed25519.Sign(priv, SigningPayload(cred, method, fields...))
+}
+
+// AppendToContext attaches a signed credential to the outgoing gRPC metadata.
+func AppendToContext(ctx context.Context, cred *Credential) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		MDIssuer, cred.Issuer,
+		MDSubject, cred.Subject,
+		MDTimestamp, strconv.FormatInt(cred.Timestamp, 10),
+		MDNonce, cred.Nonce,
+		MDSignature, base64.StdEncoding.EncodeToString(cred.Signature),
+	)
+}
+
+// SigningPayload is the version, method, credential and request fields,
+// newline separated. Each method signs a fixed number of fields, so the
+// payload is unambiguous as long as no value holds a newline; Verify
+// refuses those, and Sign signs them for nobody to accept.
+func SigningPayload(cred *Credential, method string, fields ...string) []byte {
+	payload, _ := signingPayload(cred, method, fields...)
+	return payload
+}
+
+func signingPayload(cred *Credential, method string, fields ...string) ([]byte, error) {
+	parts := []string{
+		signingVersion,
+		method,
+		cred.Issuer,
+		cred.Subject,
+		strconv.FormatInt(cred.Timestamp, 10),
+		cred.Nonce,
+	}
+	parts = append(parts, fields...)
+	var err error
+	if slices.ContainsFunc(parts, func(part string) bool { return strings.Contains(part, "\n") }) {
+		err = ErrNewline
+	}
+	return []byte(strings.Join(parts, "\n")), err
+}
@@ -0,0 +1,185 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestVerifier(t *testing.T, now time.Time) (*Verifier, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVerifier(map[string]string{"ops": base64.StdEncoding.EncodeToString(pub)}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.now = func() time.Time { return now }
+	return v, priv
+}
+
+func TestParsePublicKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		encoded string
+		ok      bool
+	}{
+		{"key", base64.StdEncoding.EncodeToString(pub), true},
+		{"not base64", "not base64", false},
+		{"short", base64.StdEncoding.EncodeToString(pub[:16]), false},
+	}
+	for _, tt := range tests {
+		key, err := ParsePublicKey(tt.encoded)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: ParsePublicKey() error = %v, want ok %v", tt.name, err, tt.ok)
+		}
+		if tt.ok && !key.Equal(pub) {
+			t.Errorf("%s: ParsePublicKey() = %x, want %x", tt.name, key, pub)
+		}
+	}
+}
+
+func signed(priv ed25519.PrivateKey, issued time.Time, nonce, method string, fields ...string) *Credential {
+	cred := &Credential{Issuer: "ops", Subject: "alice", Timestamp: issued.Unix(), Nonce: nonce}
+	Sign(priv, cred, method, fields...)
+	return cred
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	_, other, _ := ed25519.GenerateKey(nil)
+
+	tests := []struct {
+		name   string
+		cred   func(priv ed25519.PrivateKey) *Credential
+		fields []string
+		want   error
+	}{
+		{
+			name: "valid",
+			cred: func(priv ed25519.PrivateKey) *Credential {
+				return signed(priv, now, "n1", "RegisterAgent", "eu", "a.example.com")
+			},
+			fields: []string{"eu", "a.example.com"},
+		},
+		{
+			name: "within skew",
+			cred: func(priv ed25519.PrivateKey) *Credential {
+				return signed(priv, now.Add(-59*time.Second), "n1", "RegisterAgent")
+			},
+		},
+		{
+			name: "too old",
+			cred: func(priv ed25519.PrivateKey) *Credential {
+				return signed(priv, now.Add(-2*time.Minute), "n1", "RegisterAgent")
+			},
+			want: ErrExpired,
+		},
+		{
+			name: "from the future",
+			cred: func(priv ed25519.PrivateKey) *Credential {
+				return signed(priv, now.Add(2*time.Minute), "n1", "RegisterAgent")
+			},
+			want: ErrExpired,
+		},
+		{
+			name: "other method",
+			cred: func(priv ed25519.PrivateKey) *Credential {
+				return signed(priv, now, "n1", "RegisterGateway")
+			},
+			want: ErrBadSignature,
+		},
+		{
+			name: "other fields",
+			cred: func(priv ed25519.PrivateKey) *Credential {
+				return signed(priv, now, "n1", "RegisterAgent", "eu", "a.example.com")
+			},
+			fields: []string{"eu", "b.example.com"},
+			want:   ErrBadSignature,
+		},
+		{
+			name: "other key",
+			cred: func(ed25519.PrivateKey) *Credential {
+				return signed(other, now, "n1", "RegisterAgent")
+			},
+			want: ErrBadSignature,
+		},
+		{
+			name: "unknown issuer",
+			cred: func(priv ed25519.PrivateKey) *Credential {
+				cred := signed(priv, now, "n1", "RegisterAgent")
+				cred.Issuer = "dev"
+				return cred
+			},
+			want: ErrUnknownIssuer,
+		},
+		{
+			name: "newline in a field",
+			cred: func(priv ed25519.PrivateKey) *Credential {
+				return signed(priv, now, "n1", "RegisterAgent", "eu\na.example.com")
+			},
+			fields: []string{"eu\na.example.com"},
+			want:   ErrNewline,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, priv := newTestVerifier(t, now)
+			subject, err := v.Verify(tt.cred(priv), "RegisterAgent", tt.fields...)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && subject != "alice" {
+				t.Errorf("Verify() subject = %q, want alice", subject)
+			}
+		})
+	}
+}
+
+func TestVerifyReplay(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	v, priv := newTestVerifier(t, now)
+
+	cred := signed(priv, now, "n1", "RegisterAgent")
+	if _, err := v.Verify(cred, "RegisterAgent"); err != nil {
+		t.Fatalf("first Verify() error = %v", err)
+	}
+	if _, err := v.Verify(cred, "RegisterAgent"); !errors.Is(err, ErrReplay) {
+		t.Fatalf("second Verify() error = %v, want %v", err, ErrReplay)
+	}
+
+	// a failed check must not burn the nonce
+	fresh := signed(priv, now, "n2", "RegisterAgent")
+	if _, err := v.Verify(fresh, "RegisterGateway"); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("Verify() with the wrong method error = %v", err)
+	}
+	if _, err := v.Verify(fresh, "RegisterAgent"); err != nil {
+		t.Fatalf("Verify() after a failed check error = %v", err)
+	}
+
+	// nonces survive a reload
+	reloaded, _ := newTestVerifier(t, now)
+	reloaded.issuers = v.issuers
+	reloaded.KeepNonces(v)
+	if _, err := reloaded.Verify(cred, "RegisterAgent"); !errors.Is(err, ErrReplay) {
+		t.Fatalf("Verify() after reload error = %v, want %v", err, ErrReplay)
+	}
+
+	// once the timestamp is out of skew the nonce is forgotten, the
+	// credential is refused as expired instead
+	v.now = func() time.Time { return now.Add(2 * time.Minute) }
+	if _, err := v.Verify(signed(priv, now.Add(2*time.Minute), "n3", "RegisterAgent"), "RegisterAgent"); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if _, used := v.nonces["ops|n1"]; used {
+		t.Error("expired nonce n1 was not swept")
+	}
+}
@@ -0,0 +1,51 @@
+package identity
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// PeerName names the caller by its client certificate when one was
+// presented and verified, falling back to the remote address.
+func PeerName(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if cn := commonName(p); cn != "" {
+		return cn + "@" + p.Addr.String()
+	}
+	return p.Addr.String()
+}
+
+// PeerKey is a stable per-caller key: the verified certificate common name,
+// or the remote host without the ephemeral port.
+func PeerKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if cn := commonName(p); cn != "" {
+		return cn
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// commonName is empty unless the certificate chains to a trusted CA, the
+// caller picks the name of an unverified one.
+func commonName(p *peer.Peer) string {
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 {
+		return ""
+	}
+	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+}
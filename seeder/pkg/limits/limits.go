@@ -0,0 +1,141 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+)
+
+// idle identity buckets are dropped after this long
+const identityTTL = 10 * time.Minute
+
+// Rate is a token bucket refilled at Rate tokens per second. A zero Rate
+// means unlimited.
+type Rate struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+func (r Rate) limiter() *rate.Limiter {
+	burst := r.Burst
+	if burst <= 0 {
+		burst = max(1, int(r.Rate))
+	}
+	return rate.NewLimiter(rate.Limit(r.Rate), burst)
+}
+
+type Limiter struct {
+	mu          sync.Mutex
+	perIdentity Rate
+	identities  map[string]*bucket
+	methods     map[string]*rate.Limiter
+	lastSweep   time.Time
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// NewLimiter builds a limiter with one bucket per caller identity and one
+// shared bucket per gRPC method, keyed by the short method name.
+func NewLimiter(perIdentity Rate, perMethod map[string]Rate) *Limiter {
+	return &Limiter{
+		perIdentity: perIdentity,
+		identities:  make(map[string]*bucket),
+		methods:     methodLimiters(perMethod),
+		lastSweep:   time.Now(),
+	}
+}
+
+func methodLimiters(perMethod map[string]Rate) map[string]*rate.Limiter {
+	methods := make(map[string]*rate.Limiter, len(perMethod))
+	for name, r := range perMethod {
+		if r.Rate > 0 {
+			methods[name] = r.limiter()
+		}
+	}
+	return methods
+}
+
+// Update replaces the rates. Every bucket starts full again.
+func (l *Limiter) Update(perIdentity Rate, perMethod map[string]Rate) {
+	methods := methodLimiters(perMethod)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.perIdentity = perIdentity
+	l.methods = methods
+	clear(l.identities)
+}
+
+// Allow takes a token from the method and identity buckets. When either is
+// empty it returns false and how long the caller should wait.
+func (l *Limiter) Allow(peerKey, method string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var reservations []*rate.Reservation
+	if lim, ok := l.methods[method]; ok {
+		reservations = append(reservations, lim.ReserveN(now, 1))
+	}
+	if l.perIdentity.Rate > 0 {
+		reservations = append(reservations, l.identity(peerKey, now).ReserveN(now, 1))
+	}
+
+	var wait time.Duration
+	for _, r := range reservations {
+		if !r.OK() {
+			wait = time.Second
+			continue
+		}
+		wait = max(wait, r.DelayFrom(now))
+	}
+	if wait == 0 {
+		return true, 0
+	}
+
+	// a rejected call must not consume tokens
+	for _, r := range reservations {
+		r.CancelAt(now)
+	}
+	return false, wait
+}
+
+func (l *Limiter) identity(key string, now time.Time) *rate.Limiter {
+	if now.Sub(l.lastSweep) > identityTTL {
+		for k, b := range l.identities {
+			if now.Sub(b.seen) > identityTTL {
+				delete(l.identities, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.identities[key]
+	if !ok {
+		b = &bucket{limiter: l.perIdentity.limiter()}
+		l.identities[key] = b
+	}
+	b.seen = now
+	return b.limiter
+}
+
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		method := path.Base(info.FullMethod)
+		if ok, wait := l.Allow(identity.PeerKey(ctx), method); !ok {
+			return nil, rpcerr.Exhausted(fmt.Sprintf("rate limit exceeded for %s", method), wait)
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,116 @@
+package limits
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// slow refills well after any test ends, so only the burst counts
+const slow = 0.001
+
+func allowN(l *Limiter, peer, method string, n int) int {
+	allowed := 0
+	for range n {
+		if ok, _ := l.Allow(peer, method); ok {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestAllowPerIdentity(t *testing.T) {
+	l := NewLimiter(Rate{Rate: slow, Burst: 2}, nil)
+
+	if got := allowN(l, "alice", "RegisterAgent", 5); got != 2 {
+		t.Errorf("alice allowed %d calls, want the burst of 2", got)
+	}
+	// buckets are per identity, not per method
+	if ok, wait := l.Allow("alice", "ResolveGatewayForProxy"); ok || wait <= 0 {
+		t.Errorf("Allow() on another method = %v, %v, want refused with a wait", ok, wait)
+	}
+	if got := allowN(l, "bob", "RegisterAgent", 5); got != 2 {
+		t.Errorf("bob allowed %d calls, want their own burst of 2", got)
+	}
+}
+
+func TestAllowPerMethod(t *testing.T) {
+	l := NewLimiter(Rate{}, map[string]Rate{
+		"RegisterAgent": {Rate: slow, Burst: 3},
+		"Unlimited":     {},
+	})
+
+	// a method bucket is shared by every caller
+	if got := allowN(l, "alice", "RegisterAgent", 2) + allowN(l, "bob", "RegisterAgent", 2); got != 3 {
+		t.Errorf("RegisterAgent allowed %d calls, want the burst of 3", got)
+	}
+	if got := allowN(l, "alice", "RegisterGateway", 10); got != 10 {
+		t.Errorf("RegisterGateway without a rate allowed %d of 10 calls", got)
+	}
+	if got := allowN(l, "alice", "Unlimited", 10); got != 10 {
+		t.Errorf("a zero rate allowed %d of 10 calls", got)
+	}
+}
+
+func TestAllowRejectedKeepsTokens(t *testing.T) {
+	l := NewLimiter(Rate{Rate: slow, Burst: 1}, map[string]Rate{
+		"RegisterAgent": {Rate: slow, Burst: 2},
+	})
+
+	if ok, _ := l.Allow("alice", "RegisterAgent"); !ok {
+		t.Fatal("first call refused")
+	}
+	// alice is out of tokens, the refused call must leave the method
+	// bucket its last token for bob
+	if ok, _ := l.Allow("alice", "RegisterAgent"); ok {
+		t.Fatal("alice allowed past her burst")
+	}
+	if ok, _ := l.Allow("bob", "RegisterAgent"); !ok {
+		t.Error("bob refused, a rejected call consumed a method token")
+	}
+}
+
+func TestUpdateRefillsBuckets(t *testing.T) {
+	l := NewLimiter(Rate{Rate: slow, Burst: 1}, nil)
+	allowN(l, "alice", "RegisterAgent", 1)
+
+	l.Update(Rate{Rate: slow, Burst: 3}, map[string]Rate{"RegisterAgent": {Rate: slow, Burst: 2}})
+	if got := allowN(l, "alice", "RegisterAgent", 5); got != 2 {
+		t.Errorf("allowed %d calls after Update, want the method burst of 2", got)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	l := NewLimiter(Rate{}, map[string]Rate{"RegisterAgent": {Rate: slow, Burst: 1}})
+	interceptor := l.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/maps.Maps/RegisterAgent"}
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+	if _, err := interceptor(context.Background(), nil, info, handler); err != nil {
+		t.Fatalf("first call error = %v", err)
+	}
+	_, err := interceptor(context.Background(), nil, info, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("second call error = %v, want %v", err, codes.ResourceExhausted)
+	}
+}
+
+func TestIdentitySweep(t *testing.T) {
+	l := NewLimiter(Rate{Rate: slow, Burst: 1}, nil)
+	now := time.Now()
+	l.lastSweep = now
+	l.identity("alice", now)
+	l.identity("bob", now.Add(identityTTL/2))
+
+	l.identity("bob", now.Add(identityTTL+time.Second))
+	if _, ok := l.identities["alice"]; ok {
+		t.Error("idle bucket of alice was not swept")
+	}
+	if _, ok := l.identities["bob"]; !ok {
+		t.Error("bucket of bob was swept while in use")
+	}
+}
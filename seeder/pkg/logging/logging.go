@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const requestIDHeader = "x-request-id"
+
+// endings of the keys whose values are credentials or credential derived
+// and never logged, in lower case: verifiable_cred_hash, VerifiableHash and
+// x-agni-signature all match
+var redactedSuffixes = []string{
+	"cred_hash",
+	"verifiable_hash",
+	"verifiablehash",
+	"signature",
+	"nonce",
+}
+
+type Config struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// New builds the root logger. The returned LevelVar changes the level of
+// every logger derived from it.
+func New(w io.Writer, config Config) (*slog.Logger, *slog.LevelVar, error) {
+	level := new(slog.LevelVar)
+	if err := SetLevel(level, config.Level); err != nil {
+		return nil, nil, err
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(config.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, nil, fmt.Errorf("unknown log format %q, use text or json", config.Format)
+	}
+	return slog.New(handler), level, nil
+}
+
+func SetLevel(level *slog.LevelVar, name string) error {
+	if name == "" {
+		name = "info"
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("unknown log level %q", name)
+	}
+	level.Set(l)
+	return nil
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if redacted(a.Key) && a.Value.String() != "" {
+		return slog.String(a.Key, "[redacted]")
+	}
+	return a
+}
+
+func redacted(key string) bool {
+	key = strings.ToLower(key)
+	for _, suffix := range redactedSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+type attrsKey struct{}
+
+// WithAttrs attaches request scoped fields picked up by FromContext.
+func WithAttrs(ctx context.Context, attrs ...any) context.Context {
+	existing, _ := ctx.Value(attrsKey{}).([]any)
+	merged := append(append([]any{}, existing...), attrs...)
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+// FromContext returns base carrying the request scoped fields of ctx.
+func FromContext(ctx context.Context, base *slog.Logger) *slog.Logger {
+	if base == nil {
+		base = slog.Default()
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]any)
+	if len(attrs) == 0 {
+		return base
+	}
+	return base.With(attrs...)
+}
+
+// UnaryServerInterceptor tags every call with the peer, method and a request
+// id, taken from x-request-id when the caller sent one.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx = WithAttrs(ctx,
+			"request_id", requestID(ctx),
+			"peer", identity.PeerName(ctx),
+			"method", path.Base(info.FullMethod),
+		)
+		return handler(ctx, req)
+	}
+}
+
+func requestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(requestIDHeader); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRedacted(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"verifiable_cred_hash", true},
+		{"VerifiableHash", true},
+		{"verifiable_hash", true},
+		{"x-agni-signature", true},
+		{"Signature", true},
+		{"nonce", true},
+		{"gateway_id", false},
+		{"agent_domain", false},
+		{"hash_algorithm", false},
+	}
+	for _, tt := range tests {
+		if got := redacted(tt.key); got != tt.want {
+			t.Errorf("redacted(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestNewRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _, err := New(&buf, Config{Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithAttrs(context.Background(), "nonce", "n-1234")
+	FromContext(ctx, logger).Info("registered",
+		"verifiable_cred_hash", "secret-hash",
+		"signature", "",
+		"gateway_id", "g1",
+	)
+
+	out := buf.String()
+	for _, secret := range []string{"secret-hash", "n-1234"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("logged %q: %s", secret, out)
+		}
+	}
+	// empty values stay empty, they leak nothing
+	for _, want := range []string{`"verifiable_cred_hash":"[redacted]"`, `"nonce":"[redacted]"`, `"signature":""`, `"gateway_id":"g1"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log line lacks %s: %s", want, out)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package maps
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/odio4u/memstore/seeder/audit"
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+	"github.com/odio4u/memstore/seeder/pkg/logging"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	registrypb "github.com/odio4u/memstore/seeder/proto"
+	"github.com/odio4u/memstore/seeder/wal"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+)
+
+// Admin holds the operator operations that have no maps RPC: removing and
+// draining gateways, moving agents and snapshotting the store.
+type Admin struct {
+	MemStore *memstore.MemStore
+	WALer    *wal.WALer
+	// Policy needs a verifier, admin operations are refused without one
+	Policy *Policy
+	Audit  *audit.Log
+	// Reload re-reads the config, nil refuses reloads
+	Reload func(ctx context.Context) (ReloadResult, error)
+	Logger *slog.Logger
+}
+
+// ReloadResult lists the config keys a reload changed.
+type ReloadResult struct {
+	// Applied took effect immediately
+	Applied []string `json:"applied"`
+	// Ignored need a restart, the old value stays in use
+	Ignored []string `json:"ignored"`
+}
+
+// Caller is who asked for an admin operation, as recorded in the audit log.
+type Caller struct {
+	Subject string
+	Peer    string
+}
+
+func (a *Admin) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, a.Logger)
+}
+
+// Authorize verifies cred for method and applies the same ACL as the maps
+// service. It returns the verified subject.
+func (a *Admin) Authorize(cred *identity.Credential, method string, fields ...string) (string, error) {
+	verifier, acl := a.Policy.get()
+	if verifier == nil {
+		return "", rpcerr.PermissionDenied("admin operations need identity issuers configured")
+	}
+	subject, err := verifier.Verify(cred, method, fields...)
+	if err != nil {
+		return "", rpcerr.Unauthenticated(err.Error())
+	}
+	if !acl.Allow(subject, method) {
+		return "", rpcerr.PermissionDenied(subject + " may not call " + method)
+	}
+	return subject, nil
+}
+
+// locate finds the region of a gateway when the caller did not name one.
+func (a *Admin) locate(ctx context.Context, region, gatewayID string) (string, error) {
+	gateway, exist := a.MemStore.FindGateway(ctx, region, gatewayID)
+	if !exist {
+		return "", rpcerr.NotFound("gateway", gatewayID, "gateway not found")
+	}
+	return gateway.Region, nil
+}
+
+// DeleteGateway removes a gateway that no longer has agents.
+func (a *Admin) DeleteGateway(ctx context.Context, caller Caller, region, gatewayID string) (*registrypb.Gateway, error) {
+	region, err := a.locate(ctx, region, gatewayID)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := a.MemStore.DeleteGateway(ctx, region, gatewayID, false)
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	a.log(ctx).Info("deleted gateway", "region", region, "gateway_id", gatewayID, "subject", caller.Subject)
+	a.recordAudit(ctx, caller, "DeleteGateway", region, gatewayID, old, nil)
+	return GatewayMessage(&old), nil
+}
+
+// DrainGateway stops new agents from being assigned to a gateway. Agents
+// already on it keep resolving to it.
+func (a *Admin) DrainGateway(ctx context.Context, caller Caller, region, gatewayID string) (*registrypb.Gateway, error) {
+	return a.setGatewayState(ctx, caller, "DrainGateway", region, gatewayID, memstore.GatewayDraining)
+}
+
+// SetGatewayState moves a gateway to any state, back to active after
+// maintenance for example.
+func (a *Admin) SetGatewayState(ctx context.Context, caller Caller, region, gatewayID string, state memstore.GatewayState) (*registrypb.Gateway, error) {
+	return a.setGatewayState(ctx, caller, "SetGatewayState", region, gatewayID, state)
+}
+
+func (a *Admin) setGatewayState(ctx context.Context, caller Caller, method, region, gatewayID string, state memstore.GatewayState) (*registrypb.Gateway, error) {
+	region, err := a.locate(ctx, region, gatewayID)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := a.MemStore.SetGatewayState(ctx, region, gatewayID, state, false)
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	updated := old
+	updated.State = state
+
+	a.log(ctx).Info("gateway state changed",
+		"region", region,
+		"gateway_id", gatewayID,
+		"from", old.State,
+		"to", state,
+		"subject", caller.Subject,
+	)
+	a.recordAudit(ctx, caller, method, region, gatewayID, old, updated)
+	return GatewayMessage(&updated), nil
+}
+
+// ReassignAgent moves an agent onto another gateway of its region.
+func (a *Admin) ReassignAgent(ctx context.Context, caller Caller, region, agentDomain, gatewayID string) (*registrypb.Agent, error) {
+	agent, _, old, err := a.MemStore.ReassignAgent(ctx, region, agentDomain, gatewayID)
+	if errors.Is(err, memstore.ErrAgentNotFound) {
+		return nil, rpcerr.NotFound("agent", agentDomain, "agent not found")
+	}
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	a.log(ctx).Info("reassigned agent",
+		"region", region,
+		"agent_domain", agentDomain,
+		"from", old.GatewayID,
+		"to", gatewayID,
+		"subject", caller.Subject,
+	)
+	a.recordAudit(ctx, caller, "ReassignAgent", region, agentDomain, old, agent)
+	return AgentMessage(&agent), nil
+}
+
+// Snapshot writes the store to the snapshot file and truncates the WAL.
+func (a *Admin) Snapshot(ctx context.Context, caller Caller) (wal.SnapshotInfo, error) {
+	info, err := a.WALer.Snapshot(ctx, func(emit func(*walpb.WalRecord) error) error {
+		return wal.Dump(a.MemStore, emit)
+	})
+	if err != nil {
+		return wal.SnapshotInfo{}, rpcerr.Unavailable("failed to write snapshot: " + err.Error())
+	}
+
+	a.log(ctx).Info("snapshot created", "records", info.Records, "subject", caller.Subject)
+	a.recordAudit(ctx, caller, "CreateSnapshot", "", info.Path, nil, info)
+	return info, nil
+}
+
+// ReloadConfig re-reads the config like SIGHUP does.
+func (a *Admin) ReloadConfig(ctx context.Context, caller Caller) (ReloadResult, error) {
+	if a.Reload == nil {
+		return ReloadResult{}, rpcerr.FailedPrecondition("RELOAD_UNAVAILABLE", "config", "config reload is not available")
+	}
+	result, err := a.Reload(ctx)
+	if err != nil {
+		return ReloadResult{}, rpcerr.FailedPrecondition("CONFIG_INVALID", "config", "config reload failed: "+err.Error())
+	}
+
+	a.log(ctx).Info("config reload requested", "subject", caller.Subject)
+	a.recordAudit(ctx, caller, "ReloadConfig", "", "config", nil, result)
+	return result, nil
+}
+
+func (a *Admin) WALStats() (wal.Stats, error) {
+	st, err := a.WALer.Stats()
+	if err != nil {
+		return wal.Stats{}, rpcerr.Internal(err.Error())
+	}
+	return st, nil
+}
+
+func (a *Admin) recordAudit(ctx context.Context, caller Caller, method, region, key string, old, new any) {
+	if a.Audit == nil {
+		return
+	}
+
+	if err := a.Audit.Record(caller.Peer, caller.Subject, method, region, key, old, new); err != nil {
+		a.log(ctx).Error("failed to record audit entry", "region", region, "key", key, "err", err)
+	}
+}
+
+// adminError extends storeError with the failures of admin operations.
+func adminError(err error) error {
+	switch {
+	case errors.Is(err, memstore.ErrAgentNotFound):
+		return rpcerr.NotFound("agent", "", err.Error())
+	case errors.Is(err, memstore.ErrGatewayHasAgents):
+		return rpcerr.FailedPrecondition("GATEWAY_EMPTY", "gateway", err.Error())
+	}
+	return storeError(err)
+}
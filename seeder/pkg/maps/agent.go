@@ -4,22 +4,46 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
 
 	mapper "github.com/odio4u/agni-schema/maps"
-	walpb "github.com/odio4u/agni-schema/wal"
 	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
 )
 
+func validateAgent(req *mapper.AgentConnectionRequest) error {
+	var fields []rpcerr.Field
+	if req.VerifiableCredHash == "" {
+		fields = append(fields, rpcerr.Field{Name: "verifiable_cred_hash", Description: "verifiable_cred_hash is required"})
+	}
+	if req.AgentDomain == "" {
+		fields = append(fields, rpcerr.Field{Name: "agent_domain", Description: "agent_domain is required"})
+	} else if _, err := memstore.ParseDomain(req.AgentDomain); err != nil {
+		fields = append(fields, rpcerr.Field{Name: "agent_domain", Description: err.Error()})
+	}
+	if req.GatewayId == "" {
+		fields = append(fields, rpcerr.Field{Name: "gateway_id", Description: "gateway_id is required"})
+	}
+	if req.Region == "" {
+		fields = append(fields, rpcerr.Field{Name: "region", Description: "region is required"})
+	}
+
+	if len(fields) > 0 {
+		return rpcerr.InvalidArgument("invalid agent registration request", fields...)
+	}
+	return nil
+}
+
 func (rpc *RPCMap) RegisterAgent(ctx context.Context, req *mapper.AgentConnectionRequest) (*mapper.AgentResponse, error) {
 
-	if req.VerifiableCredHash == "" || req.AgentDomain == "" || req.GatewayId == "" || req.Region == "" {
-		return &mapper.AgentResponse{
-			Error: &mapper.Error{
-				Code:    1,
-				Message: "invalid agent registration request",
-			},
-		}, nil
+	if err := validateAgent(req); err != nil {
+		return rpc.agentFailure(err)
+	}
+
+	subject, err := rpc.Policy.verify(ctx, "RegisterAgent",
+		req.VerifiableCredHash, req.AgentDomain, req.GatewayId, req.Region,
+	)
+	if err != nil {
+		return rpc.agentFailure(err)
 	}
 
 	identityBytes := sha256.Sum256([]byte(
@@ -27,36 +51,31 @@ func (rpc *RPCMap) RegisterAgent(ctx context.Context, req *mapper.AgentConnectio
 	))
 
 	identity := hex.EncodeToString(identityBytes[:])
-	fmt.Println("identity:", identity)
 
 	agentData := &memstore.AgentData{
 		AgentDomain:    req.AgentDomain,
 		AgentID:        identity,
 		GatewayID:      req.GatewayId,
 		VerifiableHash: req.VerifiableCredHash,
+		Subject:        subject,
 	}
 
-	agent, gateway, err := rpc.MemStore.AddAgent(req.Region, agentData)
+	agent, gateway, old, err := rpc.MemStore.AddAgent(ctx, req.Region, agentData, false)
 	if err != nil {
-		return &mapper.AgentResponse{
-			Error: &mapper.Error{
-				Code:    1,
-				Message: err.Error(),
-			},
-		}, nil
+		return rpc.agentFailure(storeError(err))
+	}
+	var previous any
+	if old != nil {
+		previous = *old
 	}
 
-	err = rpc.WALer.Append(&walpb.WalRecord{
-		Op: walpb.Operation_OP_PUT_AGENT,
-		Agent: &walpb.AgentConnectionRequest{
-			VerifiableCredHash: agent.VerifiableHash,
-			AgentDomain:        agent.AgentDomain,
-			GatewayId:          agent.GatewayID,
-			Region:             req.Region,
-			GatewayAddress:     gateway.GatewayAddress,
-			AgentId:            agent.AgentID,
-		},
-	})
+	rpc.log(ctx).Info("registered agent",
+		"region", req.Region,
+		"agent_domain", agent.AgentDomain,
+		"gateway_id", agent.GatewayID,
+		"subject", subject,
+	)
+	rpc.recordAudit(ctx, subject, "RegisterAgent", req.Region, agent.AgentDomain, previous, agent)
 
 	return &mapper.AgentResponse{
 		AgentId:        agent.AgentID,
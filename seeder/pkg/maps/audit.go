@@ -0,0 +1,17 @@
+package maps
+
+import (
+	"context"
+
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+)
+
+func (rpc *RPCMap) recordAudit(ctx context.Context, subject, method, region, key string, old, new any) {
+	if rpc.Audit == nil {
+		return
+	}
+
+	if err := rpc.Audit.Record(identity.PeerName(ctx), subject, method, region, key, old, new); err != nil {
+		rpc.log(ctx).Error("failed to record audit entry", "region", region, "key", key, "err", err)
+	}
+}
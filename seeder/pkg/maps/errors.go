@@ -0,0 +1,66 @@
+package maps
+
+import (
+	"errors"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+)
+
+// failure decides how a failed call reaches the client. With StatusErrors
+// unset, errors that have an in-band equivalent are returned in the response
+// Error for older clients; everything else travels as a gRPC status.
+func (rpc *RPCMap) failure(err error) (*mapper.Error, error) {
+	if !rpc.StatusErrors {
+		if inBand, ok := rpcerr.Legacy(err); ok {
+			return inBand, nil
+		}
+	}
+	return nil, rpcerr.From(err)
+}
+
+func (rpc *RPCMap) gatewayFailure(err error) (*mapper.GatewayResponse, error) {
+	inBand, err := rpc.failure(err)
+	if err != nil {
+		return nil, err
+	}
+	return &mapper.GatewayResponse{Error: inBand}, nil
+}
+
+func (rpc *RPCMap) agentFailure(err error) (*mapper.AgentResponse, error) {
+	inBand, err := rpc.failure(err)
+	if err != nil {
+		return nil, err
+	}
+	return &mapper.AgentResponse{Error: inBand}, nil
+}
+
+func (rpc *RPCMap) gatewaysFailure(err error) (*mapper.MultipleGateways, error) {
+	inBand, err := rpc.failure(err)
+	if err != nil {
+		return nil, err
+	}
+	return &mapper.MultipleGateways{
+		Gateways: []*mapper.GatewayResponse{},
+		Error:    inBand,
+	}, nil
+}
+
+// storeError maps memstore errors onto the catalogue.
+func storeError(err error) error {
+	var conflict *memstore.DomainConflict
+	switch {
+	case errors.As(err, &conflict):
+		return rpcerr.AlreadyExists("agent", conflict.Claim, err.Error())
+	case errors.Is(err, memstore.ErrQuotaExceeded):
+		return rpcerr.Exhausted(err.Error(), 0)
+	case errors.Is(err, memstore.ErrGatewayNotFound):
+		return rpcerr.FailedPrecondition("GATEWAY_REGISTERED", "gateway", err.Error())
+	case errors.Is(err, memstore.ErrGatewayNotActive):
+		return rpcerr.FailedPrecondition("GATEWAY_ACTIVE", "gateway", err.Error())
+	case errors.Is(err, memstore.ErrNotPersisted):
+		return rpcerr.Unavailable(err.Error())
+	}
+	return rpcerr.Internal(err.Error())
+}
@@ -0,0 +1,110 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	"google.golang.org/grpc/codes"
+)
+
+// legacy is the in-band code older clients get for err, 0 if it travels as
+// a status only.
+func legacy(err error) mapper.ErrorCode {
+	inBand, ok := rpcerr.Legacy(err)
+	if !ok {
+		return 0
+	}
+	return inBand.Code
+}
+
+const (
+	invalid  = mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT
+	notFound = mapper.ErrorCode_ERROR_CODE_NOT_FOUND
+)
+
+func TestStoreError(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   codes.Code
+		inBand mapper.ErrorCode
+	}{
+		{&memstore.DomainConflict{Domain: "api.example.com", Claim: "example.com"}, codes.AlreadyExists, invalid},
+		{memstore.ErrQuotaExceeded, codes.ResourceExhausted, 0},
+		{fmt.Errorf("gateway g1: %w", memstore.ErrQuotaExceeded), codes.ResourceExhausted, 0},
+		{memstore.ErrGatewayNotFound, codes.FailedPrecondition, invalid},
+		{memstore.ErrGatewayNotActive, codes.FailedPrecondition, invalid},
+		{fmt.Errorf("%w: disk full", memstore.ErrNotPersisted), codes.Unavailable, invalid},
+		{memstore.ErrGatewayHasAgents, codes.Internal, invalid},
+		{memstore.ErrAgentNotFound, codes.Internal, invalid},
+		{memstore.ErrNoGateway, codes.Internal, invalid},
+		{errors.New("something else"), codes.Internal, invalid},
+	}
+	for _, tt := range tests {
+		err := storeError(tt.err)
+		if got := rpcerr.From(err); got.Code != tt.code {
+			t.Errorf("storeError(%v) code = %v, want %v", tt.err, got.Code, tt.code)
+		}
+		if got := legacy(err); got != tt.inBand {
+			t.Errorf("storeError(%v) in-band code = %v, want %v", tt.err, got, tt.inBand)
+		}
+	}
+}
+
+func TestAdminError(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   codes.Code
+		inBand mapper.ErrorCode
+	}{
+		{memstore.ErrAgentNotFound, codes.NotFound, notFound},
+		{memstore.ErrGatewayHasAgents, codes.FailedPrecondition, invalid},
+		// the rest falls through to storeError
+		{&memstore.DomainConflict{Domain: "api.example.com", Claim: "api.example.com"}, codes.AlreadyExists, invalid},
+		{memstore.ErrGatewayNotActive, codes.FailedPrecondition, invalid},
+		{memstore.ErrGatewayNotFound, codes.FailedPrecondition, invalid},
+		{memstore.ErrQuotaExceeded, codes.ResourceExhausted, 0},
+		{fmt.Errorf("%w: disk full", memstore.ErrNotPersisted), codes.Unavailable, invalid},
+		{memstore.ErrNoGateway, codes.Internal, invalid},
+		{errors.New("something else"), codes.Internal, invalid},
+	}
+	for _, tt := range tests {
+		err := adminError(tt.err)
+		if got := rpcerr.From(err); got.Code != tt.code {
+			t.Errorf("adminError(%v) code = %v, want %v", tt.err, got.Code, tt.code)
+		}
+		if got := legacy(err); got != tt.inBand {
+			t.Errorf("adminError(%v) in-band code = %v, want %v", tt.err, got, tt.inBand)
+		}
+	}
+}
+
+func TestFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		status bool
+		err    error
+		inBand mapper.ErrorCode
+	}{
+		{"in band", false, rpcerr.NotFound("agent", "a.example.com", "gateway not found"), mapper.ErrorCode_ERROR_CODE_NOT_FOUND},
+		{"in band invalid", false, storeError(memstore.ErrGatewayNotFound), mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT},
+		{"no in-band code", false, storeError(memstore.ErrQuotaExceeded), 0},
+		{"status errors", true, rpcerr.NotFound("agent", "a.example.com", "gateway not found"), 0},
+	}
+	for _, tt := range tests {
+		rpc := &RPCMap{StatusErrors: tt.status}
+		resp, err := rpc.agentFailure(tt.err)
+		if tt.inBand == 0 {
+			if err == nil || resp != nil {
+				t.Errorf("%s: agentFailure() = %v, %v, want a status", tt.name, resp, err)
+			}
+			continue
+		}
+		if err != nil || resp.Error == nil || resp.Error.Code != tt.inBand {
+			t.Errorf("%s: agentFailure() = %v, %v, want in-band %v", tt.name, resp, err, tt.inBand)
+		}
+	}
+}
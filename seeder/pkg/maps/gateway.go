@@ -4,21 +4,45 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 
 	mapper "github.com/odio4u/agni-schema/maps"
-	walpb "github.com/odio4u/agni-schema/wal"
 	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
 )
 
+func validateGateway(req *mapper.GatewayPutRequest) error {
+	var fields []rpcerr.Field
+	if req.GatewayIp == "" {
+		fields = append(fields, rpcerr.Field{Name: "gateway_ip", Description: "gateway_ip is required"})
+	}
+	if req.GatewayPort == 0 {
+		fields = append(fields, rpcerr.Field{Name: "gateway_port", Description: "gateway_port is required"})
+	}
+	if req.VerifiableCredHash == "" {
+		fields = append(fields, rpcerr.Field{Name: "verifiable_cred_hash", Description: "verifiable_cred_hash is required"})
+	}
+	if req.Capacity == nil {
+		fields = append(fields, rpcerr.Field{Name: "capacity", Description: "capacity is required"})
+	}
+
+	if len(fields) > 0 {
+		return rpcerr.InvalidArgument("invalid gateway registration request", fields...)
+	}
+	return nil
+}
+
 func (rpc *RPCMap) RegisterGateway(ctx context.Context, req *mapper.GatewayPutRequest) (*mapper.GatewayResponse, error) {
 
-	if req.GatewayIp == "" || req.GatewayPort == 0 || req.VerifiableCredHash == "" {
-		return &mapper.GatewayResponse{
-			Error: &mapper.Error{
-				Code:    1,
-				Message: "invalid gateway registration request",
-			},
-		}, nil
+	if err := validateGateway(req); err != nil {
+		return rpc.gatewayFailure(err)
+	}
+
+	subject, err := rpc.Policy.verify(ctx, "RegisterGateway",
+		req.VerifiableCredHash, req.GatewayIp, fmt.Sprint(req.GatewayPort), req.Region,
+	)
+	if err != nil {
+		return rpc.gatewayFailure(err)
 	}
 
 	identityBytes := sha256.Sum256([]byte(
@@ -33,6 +57,7 @@ func (rpc *RPCMap) RegisterGateway(ctx context.Context, req *mapper.GatewayPutRe
 		GatewayPort:    req.GatewayPort,
 		VerifiableHash: req.VerifiableCredHash,
 		Wssport:        req.WssPort,
+		Subject:        subject,
 		Capacity: memstore.Capacity{
 			CPU:     req.Capacity.Cpu,
 			Memory:  req.Capacity.Memory,
@@ -45,47 +70,27 @@ func (rpc *RPCMap) RegisterGateway(ctx context.Context, req *mapper.GatewayPutRe
 		region = "global"
 	}
 
-	data, err := rpc.MemStore.AddGateway(
+	data, old, err := rpc.MemStore.AddGateway(
+		ctx,
 		region,
 		gatewayData,
 	)
 	if err != nil {
-		return &mapper.GatewayResponse{
-			Error: &mapper.Error{
-				Code:    1,
-				Message: err.Error(),
-			},
-		}, nil
+		return rpc.gatewayFailure(storeError(err))
+	}
+	var previous any
+	if old != nil {
+		previous = *old
 	}
 
-	// this should be zero lock write to WAL
-	err = rpc.WALer.Append(&walpb.WalRecord{
-
-		Op: walpb.Operation_OP_PUT_GATEWAY,
-		Gateway: &walpb.GatewayPutRequest{
-			Region:             region,
-			GatewayIp:          data.GatewayIP,
-			GatewayId:          data.GatewayID,
-			GatewayPort:        data.GatewayPort,
-			GatewayAddress:     data.GatewayAddress,
-			WssPort:            data.Wssport,
-			VerifiableCredHash: data.VerifiableHash,
-			Capacity: &walpb.Capacity{
-				Cpu:     data.Capacity.CPU,
-				Memory:  data.Capacity.Memory,
-				Storage: data.Capacity.Storage,
-			},
-		},
-	})
+	rpc.log(ctx).Info("registered gateway",
+		"region", region,
+		"gateway_id", data.GatewayID,
+		"gateway_address", data.GatewayAddress,
+		"subject", subject,
+	)
+	rpc.recordAudit(ctx, subject, "RegisterGateway", region, data.GatewayID, previous, data)
 
-	if err != nil {
-		return &mapper.GatewayResponse{
-			Error: &mapper.Error{
-				Code:    1,
-				Message: err.Error(),
-			},
-		}, nil
-	}
 	return &mapper.GatewayResponse{
 		GatewayId:      data.GatewayID,
 		GatewayIp:      data.GatewayIP,
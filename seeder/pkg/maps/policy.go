@@ -0,0 +1,78 @@
+package maps
+
+import (
+	"context"
+	"sync"
+
+	"github.com/odio4u/memstore/seeder/pkg/acl"
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+)
+
+// ReadMethod is the method a credential is signed for, over no fields, to
+// read the registry: the Registry service, the /v1 reads, the WAL stats and
+// the event stream. The ACL grants it like any other method.
+const ReadMethod = "ReadRegistry"
+
+// Policy is how callers are checked: the verifier of signed credentials
+// and the ACL. The maps service and the admin API share one, a config
+// reload swaps both at once.
+type Policy struct {
+	mu       sync.RWMutex
+	verifier *identity.Verifier
+	acl      *acl.ACL
+}
+
+// NewPolicy takes a nil verifier to trust the bare VerifiableCredHash of
+// registrations, and refuse admin operations.
+func NewPolicy(verifier *identity.Verifier, acl *acl.ACL) *Policy {
+	return &Policy{verifier: verifier, acl: acl}
+}
+
+// Set replaces the verifier and the ACL. Nonces the old verifier has seen
+// stay refused.
+func (p *Policy) Set(verifier *identity.Verifier, acl *acl.ACL) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if verifier != nil && p.verifier != nil {
+		verifier.KeepNonces(p.verifier)
+	}
+	p.verifier, p.acl = verifier, acl
+}
+
+func (p *Policy) get() (*identity.Verifier, *acl.ACL) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.verifier, p.acl
+}
+
+// verify checks the credential of a call to method and returns the verified
+// subject. Without a verifier callers are not checked and have no subject.
+func (p *Policy) verify(ctx context.Context, method string, fields ...string) (string, error) {
+	verifier, acl := p.get()
+	if verifier == nil {
+		return "", nil
+	}
+
+	cred, err := identity.FromContext(ctx)
+	if err != nil {
+		return "", rpcerr.Unauthenticated(err.Error())
+	}
+	subject, err := verifier.Verify(cred, method, fields...)
+	if err != nil {
+		return "", rpcerr.Unauthenticated(err.Error())
+	}
+	if !acl.Allow(subject, method) {
+		return "", rpcerr.PermissionDenied(subject + " may not call " + method)
+	}
+	return subject, nil
+}
+
+// AuthorizeRead checks a read of the registry. Reads are open while no
+// issuers are configured, like registrations, and signed as ReadMethod
+// once there are.
+func (p *Policy) AuthorizeRead(ctx context.Context) error {
+	_, err := p.verify(ctx, ReadMethod)
+	return err
+}
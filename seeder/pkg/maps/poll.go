@@ -2,19 +2,17 @@ package maps
 
 import (
 	"context"
-	"fmt"
-	"log"
 
 	mapper "github.com/odio4u/agni-schema/maps"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
 )
 
 func (rpc *RPCMap) ResolveGatewayForAgent(ctx context.Context, req *mapper.GatewayHandshake) (*mapper.MultipleGateways, error) {
 
-	gateways := rpc.MemStore.GetTopKGateways("global", 10)
+	gateways := rpc.MemStore.GetTopKGateways(ctx, "global", 10)
 
 	var gatewayResponses []*mapper.GatewayResponse
 	for _, gateway := range gateways {
-		fmt.Println("gateway details:", gateway.GatewayID, gateway.Wssport)
 		gatewayResponses = append(gatewayResponses, &mapper.GatewayResponse{
 			GatewayId:      gateway.GatewayID,
 			GatewayIp:      gateway.GatewayIP,
@@ -32,13 +30,7 @@ func (rpc *RPCMap) ResolveGatewayForAgent(ctx context.Context, req *mapper.Gatew
 	}
 
 	if len(gatewayResponses) == 0 {
-		return &mapper.MultipleGateways{
-			Gateways: []*mapper.GatewayResponse{},
-			Error: &mapper.Error{
-				Code:    2,
-				Message: "no gateway found",
-			},
-		}, nil
+		return rpc.gatewaysFailure(rpcerr.NotFound("gateway", "global", "no gateway found"))
 	}
 	return &mapper.MultipleGateways{
 		Gateways: gatewayResponses,
@@ -48,15 +40,15 @@ func (rpc *RPCMap) ResolveGatewayForAgent(ctx context.Context, req *mapper.Gatew
 
 func (rpc *RPCMap) ResolveGatewayForProxy(ctx context.Context, req *mapper.ProxyMapping) (*mapper.AgentResponse, error) {
 
-	log.Println("finding gateway for", req.AgentDomain)
-
-	agent, exist := rpc.MemStore.GetAgent(
-		req.AgentDomain,
-		req.Region,
-	)
+	agent, exist := rpc.MemStore.ResolveAgent(ctx, req.Region, req.AgentDomain)
 
 	if exist {
-		log.Println("found the gateway", agent.GatewayIP, agent.GatewayPort)
+		rpc.log(ctx).Debug("resolved gateway for proxy",
+			"region", req.Region,
+			"agent_domain", req.AgentDomain,
+			"matched_domain", agent.AgentDomain,
+			"gateway_id", agent.GatewayID,
+		)
 		return &mapper.AgentResponse{
 			AgentId:        agent.AgentID,
 			AgentDomain:    agent.AgentDomain,
@@ -68,10 +60,5 @@ func (rpc *RPCMap) ResolveGatewayForProxy(ctx context.Context, req *mapper.Proxy
 			Identity:       agent.VerifiableHash,
 		}, nil
 	}
-	return &mapper.AgentResponse{
-		Error: &mapper.Error{
-			Code:    2,
-			Message: "gateway not found",
-		},
-	}, nil
+	return rpc.agentFailure(rpcerr.NotFound("agent", req.AgentDomain, "gateway not found"))
 }
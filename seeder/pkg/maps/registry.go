@@ -0,0 +1,258 @@
+package maps
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	registrypb "github.com/odio4u/memstore/seeder/proto"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+type RPCRegistry struct {
+	registrypb.UnimplementedRegistryServer
+	MemStore *memstore.MemStore
+	// Policy is shared with the maps service, reads are signed as
+	// ReadMethod once identity issuers are configured
+	Policy *Policy
+}
+
+var _ registrypb.RegistryServer = (*RPCRegistry)(nil)
+
+func pageSize(size int32) (int, error) {
+	switch {
+	case size == 0:
+		return defaultPageSize, nil
+	case size < 0 || size > maxPageSize:
+		return 0, rpcerr.InvalidArgument("invalid page size", rpcerr.Field{
+			Name:        "page_size",
+			Description: "page_size must be between 1 and 1000",
+		})
+	}
+	return int(size), nil
+}
+
+func encodeCursor(c *memstore.Cursor) string {
+	if c == nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Region + "\x00" + c.Key))
+}
+
+func decodeCursor(token string) (*memstore.Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	region, key, ok := strings.Cut(string(raw), "\x00")
+	if err != nil || !ok {
+		return nil, rpcerr.InvalidArgument("invalid page token", rpcerr.Field{
+			Name:        "page_token",
+			Description: "page_token must be a next_page_token returned by the server",
+		})
+	}
+	return &memstore.Cursor{Region: region, Key: key}, nil
+}
+
+func (rpc *RPCRegistry) ListRegions(ctx context.Context, req *registrypb.ListRegionsRequest) (*registrypb.ListRegionsResponse, error) {
+	if err := rpc.Policy.AuthorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	limit, err := pageSize(req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	after := ""
+	if req.PageToken != "" {
+		cursor, err := decodeCursor(req.PageToken)
+		if err != nil {
+			return nil, err
+		}
+		after = cursor.Region
+	}
+
+	regions, next := rpc.MemStore.ListRegions(ctx, after, limit)
+
+	resp := &registrypb.ListRegionsResponse{}
+	for i := range regions {
+		resp.Regions = append(resp.Regions, regionMessage(&regions[i]))
+	}
+	if next != "" {
+		resp.NextPageToken = encodeCursor(&memstore.Cursor{Region: next})
+	}
+	return resp, nil
+}
+
+func (rpc *RPCRegistry) GetRegion(ctx context.Context, req *registrypb.GetRegionRequest) (*registrypb.Region, error) {
+	if err := rpc.Policy.AuthorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	if req.Name == "" {
+		return nil, rpcerr.InvalidArgument("name is required", rpcerr.Field{
+			Name:        "name",
+			Description: "name is required",
+		})
+	}
+
+	region, exist := rpc.MemStore.GetRegion(ctx, req.Name)
+	if !exist {
+		return nil, rpcerr.NotFound("region", req.Name, "region not found")
+	}
+	return regionMessage(&region), nil
+}
+
+func (rpc *RPCRegistry) ListGateways(ctx context.Context, req *registrypb.ListGatewaysRequest) (*registrypb.ListGatewaysResponse, error) {
+	if err := rpc.Policy.AuthorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	limit, err := pageSize(req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeCursor(req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	gateways, next := rpc.MemStore.ListGateways(ctx, memstore.GatewayFilter{
+		Region:    req.Region,
+		GatewayIP: req.GatewayIp,
+	}, after, limit)
+
+	resp := &registrypb.ListGatewaysResponse{
+		NextPageToken: encodeCursor(next),
+	}
+	for i := range gateways {
+		resp.Gateways = append(resp.Gateways, GatewayMessage(&gateways[i]))
+	}
+	return resp, nil
+}
+
+func (rpc *RPCRegistry) GetGateway(ctx context.Context, req *registrypb.GetGatewayRequest) (*registrypb.Gateway, error) {
+	if err := rpc.Policy.AuthorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	if req.GatewayId == "" {
+		return nil, rpcerr.InvalidArgument("gateway_id is required", rpcerr.Field{
+			Name:        "gateway_id",
+			Description: "gateway_id is required",
+		})
+	}
+
+	gateway, exist := rpc.MemStore.FindGateway(ctx, req.Region, req.GatewayId)
+	if !exist {
+		return nil, rpcerr.NotFound("gateway", req.GatewayId, "gateway not found")
+	}
+	return GatewayMessage(&gateway), nil
+}
+
+func (rpc *RPCRegistry) ListAgents(ctx context.Context, req *registrypb.ListAgentsRequest) (*registrypb.ListAgentsResponse, error) {
+	if err := rpc.Policy.AuthorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	limit, err := pageSize(req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeCursor(req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	agents, next := rpc.MemStore.ListAgents(ctx, memstore.AgentFilter{
+		Region:         req.Region,
+		GatewayID:      req.GatewayId,
+		VerifiableHash: req.VerifiableCredHash,
+		DomainPrefix:   req.DomainPrefix,
+	}, after, limit)
+
+	resp := &registrypb.ListAgentsResponse{
+		NextPageToken: encodeCursor(next),
+	}
+	for i := range agents {
+		resp.Agents = append(resp.Agents, AgentMessage(&agents[i]))
+	}
+	return resp, nil
+}
+
+func (rpc *RPCRegistry) GetAgent(ctx context.Context, req *registrypb.GetAgentRequest) (*registrypb.Agent, error) {
+	if err := rpc.Policy.AuthorizeRead(ctx); err != nil {
+		return nil, err
+	}
+
+	var fields []rpcerr.Field
+	if req.Region == "" {
+		fields = append(fields, rpcerr.Field{Name: "region", Description: "region is required"})
+	}
+	if req.AgentDomain == "" {
+		fields = append(fields, rpcerr.Field{Name: "agent_domain", Description: "agent_domain is required"})
+	}
+	if len(fields) > 0 {
+		return nil, rpcerr.InvalidArgument("invalid get agent request", fields...)
+	}
+
+	agent, exist := rpc.MemStore.LookupAgent(ctx, req.Region, req.AgentDomain)
+	if !exist {
+		return nil, rpcerr.NotFound("agent", req.AgentDomain, "agent not found")
+	}
+	return AgentMessage(&agent), nil
+}
+
+func regionMessage(r *memstore.RegionSummary) *registrypb.Region {
+	return &registrypb.Region{
+		Name:         r.Name,
+		GatewayCount: int32(r.Gateways),
+		AgentCount:   int32(r.Agents),
+		SeederCount:  int32(r.Seeders),
+	}
+}
+
+// GatewayMessage converts a stored gateway to its Registry message. The
+// credential hash is left out, it is what proves ownership of the gateway.
+func GatewayMessage(g *memstore.GatewayData) *registrypb.Gateway {
+	return &registrypb.Gateway{
+		Region:         g.Region,
+		GatewayId:      g.GatewayID,
+		GatewayIp:      g.GatewayIP,
+		GatewayAddress: g.GatewayAddress,
+		GatewayPort:    g.GatewayPort,
+		WssPort:        g.Wssport,
+		Subject:        g.Subject,
+		State:          string(g.State),
+		Capacity: &registrypb.Capacity{
+			Cpu:       g.Capacity.CPU,
+			Memory:    g.Capacity.Memory,
+			Storage:   g.Capacity.Storage,
+			Bandwidth: g.Capacity.Bandwidth,
+		},
+	}
+}
+
+// AgentMessage converts a stored agent to its Registry message, without
+// the credential hash.
+func AgentMessage(a *memstore.AgentData) *registrypb.Agent {
+	return &registrypb.Agent{
+		Region:         a.Region,
+		AgentId:        a.AgentID,
+		AgentDomain:    a.AgentDomain,
+		GatewayId:      a.GatewayID,
+		GatewayIp:      a.GatewayIP,
+		GatewayAddress: a.GatewayAddress,
+		GatewayPort:    a.GatewayPort,
+		WssPort:        a.Wssport,
+		Subject:        a.Subject,
+	}
+}
@@ -0,0 +1,79 @@
+package maps
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/odio4u/memstore/seeder/pkg/acl"
+	"github.com/odio4u/memstore/seeder/pkg/identity"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	registrypb "github.com/odio4u/memstore/seeder/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRegistryReads(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewMemStore(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, _, err := store.AddGateway(ctx, "eu", &memstore.GatewayData{GatewayID: "g1", GatewayIP: "10.0.0.1", GatewayPort: 7000, VerifiableHash: "h0"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := store.AddAgent(ctx, "eu", &memstore.AgentData{AgentDomain: "a.example.com", GatewayID: "g1", VerifiableHash: "h1"}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := identity.NewVerifier(map[string]string{"ops": base64.StdEncoding.EncodeToString(pub)}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := NewPolicy(verifier, acl.New([]acl.Rule{{Subject: "alice", Methods: []string{ReadMethod}}}))
+	rpc := &RPCRegistry{MemStore: store, Policy: policy}
+
+	nonce := 0
+	signed := func(subject, method string) context.Context {
+		nonce++
+		cred := &identity.Credential{Issuer: "ops", Subject: subject, Timestamp: time.Now().Unix(), Nonce: fmt.Sprint(nonce)}
+		identity.Sign(priv, cred, method)
+		md, _ := metadata.FromOutgoingContext(identity.AppendToContext(ctx, cred))
+		return metadata.NewIncomingContext(ctx, md)
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{"unsigned", ctx, codes.Unauthenticated},
+		{"signed for another method", signed("alice", "RegisterAgent"), codes.Unauthenticated},
+		{"denied by the acl", signed("bob", ReadMethod), codes.PermissionDenied},
+		{"allowed", signed("alice", ReadMethod), codes.OK},
+	}
+	for _, tt := range tests {
+		_, err := rpc.ListAgents(tt.ctx, &registrypb.ListAgentsRequest{Region: "eu"})
+		if got := status.Code(err); got != tt.code {
+			t.Errorf("%s: ListAgents() code = %v, want %v", tt.name, got, tt.code)
+		}
+	}
+
+	// without issuers reads are open, and never return credential hashes
+	policy.Set(nil, nil)
+	agent, err := rpc.GetAgent(ctx, &registrypb.GetAgentRequest{Region: "eu", AgentDomain: "a.example.com"})
+	if err != nil || agent.Identity != "" {
+		t.Errorf("GetAgent() = %v, %v, want no identity", agent, err)
+	}
+	gateway, err := rpc.GetGateway(ctx, &registrypb.GetGatewayRequest{Region: "eu", GatewayId: "g1"})
+	if err != nil || gateway.Identity != "" {
+		t.Errorf("GetGateway() = %v, %v, want no identity", gateway, err)
+	}
+}
@@ -1,15 +1,32 @@
 package maps
 
 import (
+	"context"
+	"log/slog"
+
 	mapper "github.com/odio4u/agni-schema/maps"
+	"github.com/odio4u/memstore/seeder/audit"
+	"github.com/odio4u/memstore/seeder/pkg/logging"
 	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
-	"github.com/odio4u/memstore/seeder/wal"
 )
 
 type RPCMap struct {
 	mapper.UnimplementedMapsServer
 	MemStore *memstore.MemStore
-	WALer    *wal.WALer
+	// Policy checks signed registration credentials and limits the methods
+	// a verified subject may call
+	Policy *Policy
+	// Audit records every registry mutation, nil disables auditing
+	Audit *audit.Log
+	// StatusErrors returns every failure as a gRPC status. While unset,
+	// failures with an in-band code are reported in the response Error.
+	StatusErrors bool
+	Logger       *slog.Logger
 }
 
 var _ mapper.MapsServer = (*RPCMap)(nil)
+
+// log returns the handler logger carrying the request scoped fields.
+func (rpc *RPCMap) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, rpc.Logger)
+}
@@ -1,57 +1,194 @@
 package memstore
 
 import (
+	"context"
+	"errors"
 	"fmt"
+
+	"github.com/odio4u/memstore/seeder/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func (mem *MemStore) AddAgent(region string, agent *AgentData) (*AgentData, *GatewayData, error) {
+var (
+	ErrGatewayNotFound = errors.New("gateway not found")
+	ErrAgentNotFound   = errors.New("agent not found")
+)
 
-	gateway, exist := mem.GetGateway(region, agent.GatewayID)
-	if !exist {
-		return &AgentData{}, nil, fmt.Errorf("gateway %s not found in region %s", agent.GatewayID, region)
-	}
+// assign points the agent at gateway.
+func (agent *AgentData) assign(gateway *GatewayData) {
+	agent.GatewayID = gateway.GatewayID
+	agent.GatewayIP = gateway.GatewayIP
+	agent.GatewayAddress = gateway.GatewayAddress
+	agent.GatewayPort = gateway.GatewayPort
+	agent.Wssport = gateway.Wssport
+}
 
-	data := mem.RegionExist(region)
+// AddAgent stores a new agent or repoints a registered one onto its
+// gateway, returning it, its gateway and its previous value, nil when it is
+// new. The gateway must be active unless the agent is already on it;
+// force skips that check, which only replay does: records of a gateway's
+// state may come before those of the agents it kept.
+func (mem *MemStore) AddAgent(ctx context.Context, region string, agent *AgentData, force bool) (_ AgentData, _ GatewayData, previous *AgentData, err error) {
+	_, span := tracing.Child(ctx, "memstore.AddAgent", attribute.String("region", region))
+	defer func() { tracing.End(span, err) }()
+
+	quotas := mem.getQuotas()
+	data := mem.region(region)
+	if data == nil {
+		return AgentData{}, GatewayData{}, nil, fmt.Errorf("%w: %s in region %s", ErrGatewayNotFound, agent.GatewayID, region)
+	}
 
 	data.Mu.Lock()
 	defer data.Mu.Unlock()
+	span.AddEvent("region lock acquired")
+
+	gateway, exist := data.Gateways[agent.GatewayID]
+	if !exist {
+		return AgentData{}, GatewayData{}, nil, fmt.Errorf("%w: %s in region %s", ErrGatewayNotFound, agent.GatewayID, region)
+	}
+
+	if err := data.checkAgentQuota(quotas, agent); err != nil {
+		return AgentData{}, GatewayData{}, nil, err
+	}
+
+	stored, exist := data.Agents[agent.AgentDomain]
+	onGateway := exist && stored.GatewayID == gateway.GatewayID
+	if !force && !onGateway && !gateway.State.Assignable() {
+		return AgentData{}, GatewayData{}, nil, fmt.Errorf("%w: %s is %s", ErrGatewayNotActive, gateway.GatewayID, gateway.State)
+	}
 
-	_, exist = data.Agents[agent.AgentDomain]
 	if exist {
-		fmt.Printf("Agent %s already exists in region %s\n", agent.AgentDomain, region)
-		agent_data := data.Agents[agent.AgentDomain]
-		agent_data.GatewayID = gateway.GatewayID
-		agent_data.GatewayIP = gateway.GatewayIP
-		agent_data.GatewayAddress = gateway.GatewayAddress
-		return agent_data, gateway, nil
+		if !stored.ownedBy(agent) {
+			return AgentData{}, GatewayData{}, nil, &DomainConflict{Domain: agent.AgentDomain, Claim: stored.AgentDomain}
+		}
+		moved := *stored
+		moved.assign(gateway)
+		if err := mem.persist(func(j Journal) error { return j.PutAgent(ctx, moved, *gateway) }); err != nil {
+			return AgentData{}, GatewayData{}, nil, err
+		}
+
+		mem.logger.Debug("agent already registered, repointing", "region", region, "agent_domain", agent.AgentDomain, "gateway_id", gateway.GatewayID)
+		old := *stored
+		data.moveAgent(stored, gateway)
+		mem.recordAgent(ChangePut, *stored)
+		mem.leftGateway(data, old.GatewayID, gateway.GatewayID)
+		return *stored, *gateway, &old, nil
 	}
 
-	agent.GatewayIP = gateway.GatewayIP
-	agent.GatewayAddress = gateway.GatewayAddress
-	agent.GatewayPort = gateway.GatewayPort
-	agent.Wssport = gateway.Wssport
+	if err := data.checkDomain(agent); err != nil {
+		return AgentData{}, GatewayData{}, nil, err
+	}
 
-	data.Agents[agent.AgentDomain] = agent
+	agent.Region = region
+	agent.assign(gateway)
+	if err := mem.persist(func(j Journal) error { return j.PutAgent(ctx, *agent, *gateway) }); err != nil {
+		return AgentData{}, GatewayData{}, nil, err
+	}
+	data.putAgent(agent)
+	mem.recordAgent(ChangePut, *agent)
 
-	fmt.Println("Added the agent to gateway", agent.AgentID, agent.GatewayID)
-	return agent, gateway, nil
+	mem.logger.Debug("added agent", "region", region, "agent_id", agent.AgentID, "gateway_id", agent.GatewayID)
+	return *agent, *gateway, nil, nil
 }
 
-func (mem *MemStore) GetAgent(agentDomain, region string) (*AgentData, bool) {
-	data := mem.RegionExist(region)
-	agent, exists := data.Agents[agentDomain]
-	if !exists {
-		return &AgentData{}, false
+// ResolveAgent returns a copy of the agent routing name: the one registered
+// as name, or else the most specific wildcard or suffix domain matching it.
+// The gateway address is taken from the gateway.
+func (mem *MemStore) ResolveAgent(ctx context.Context, region, name string) (AgentData, bool) {
+	_, span := tracing.Child(ctx, "memstore.ResolveAgent", attribute.String("region", region))
+	defer span.End()
+
+	data := mem.region(region)
+	if data == nil {
+		return AgentData{}, false
 	}
 
-	fmt.Println("found the agent ", agent.AgentID)
+	data.Mu.RLock()
+	defer data.Mu.RUnlock()
+	agent, exist := data.Agents[name]
+	if !exist {
+		domain, ok := data.domains.match(name)
+		if !ok {
+			return AgentData{}, false
+		}
+		agent = data.Agents[domain]
+	}
 
-	gateway, exist := mem.GetGateway(region, agent.GatewayID)
-	if exist {
-		agent.GatewayIP = gateway.GatewayIP
-		agent.GatewayAddress = gateway.GatewayAddress
-		agent.GatewayID = gateway.GatewayID
+	found := *agent
+	if gateway, exist := data.Gateways[agent.GatewayID]; exist {
+		found.GatewayIP = gateway.GatewayIP
+		found.GatewayAddress = gateway.GatewayAddress
+	}
+	return found, true
+}
+
+// LookupAgent returns a copy of the stored agent without resolving its gateway.
+func (mem *MemStore) LookupAgent(ctx context.Context, region, agentDomain string) (AgentData, bool) {
+	_, span := tracing.Child(ctx, "memstore.LookupAgent", attribute.String("region", region))
+	defer span.End()
+
+	data := mem.region(region)
+	if data == nil {
+		return AgentData{}, false
+	}
+
+	data.Mu.RLock()
+	defer data.Mu.RUnlock()
+	agent, exist := data.Agents[agentDomain]
+	if !exist {
+		return AgentData{}, false
+	}
+	return *agent, true
+}
+
+// leftGateway completes the drain of gateway from when an agent moved to
+// another one. The region lock must be held.
+func (mem *MemStore) leftGateway(data *MemData, from, to string) {
+	if from == to {
+		return
 	}
+	if gateway, exist := data.Gateways[from]; exist {
+		mem.checkDrained(data, gateway)
+	}
+}
+
+// ReassignAgent moves an agent onto another gateway of the same region and
+// returns it, its gateway and its previous value. The target must be active.
+func (mem *MemStore) ReassignAgent(ctx context.Context, region, agentDomain, gatewayID string) (_ AgentData, _ GatewayData, previous AgentData, err error) {
+	_, span := tracing.Child(ctx, "memstore.ReassignAgent", attribute.String("region", region))
+	defer func() { tracing.End(span, err) }()
+
+	data := mem.region(region)
+	if data == nil {
+		return AgentData{}, GatewayData{}, AgentData{}, fmt.Errorf("%w: %s in region %s", ErrAgentNotFound, agentDomain, region)
+	}
+
+	data.Mu.Lock()
+	defer data.Mu.Unlock()
+	span.AddEvent("region lock acquired")
+
+	agent, exist := data.Agents[agentDomain]
+	if !exist {
+		return AgentData{}, GatewayData{}, AgentData{}, fmt.Errorf("%w: %s in region %s", ErrAgentNotFound, agentDomain, region)
+	}
+	gateway, exist := data.Gateways[gatewayID]
+	if !exist {
+		return AgentData{}, GatewayData{}, AgentData{}, fmt.Errorf("%w: %s in region %s", ErrGatewayNotFound, gatewayID, region)
+	}
+	if !gateway.State.Assignable() {
+		return AgentData{}, GatewayData{}, AgentData{}, fmt.Errorf("%w: %s is %s", ErrGatewayNotActive, gatewayID, gateway.State)
+	}
+	moved := *agent
+	moved.assign(gateway)
+	if err := mem.persist(func(j Journal) error { return j.PutAgent(ctx, moved, *gateway) }); err != nil {
+		return AgentData{}, GatewayData{}, AgentData{}, err
+	}
+
+	previous = *agent
+	data.moveAgent(agent, gateway)
+	mem.recordAgent(ChangePut, *agent)
+	mem.leftGateway(data, previous.GatewayID, gatewayID)
 
-	return agent, exists
+	mem.logger.Debug("reassigned agent", "region", region, "agent_domain", agentDomain, "gateway_id", gatewayID)
+	return *agent, *gateway, previous, nil
 }
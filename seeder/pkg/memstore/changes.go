@@ -0,0 +1,126 @@
+package memstore
+
+import (
+	"sync"
+	"time"
+)
+
+// changeHistory is how many changes are kept for subscribers to resume from.
+const changeHistory = 10000
+
+type ChangeKind string
+
+const (
+	ChangePut    ChangeKind = "put"
+	ChangeDelete ChangeKind = "delete"
+	// ChangeExpire is reserved for registrations that time out, nothing
+	// expires yet.
+	ChangeExpire ChangeKind = "expire"
+	// ChangeDrained tells that a draining gateway lost its last agent, the
+	// gateway itself is unchanged.
+	ChangeDrained ChangeKind = "drained"
+)
+
+// Change is one mutation of the store. IDs grow by one per change and
+// restart with the process, Epoch tells the runs apart.
+type Change struct {
+	Epoch    int64
+	ID       uint64
+	Time     time.Time
+	Kind     ChangeKind
+	Resource Resource
+	Region   string
+	// Key is the gateway id or the agent domain
+	Key string
+	// Gateway or Agent holds the value after the change, or the removed
+	// value for a delete
+	Gateway *GatewayData
+	Agent   *AgentData
+}
+
+// changeLog is a ring of the latest changes. wake is closed and replaced on
+// every record, so waiters never miss one between reading and waiting.
+type changeLog struct {
+	mu    sync.Mutex
+	epoch int64
+	last  uint64
+	ring  []Change
+	wake  chan struct{}
+}
+
+func newChangeLog() *changeLog {
+	return &changeLog{
+		epoch: time.Now().UnixMilli(),
+		ring:  make([]Change, changeHistory),
+		wake:  make(chan struct{}),
+	}
+}
+
+func (l *changeLog) record(c Change) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.last++
+	c.Epoch = l.epoch
+	c.ID = l.last
+	c.Time = time.Now().UTC()
+	l.ring[c.ID%changeHistory] = c
+
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// recordGateway must be called with the region lock held, which keeps
+// changes to one key in order.
+func (mem *MemStore) recordGateway(kind ChangeKind, gateway GatewayData) {
+	mem.changes.record(Change{
+		Kind:     kind,
+		Resource: ResourceGateway,
+		Region:   gateway.Region,
+		Key:      gateway.GatewayID,
+		Gateway:  &gateway,
+	})
+}
+
+// recordAgent must be called with the region lock held.
+func (mem *MemStore) recordAgent(kind ChangeKind, agent AgentData) {
+	mem.changes.record(Change{
+		Kind:     kind,
+		Resource: ResourceAgent,
+		Region:   agent.Region,
+		Key:      agent.AgentDomain,
+		Agent:    &agent,
+	})
+}
+
+// ChangeEpoch identifies this run of the store, change ids from another
+// epoch cannot be resumed.
+func (mem *MemStore) ChangeEpoch() int64 {
+	return mem.changes.epoch
+}
+
+// LastChange returns the id of the latest change, 0 before the first one.
+func (mem *MemStore) LastChange() uint64 {
+	mem.changes.mu.Lock()
+	defer mem.changes.mu.Unlock()
+	return mem.changes.last
+}
+
+// ChangesSince returns the changes recorded after id and a channel closed
+// when the next one is. ok is false when changes after id were already
+// dropped from the history, or id was never recorded.
+func (mem *MemStore) ChangesSince(id uint64) (_ []Change, wait <-chan struct{}, ok bool) {
+	l := mem.changes
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if id > l.last || l.last-id > changeHistory {
+		return nil, l.wake, false
+	}
+
+	changes := make([]Change, 0, l.last-id)
+	for i := id + 1; i <= l.last; i++ {
+		changes = append(changes, l.ring[i%changeHistory])
+	}
+	return changes, l.wake, true
+}
@@ -0,0 +1,48 @@
+package memstore
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestChangesSince(t *testing.T) {
+	mem := newTestStore()
+	addGateway(t, mem, "eu", "g1", 1)
+	if err := addAgent(mem, "eu", "a.example.com", "g1", "h1", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, wait, ok := mem.ChangesSince(0)
+	if !ok || len(changes) != 2 {
+		t.Fatalf("ChangesSince(0) = %d changes, %v", len(changes), ok)
+	}
+	if c := changes[0]; c.ID != 1 || c.Kind != ChangePut || c.Resource != ResourceGateway || c.Key != "g1" || c.Epoch != mem.ChangeEpoch() {
+		t.Errorf("first change = %+v", c)
+	}
+	if c := changes[1]; c.ID != 2 || c.Resource != ResourceAgent || c.Key != "a.example.com" || c.Agent == nil {
+		t.Errorf("second change = %+v", c)
+	}
+	if _, _, ok := mem.ChangesSince(3); ok {
+		t.Error("ChangesSince() resumed after a change that never happened")
+	}
+
+	addGateway(t, mem, "eu", "g2", 1)
+	select {
+	case <-wait:
+	default:
+		t.Fatal("wait was not closed by the next change")
+	}
+
+	// the ring keeps the latest changeHistory changes
+	for i := range changeHistory {
+		addGateway(t, mem, "us", fmt.Sprint(i%2), 1)
+	}
+	last := mem.LastChange()
+	if _, _, ok := mem.ChangesSince(last - changeHistory - 1); ok {
+		t.Error("ChangesSince() resumed from a dropped change")
+	}
+	changes, _, ok = mem.ChangesSince(last - changeHistory)
+	if !ok || len(changes) != changeHistory || changes[0].ID != last-changeHistory+1 {
+		t.Errorf("ChangesSince(oldest) = %d changes, %v", len(changes), ok)
+	}
+}
@@ -0,0 +1,91 @@
+package memstore
+
+import "time"
+
+// RegionDebug is what /debug/store reports about one region.
+type RegionDebug struct {
+	Name     string `json:"name"`
+	Gateways int    `json:"gateways"`
+	Agents   int    `json:"agents"`
+	Seeders  int    `json:"seeders"`
+	Draining int    `json:"draining_gateways"`
+	// States counts the gateways in each state
+	States map[GatewayState]int `json:"gateway_states"`
+	// Ranked counts B-tree items, it must equal Gateways
+	Ranked  int     `json:"btree_items"`
+	Degree  int     `json:"btree_degree"`
+	MinRank float64 `json:"btree_min_rank"`
+	MaxRank float64 `json:"btree_max_rank"`
+	// IndexedAgents and IndexedGateways count the entries of the gateway and
+	// IP indexes, they must equal Agents and Gateways
+	IndexedAgents   int `json:"indexed_agents"`
+	IndexedGateways int `json:"indexed_gateways"`
+	// LockWait is how long taking the region read lock took
+	LockWait time.Duration `json:"lock_wait_ns"`
+}
+
+type ChangesDebug struct {
+	Epoch    int64  `json:"epoch"`
+	Last     uint64 `json:"last_id"`
+	Retained int    `json:"retained"`
+}
+
+type StoreDebug struct {
+	// RegionsLockWait is how long taking MemStore.mu took
+	RegionsLockWait time.Duration `json:"regions_lock_wait_ns"`
+	Regions         []RegionDebug `json:"regions"`
+	Changes         ChangesDebug  `json:"changes"`
+}
+
+// Debug inspects every region under its read lock, one at a time.
+func (mem *MemStore) Debug() StoreDebug {
+	start := time.Now()
+	mem.mu.RLock()
+	wait := time.Since(start)
+	mem.mu.RUnlock()
+
+	st := StoreDebug{RegionsLockWait: wait}
+	for _, name := range mem.sortedRegions("") {
+		st.Regions = append(st.Regions, mem.region(name).debug(name))
+	}
+
+	l := mem.changes
+	l.mu.Lock()
+	st.Changes = ChangesDebug{Epoch: l.epoch, Last: l.last, Retained: int(min(l.last, changeHistory))}
+	l.mu.Unlock()
+	return st
+}
+
+func (data *MemData) debug(name string) RegionDebug {
+	start := time.Now()
+	data.Mu.RLock()
+	defer data.Mu.RUnlock()
+
+	d := RegionDebug{
+		Name:     name,
+		LockWait: time.Since(start),
+		Gateways: len(data.Gateways),
+		Agents:   len(data.Agents),
+		Seeders:  len(data.Seeders),
+		Ranked:   data.ranked.Len(),
+		Degree:   rankedDegree,
+		States:   make(map[GatewayState]int),
+	}
+	for _, g := range data.Gateways {
+		d.States[g.State]++
+	}
+	d.Draining = d.States[GatewayDraining]
+	for _, keys := range data.agentsByGateway {
+		d.IndexedAgents += len(keys)
+	}
+	for _, keys := range data.gatewaysByIP {
+		d.IndexedGateways += len(keys)
+	}
+	if item := data.ranked.Min(); item != nil {
+		d.MinRank = item.(*GatewayRankItem).Rank
+	}
+	if item := data.ranked.Max(); item != nil {
+		d.MaxRank = item.(*GatewayRankItem).Rank
+	}
+	return d
+}
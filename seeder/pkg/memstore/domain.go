@@ -0,0 +1,225 @@
+package memstore
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// DomainConflict refuses an agent domain overlapping Claim, the domain of
+// an agent registered with another credential, or naming it in other case.
+// Claim is Domain itself when another owner registered it.
+type DomainConflict struct {
+	Domain string
+	Claim  string
+}
+
+func (e *DomainConflict) Error() string {
+	if e.Domain == e.Claim {
+		return fmt.Sprintf("agent domain %s is registered with another credential", e.Domain)
+	}
+	return fmt.Sprintf("agent domain %s overlaps the registered %s", e.Domain, e.Claim)
+}
+
+// ownedBy reports whether a registration of agent may repoint the stored
+// one: same credential, and same subject once one was verified.
+func (stored *AgentData) ownedBy(agent *AgentData) bool {
+	if stored.VerifiableHash != agent.VerifiableHash {
+		return false
+	}
+	return stored.Subject == "" || stored.Subject == agent.Subject
+}
+
+// DomainKind is how an agent domain matches the names proxies resolve.
+type DomainKind int
+
+const (
+	// DomainExact matches the name itself, api.example.com.
+	DomainExact DomainKind = iota
+	// DomainWildcard matches one label below the name, *.example.com
+	// matches api.example.com but neither example.com nor a.api.example.com.
+	DomainWildcard
+	// DomainSuffix matches the name and everything below it, .example.com
+	// matches example.com and a.api.example.com.
+	DomainSuffix
+)
+
+// Domain is a parsed agent domain.
+type Domain struct {
+	Kind DomainKind
+	// Labels are the fixed labels, lower case, top level domain first
+	Labels []string
+}
+
+// ParseDomain parses an agent domain: a name, a wildcard *.name or a suffix
+// .name. Wildcards and suffixes need at least two fixed labels, so nobody
+// claims a whole top level domain.
+func ParseDomain(domain string) (Domain, error) {
+	name := strings.ToLower(strings.TrimSuffix(domain, "."))
+	d := Domain{Kind: DomainExact}
+	switch {
+	case strings.HasPrefix(name, "*."):
+		d.Kind, name = DomainWildcard, name[2:]
+	case strings.HasPrefix(name, "."):
+		d.Kind, name = DomainSuffix, name[1:]
+	}
+
+	labels := strings.Split(name, ".")
+	for _, label := range labels {
+		if label == "" {
+			return Domain{}, fmt.Errorf("%q has an empty label", domain)
+		}
+		if strings.Contains(label, "*") {
+			return Domain{}, fmt.Errorf("%q: * is only allowed as the whole first label", domain)
+		}
+	}
+	if d.Kind != DomainExact && len(labels) < 2 {
+		return Domain{}, fmt.Errorf("%q claims a top level domain", domain)
+	}
+
+	d.Labels = make([]string, len(labels))
+	for i, label := range labels {
+		d.Labels[len(labels)-1-i] = label
+	}
+	return d, nil
+}
+
+// depths is the range of label counts of the names a claim of kind with n
+// fixed labels matches.
+func depths(kind DomainKind, n int) (int, int) {
+	switch kind {
+	case DomainWildcard:
+		return n + 1, n + 1
+	case DomainSuffix:
+		return n, math.MaxInt
+	}
+	return n, n
+}
+
+// domainNode is a label of the domain trie. It holds the agent domains
+// claiming the name it spells: exact and suffix claims on the node itself,
+// wildcard claims on the parent of the label they stand for.
+type domainNode struct {
+	children map[string]*domainNode
+	exact    string
+	wildcard string
+	suffix   string
+}
+
+// domainTrie indexes agent domains by their labels in reverse, so the
+// claims matching a name lie on the path it spells.
+type domainTrie struct {
+	root domainNode
+}
+
+func (n *domainNode) claim(kind DomainKind) *string {
+	switch kind {
+	case DomainWildcard:
+		return &n.wildcard
+	case DomainSuffix:
+		return &n.suffix
+	}
+	return &n.exact
+}
+
+func (t *domainTrie) insert(d Domain, key string) {
+	node := &t.root
+	for _, label := range d.Labels {
+		child, ok := node.children[label]
+		if !ok {
+			child = &domainNode{}
+			if node.children == nil {
+				node.children = make(map[string]*domainNode)
+			}
+			node.children[label] = child
+		}
+		node = child
+	}
+	*node.claim(d.Kind) = key
+}
+
+// match returns the agent domain routing name: the claim with the most
+// fixed labels, an exact one before a wildcard before a suffix.
+func (t *domainTrie) match(name string) (string, bool) {
+	d, err := ParseDomain(name)
+	if err != nil || d.Kind != DomainExact {
+		return "", false
+	}
+
+	best := ""
+	node := &t.root
+	for depth := 0; ; depth++ {
+		if node.suffix != "" {
+			best = node.suffix
+		}
+		if depth == len(d.Labels)-1 && node.wildcard != "" {
+			best = node.wildcard
+		}
+		if depth == len(d.Labels) {
+			if node.exact != "" {
+				best = node.exact
+			}
+			break
+		}
+		child, ok := node.children[d.Labels[depth]]
+		if !ok {
+			break
+		}
+		node = child
+	}
+	return best, best != ""
+}
+
+// overlapping returns the agent domains some name matches together with
+// d. They share its labels, so it is down to the label counts they match:
+// the claims on the path of d, and below it as deep as d reaches.
+func (t *domainTrie) overlapping(d Domain) []string {
+	lo, hi := depths(d.Kind, len(d.Labels))
+	var keys []string
+	var visit func(n *domainNode, depth int)
+	visit = func(n *domainNode, depth int) {
+		for _, kind := range []DomainKind{DomainExact, DomainWildcard, DomainSuffix} {
+			claimLo, claimHi := depths(kind, depth)
+			if key := *n.claim(kind); key != "" && claimLo <= hi && lo <= claimHi {
+				keys = append(keys, key)
+			}
+		}
+		if depth < len(d.Labels) {
+			if child, ok := n.children[d.Labels[depth]]; ok {
+				visit(child, depth+1)
+			}
+			return
+		}
+		if depth < hi {
+			for _, child := range n.children {
+				visit(child, depth+1)
+			}
+		}
+	}
+	visit(&t.root, 0)
+	return keys
+}
+
+// checkDomain refuses a new agent whose domain overlaps the domain of an
+// agent registered with another credential, so no name routes to agents of
+// two owners. An owner may register more specific domains under its own
+// wildcard or suffix, the most specific one wins. The region lock must be
+// held.
+func (data *MemData) checkDomain(agent *AgentData) error {
+	d, err := ParseDomain(agent.AgentDomain)
+	if err != nil {
+		// registrations are parsed before they reach the store, records
+		// from before wildcards only route by their exact name
+		return nil
+	}
+	for _, key := range data.domains.overlapping(d) {
+		if !data.Agents[key].ownedBy(agent) {
+			return &DomainConflict{Domain: agent.AgentDomain, Claim: key}
+		}
+		if claim, _ := ParseDomain(key); claim.Kind == d.Kind && slices.Equal(claim.Labels, d.Labels) {
+			return &DomainConflict{Domain: agent.AgentDomain, Claim: key}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseDomain(t *testing.T) {
+	tests := []struct {
+		domain  string
+		kind    DomainKind
+		labels  []string
+		wantErr bool
+	}{
+		{domain: "API.Example.com.", kind: DomainExact, labels: []string{"com", "example", "api"}},
+		{domain: "localhost", kind: DomainExact, labels: []string{"localhost"}},
+		{domain: "*.example.com", kind: DomainWildcard, labels: []string{"com", "example"}},
+		{domain: ".example.com", kind: DomainSuffix, labels: []string{"com", "example"}},
+		{domain: "*.com", wantErr: true},
+		{domain: ".com", wantErr: true},
+		{domain: "a..example.com", wantErr: true},
+		{domain: "a.*.example.com", wantErr: true},
+		{domain: "*a.example.com", wantErr: true},
+		{domain: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		d, err := ParseDomain(tt.domain)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDomain(%q) = %+v, want an error", tt.domain, d)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDomain(%q) error = %v", tt.domain, err)
+			continue
+		}
+		if d.Kind != tt.kind || !slices.Equal(d.Labels, tt.labels) {
+			t.Errorf("ParseDomain(%q) = %+v, want kind %v labels %v", tt.domain, d, tt.kind, tt.labels)
+		}
+	}
+}
+
+func newTestTrie(t *testing.T, domains ...string) *domainTrie {
+	t.Helper()
+	trie := &domainTrie{}
+	for _, domain := range domains {
+		d, err := ParseDomain(domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trie.insert(d, domain)
+	}
+	return trie
+}
+
+func TestDomainTrieMatch(t *testing.T) {
+	trie := newTestTrie(t, "api.example.com", "*.example.com", ".example.com", "*.eu.example.com")
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "api.example.com", want: "api.example.com"},
+		{name: "API.example.com.", want: "api.example.com"},
+		{name: "web.example.com", want: "*.example.com"},
+		{name: "eu.example.com", want: "*.example.com"},
+		{name: "a.eu.example.com", want: "*.eu.example.com"},
+		// a wildcard stands for one label only, the suffix takes the rest
+		{name: "example.com", want: ".example.com"},
+		{name: "a.web.example.com", want: ".example.com"},
+		{name: "a.b.eu.example.com", want: ".example.com"},
+		{name: "example.org"},
+		{name: "com"},
+		{name: "*.example.com"},
+	}
+	for _, tt := range tests {
+		got, ok := trie.match(tt.name)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("match(%q) = %q, %v, want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestDomainTrieOverlapping(t *testing.T) {
+	trie := newTestTrie(t, "api.example.com", "*.example.com", ".shop.example.com", "a.b.example.com", "example.org")
+
+	tests := []struct {
+		domain string
+		want   []string
+	}{
+		{domain: "*.example.com", want: []string{"*.example.com", ".shop.example.com", "api.example.com"}},
+		{domain: ".example.com", want: []string{"*.example.com", ".shop.example.com", "a.b.example.com", "api.example.com"}},
+		{domain: "shop.example.com", want: []string{"*.example.com", ".shop.example.com"}},
+		{domain: "x.shop.example.com", want: []string{".shop.example.com"}},
+		{domain: "*.b.example.com", want: []string{"a.b.example.com"}},
+		{domain: "example.com"},
+		{domain: "x.b.example.com"},
+	}
+	for _, tt := range tests {
+		d, err := ParseDomain(tt.domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := trie.overlapping(d)
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("overlapping(%q) = %v, want %v", tt.domain, got, tt.want)
+		}
+	}
+}
+
+func TestAddAgentDomainOwnership(t *testing.T) {
+	mem := newTestStore()
+	addGateway(t, mem, "eu", "g1", 4)
+	addGateway(t, mem, "eu", "g2", 2)
+
+	// steps run in order against the same store
+	steps := []struct {
+		name     string
+		domain   string
+		gateway  string
+		hash     string
+		subject  string
+		conflict string
+	}{
+		{name: "first claim", domain: "*.example.com", gateway: "g1", hash: "h1", subject: "alice"},
+		{name: "other owner below it", domain: "api.example.com", gateway: "g1", hash: "h2", subject: "bob", conflict: "*.example.com"},
+		{name: "other owner above it", domain: ".example.com", gateway: "g1", hash: "h2", subject: "bob", conflict: "*.example.com"},
+		{name: "same owner below it", domain: "api.example.com", gateway: "g1", hash: "h1", subject: "alice"},
+		{name: "same owner repoints", domain: "*.example.com", gateway: "g2", hash: "h1", subject: "alice"},
+		{name: "same claim spelled differently", domain: "*.Example.com", gateway: "g1", hash: "h1", subject: "alice", conflict: "*.example.com"},
+		{name: "same credential, other subject", domain: "*.example.com", gateway: "g1", hash: "h1", subject: "mallory", conflict: "*.example.com"},
+		{name: "other credential", domain: "api.example.com", gateway: "g1", hash: "h2", subject: "alice", conflict: "api.example.com"},
+		{name: "unrelated domain", domain: "example.org", gateway: "g1", hash: "h2", subject: "bob"},
+	}
+	for _, s := range steps {
+		err := addAgent(mem, "eu", s.domain, s.gateway, s.hash, s.subject)
+		if s.conflict == "" {
+			if err != nil {
+				t.Fatalf("%s: AddAgent(%s) error = %v", s.name, s.domain, err)
+			}
+			continue
+		}
+		var conflict *DomainConflict
+		if !errors.As(err, &conflict) {
+			t.Fatalf("%s: AddAgent(%s) error = %v, want a DomainConflict", s.name, s.domain, err)
+		}
+		if conflict.Domain != s.domain || conflict.Claim != s.conflict {
+			t.Errorf("%s: conflict = %+v, want %s against %s", s.name, conflict, s.domain, s.conflict)
+		}
+	}
+
+	agent, ok := mem.ResolveAgent(context.Background(), "eu", "web.example.com")
+	if !ok || agent.AgentDomain != "*.example.com" || agent.GatewayID != "g2" {
+		t.Errorf("ResolveAgent(web.example.com) = %+v, %v, want *.example.com on g2", agent, ok)
+	}
+}
@@ -1,66 +1,213 @@
 package memstore
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/btree"
+	"github.com/odio4u/memstore/seeder/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func (mem *MemStore) AddGateway(region string, gateway *GatewayData) (GatewayData, error) {
+var (
+	ErrGatewayHasAgents = errors.New("gateway still has agents")
+	ErrGatewayNotActive = errors.New("gateway takes no new agents")
+)
+
+// AddGateway stores a new gateway or updates a registered one, returning
+// it and its previous value, nil when it is new.
+func (mem *MemStore) AddGateway(ctx context.Context, region string, gateway *GatewayData) (_ GatewayData, previous *GatewayData, err error) {
+	_, span := tracing.Child(ctx, "memstore.AddGateway", attribute.String("region", region))
+	defer func() { tracing.End(span, err) }()
+
+	quotas := mem.getQuotas()
 	data := mem.RegionExist(region)
 
 	data.Mu.Lock()
 	defer data.Mu.Unlock()
+	span.AddEvent("region lock acquired")
 
+	if err := data.checkGatewayQuota(quotas, region, gateway.GatewayID); err != nil {
+		return GatewayData{}, nil, err
+	}
+
+	gateway.Region = region
 	gatewayAddress := fmt.Sprintf("%s:%d", gateway.GatewayIP, gateway.GatewayPort)
 	gateway.GatewayAddress = gatewayAddress
 
+	gateway.State = GatewayActive
 	gatewayData, exist := data.Gateways[gateway.GatewayID]
 	if exist {
+		// a re-registration does not end a drain
+		gateway.State = gatewayData.State
+	}
+	if err := mem.persist(func(j Journal) error { return j.PutGateway(ctx, *gateway) }); err != nil {
+		return GatewayData{}, nil, err
+	}
+
+	if exist {
+		old := *gatewayData
+		previous = &old
 		// Remove old rank item
 		oldRank := gatewayData.Capacity.Rank()
 		data.ranked.Delete(&GatewayRankItem{
 			Rank: oldRank,
 			ID:   gateway.GatewayID,
 		})
-		// Update gateway data
-		gateway.GatewayID = gatewayData.GatewayID
 	}
-	data.Gateways[gateway.GatewayID] = gateway
+	data.putGateway(gateway)
 	data.ranked.ReplaceOrInsert(&GatewayRankItem{
 		Rank: gateway.Capacity.Rank(),
 		ID:   gateway.GatewayID,
 	})
 
-	fmt.Printf("Added gateway %s in region %s\n", gateway.GatewayAddress, region)
-	return *gateway, nil
+	mem.recordGateway(ChangePut, *gateway)
+	mem.logger.Debug("added gateway", "region", region, "gateway_id", gateway.GatewayID, "gateway_address", gateway.GatewayAddress)
+	return *gateway, previous, nil
 }
 
-func (mem *MemStore) GetTopKGateways(region string, k int) []*GatewayData {
-	data := mem.RegionExist(region)
+// GetTopKGateways returns copies of up to k gateways taking new agents,
+// ordered by the selection strategy.
+func (mem *MemStore) GetTopKGateways(ctx context.Context, region string, k int) []GatewayData {
+	_, span := tracing.Child(ctx, "memstore.GetTopKGateways", attribute.String("region", region))
+	defer span.End()
+
+	strategy := mem.getStrategy()
+	data := mem.region(region)
+	if data == nil {
+		return nil
+	}
 
 	data.Mu.RLock()
 	defer data.Mu.RUnlock()
 
+	// the other strategies choose from every gateway
+	limit := 0
+	if strategy == StrategyRank {
+		limit = k
+	}
+	picked := pick(data.assignable(limit, nil), k, strategy, data.next.Add(1)-1)
+	result := make([]GatewayData, len(picked))
+	for i, gateway := range picked {
+		result[i] = *gateway
+	}
+	return result
+}
+
+// assignable lists the gateways taking new agents in rank order, up to
+// limit when it is positive and leaving out those skip returns true for.
+// The region lock must be held.
+func (data *MemData) assignable(limit int, skip func(*GatewayData) bool) []*GatewayData {
 	var result []*GatewayData
-	count := 0
 	data.ranked.Ascend(func(item btree.Item) bool {
-		if count >= k {
+		if limit > 0 && len(result) >= limit {
 			return false
 		}
-		gi := item.(*GatewayRankItem)
-		result = append(result, data.Gateways[gi.ID])
-		count++
+		gateway := data.Gateways[item.(*GatewayRankItem).ID]
+		if !gateway.State.Assignable() || skip != nil && skip(gateway) {
+			return true
+		}
+		result = append(result, gateway)
 		return true
 	})
 	return result
 }
 
-func (mem *MemStore) GetGateway(region, GatewayId string) (*GatewayData, bool) {
-	data := mem.RegionExist(region)
+// GetGateway returns a copy of the gateway.
+func (mem *MemStore) GetGateway(ctx context.Context, region, GatewayId string) (GatewayData, bool) {
+	_, span := tracing.Child(ctx, "memstore.GetGateway", attribute.String("region", region))
+	defer span.End()
+
+	data := mem.region(region)
+	if data == nil {
+		return GatewayData{}, false
+	}
 
 	data.Mu.RLock()
 	defer data.Mu.RUnlock()
 	gateway, exist := data.Gateways[GatewayId]
-	return gateway, exist
+	if !exist {
+		return GatewayData{}, false
+	}
+	return *gateway, true
+}
+
+// DeleteGateway removes a gateway. It refuses while agents are still
+// assigned to it unless force is set, which only replay does: the record
+// moving the last agent away may land in the WAL after the delete.
+func (mem *MemStore) DeleteGateway(ctx context.Context, region, gatewayID string, force bool) (_ GatewayData, err error) {
+	_, span := tracing.Child(ctx, "memstore.DeleteGateway", attribute.String("region", region))
+	defer func() { tracing.End(span, err) }()
+
+	data := mem.region(region)
+	if data == nil {
+		return GatewayData{}, fmt.Errorf("%w: %s in region %s", ErrGatewayNotFound, gatewayID, region)
+	}
+
+	data.Mu.Lock()
+	defer data.Mu.Unlock()
+	span.AddEvent("region lock acquired")
+
+	gateway, exist := data.Gateways[gatewayID]
+	if !exist {
+		return GatewayData{}, fmt.Errorf("%w: %s in region %s", ErrGatewayNotFound, gatewayID, region)
+	}
+	if n := data.agentsOn(gatewayID); n > 0 && !force {
+		return GatewayData{}, fmt.Errorf("%w: %d assigned to %s", ErrGatewayHasAgents, n, gatewayID)
+	}
+	if err := mem.persist(func(j Journal) error { return j.DeleteGateway(ctx, region, gatewayID) }); err != nil {
+		return GatewayData{}, err
+	}
+
+	data.ranked.Delete(&GatewayRankItem{
+		Rank: gateway.Capacity.Rank(),
+		ID:   gatewayID,
+	})
+	data.deleteGateway(gateway)
+	mem.recordGateway(ChangeDelete, *gateway)
+
+	mem.logger.Debug("deleted gateway", "region", region, "gateway_id", gatewayID)
+	return *gateway, nil
+}
+
+// SetGatewayState changes the state of a gateway and returns its previous
+// value. Maintenance is refused while agents are assigned unless force is
+// set, which only replay does.
+func (mem *MemStore) SetGatewayState(ctx context.Context, region, gatewayID string, state GatewayState, force bool) (_ GatewayData, err error) {
+	_, span := tracing.Child(ctx, "memstore.SetGatewayState",
+		attribute.String("region", region),
+		attribute.String("state", string(state)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	data := mem.region(region)
+	if data == nil {
+		return GatewayData{}, fmt.Errorf("%w: %s in region %s", ErrGatewayNotFound, gatewayID, region)
+	}
+
+	data.Mu.Lock()
+	defer data.Mu.Unlock()
+	span.AddEvent("region lock acquired")
+
+	gateway, exist := data.Gateways[gatewayID]
+	if !exist {
+		return GatewayData{}, fmt.Errorf("%w: %s in region %s", ErrGatewayNotFound, gatewayID, region)
+	}
+	if n := data.agentsOn(gatewayID); state == GatewayMaintenance && n > 0 && !force {
+		return GatewayData{}, fmt.Errorf("%w: %d assigned to %s, drain it first", ErrGatewayHasAgents, n, gatewayID)
+	}
+	if err := mem.persist(func(j Journal) error { return j.SetGatewayState(ctx, region, gatewayID, state) }); err != nil {
+		return GatewayData{}, err
+	}
+	previous := *gateway
+	gateway.State = state
+	mem.recordGateway(ChangePut, *gateway)
+	if previous.State != GatewayDraining {
+		// an empty gateway is drained as soon as it starts draining
+		mem.checkDrained(data, gateway)
+	}
+
+	mem.logger.Debug("gateway state changed", "region", region, "gateway_id", gatewayID, "from", previous.State, "to", state)
+	return previous, nil
 }
@@ -1,6 +1,7 @@
 package memstore
 
 import (
+	"log/slog"
 	"sync"
 
 	"github.com/google/btree"
@@ -11,17 +12,26 @@ var (
 	once     sync.Once
 )
 
+// rankedDegree is the degree of the per-region gateway B-tree.
+const rankedDegree = 2
+
 func GetMemStore() *MemStore {
 	once.Do(func() {
-		instance = NewMemStore()
+		instance = NewMemStore(slog.Default())
 	})
 	return instance
 }
 
-func NewMemStore() *MemStore {
+func NewMemStore(logger *slog.Logger) *MemStore {
+	if logger == nil {
+		logger = slog.Default()
+	}
 	return &MemStore{
-		regions: make(map[string]*MemData),
-		global:  newMemData(),
+		regions:  make(map[string]*MemData),
+		global:   newMemData(),
+		strategy: StrategyRank,
+		changes:  newChangeLog(),
+		logger:   logger.With("component", "memstore"),
 	}
 }
 
@@ -30,7 +40,11 @@ func newMemData() *MemData {
 		Gateways: make(map[string]*GatewayData),
 		Agents:   make(map[string]*AgentData),
 		Seeders:  make(map[string]*SeederData),
-		ranked:   btree.New(2),
+		ranked:   btree.New(rankedDegree),
+
+		agentsByGateway:    make(reverse),
+		agentsByCredential: make(reverse),
+		gatewaysByIP:       make(reverse),
 	}
 }
 
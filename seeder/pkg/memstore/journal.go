@@ -0,0 +1,49 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNotPersisted is a change the journal refused, the store is left as it
+// was.
+var ErrNotPersisted = errors.New("change not persisted")
+
+// Journal persists changes before the store applies them. It is called
+// with the region lock held, so changes are persisted in the order they
+// are applied, and a change it fails to persist is not applied.
+type Journal interface {
+	PutGateway(ctx context.Context, gateway GatewayData) error
+	DeleteGateway(ctx context.Context, region, gatewayID string) error
+	SetGatewayState(ctx context.Context, region, gatewayID string, state GatewayState) error
+	// PutAgent is the agent on gateway, registered, reassigned or moved
+	PutAgent(ctx context.Context, agent AgentData, gateway GatewayData) error
+}
+
+// SetJournal persists every following change to journal, nil stops
+// persisting. Replay runs without one.
+func (mem *MemStore) SetJournal(journal Journal) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.journal = journal
+}
+
+func (mem *MemStore) getJournal() Journal {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	return mem.journal
+}
+
+// persist runs write against the journal when there is one. The region
+// lock must be held.
+func (mem *MemStore) persist(write func(journal Journal) error) error {
+	journal := mem.getJournal()
+	if journal == nil {
+		return nil
+	}
+	if err := write(journal); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotPersisted, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// testJournal records the changes it is given, or refuses them all.
+type testJournal struct {
+	changes []string
+	err     error
+}
+
+func (j *testJournal) record(change string) error {
+	if j.err != nil {
+		return j.err
+	}
+	j.changes = append(j.changes, change)
+	return nil
+}
+
+func (j *testJournal) PutGateway(_ context.Context, gateway GatewayData) error {
+	return j.record(fmt.Sprintf("put gateway %s %s", gateway.GatewayID, gateway.State))
+}
+
+func (j *testJournal) DeleteGateway(_ context.Context, region, gatewayID string) error {
+	return j.record("delete gateway " + gatewayID)
+}
+
+func (j *testJournal) SetGatewayState(_ context.Context, region, gatewayID string, state GatewayState) error {
+	return j.record(fmt.Sprintf("state %s %s", gatewayID, state))
+}
+
+func (j *testJournal) PutAgent(_ context.Context, agent AgentData, gateway GatewayData) error {
+	return j.record(fmt.Sprintf("put agent %s %s %s", agent.AgentDomain, agent.GatewayID, gateway.GatewayAddress))
+}
+
+func TestJournal(t *testing.T) {
+	ctx := context.Background()
+	mem := newTestStore()
+	journal := &testJournal{}
+	mem.SetJournal(journal)
+
+	addGateway(t, mem, "eu", "g1", 1)
+	addGateway(t, mem, "eu", "g2", 1)
+	if err := addAgent(mem, "eu", "a.example.com", "g1", "h1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.SetGatewayState(ctx, "eu", "g1", GatewayDraining, false); err != nil {
+		t.Fatal(err)
+	}
+	// a re-registration keeps the drain
+	addGateway(t, mem, "eu", "g1", 2)
+	if _, _, _, err := mem.ReassignAgent(ctx, "eu", "a.example.com", "g2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.DeleteGateway(ctx, "eu", "g1", false); err != nil {
+		t.Fatal(err)
+	}
+	// refused changes are not persisted
+	if _, err := mem.DeleteGateway(ctx, "eu", "g2", false); !errors.Is(err, ErrGatewayHasAgents) {
+		t.Fatalf("DeleteGateway(g2) error = %v, want %v", err, ErrGatewayHasAgents)
+	}
+
+	want := []string{
+		"put gateway g1 active",
+		"put gateway g2 active",
+		"put agent a.example.com g1 10.0.0.1:7000",
+		"state g1 draining",
+		"put gateway g1 draining",
+		"put agent a.example.com g2 10.0.0.1:7000",
+		"delete gateway g1",
+	}
+	if !slices.Equal(journal.changes, want) {
+		t.Errorf("journal = %q, want %q", journal.changes, want)
+	}
+}
+
+func TestJournalFailure(t *testing.T) {
+	ctx := context.Background()
+	mem := newTestStore()
+	addGateway(t, mem, "eu", "g1", 1)
+	addGateway(t, mem, "eu", "g2", 1)
+	if err := addAgent(mem, "eu", "a.example.com", "g1", "h1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := addAgent(mem, "eu", "b.example.com", "g2", "h1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := mem.ReassignAgent(ctx, "eu", "b.example.com", "g1"); err != nil {
+		t.Fatal(err)
+	}
+	wantGateways, wantAgents := mem.Export()
+
+	mem.SetJournal(&testJournal{err: errors.New("disk full")})
+	tests := []struct {
+		name   string
+		change func() error
+	}{
+		{"AddGateway", func() error {
+			_, _, err := mem.AddGateway(ctx, "eu", &GatewayData{GatewayID: "g1", GatewayIP: "10.0.0.9", GatewayPort: 7000})
+			return err
+		}},
+		{"AddAgent", func() error { return addAgent(mem, "eu", "c.example.com", "g1", "h1", "") }},
+		{"AddAgent repoint", func() error { return addAgent(mem, "eu", "a.example.com", "g2", "h1", "") }},
+		{"ReassignAgent", func() error {
+			_, _, _, err := mem.ReassignAgent(ctx, "eu", "a.example.com", "g2")
+			return err
+		}},
+		{"SetGatewayState", func() error {
+			_, err := mem.SetGatewayState(ctx, "eu", "g1", GatewayDraining, false)
+			return err
+		}},
+		{"DeleteGateway", func() error {
+			_, err := mem.DeleteGateway(ctx, "eu", "g2", true)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		if err := tt.change(); !errors.Is(err, ErrNotPersisted) {
+			t.Errorf("%s error = %v, want %v", tt.name, err, ErrNotPersisted)
+		}
+	}
+
+	gateways, agents := mem.Export()
+	if !reflect.DeepEqual(gateways, wantGateways) {
+		t.Errorf("gateways = %+v, want %+v", gateways, wantGateways)
+	}
+	if !reflect.DeepEqual(agents, wantAgents) {
+		t.Errorf("agents = %+v, want %+v", agents, wantAgents)
+	}
+	if n := mem.region("eu").agentsOn("g1"); n != 2 {
+		t.Errorf("g1 holds %d agents, want 2", n)
+	}
+}
@@ -0,0 +1,312 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/odio4u/memstore/seeder/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Cursor is the position after the last item of a page. Items are ordered
+// by region, then by their key within the region, so a cursor stays valid
+// while the maps change underneath it.
+type Cursor struct {
+	Region string
+	Key    string
+}
+
+func (c Cursor) before(region, key string) bool {
+	if region != c.Region {
+		return c.Region < region
+	}
+	return c.Key < key
+}
+
+type RegionSummary struct {
+	Name     string
+	Gateways int
+	Agents   int
+	Seeders  int
+	Ranked   int
+}
+
+type GatewayFilter struct {
+	Region    string
+	GatewayIP string
+}
+
+type AgentFilter struct {
+	Region         string
+	GatewayID      string
+	VerifiableHash string
+	DomainPrefix   string
+}
+
+// sortedRegions returns the region names in order, or just region when set.
+func (mem *MemStore) sortedRegions(region string) []string {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+
+	if region != "" {
+		if _, ok := mem.regions[region]; !ok {
+			return nil
+		}
+		return []string{region}
+	}
+
+	names := make([]string, 0, len(mem.regions))
+	for name := range mem.regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (mem *MemStore) region(name string) *MemData {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	return mem.regions[name]
+}
+
+// pageLimit makes a page hold at least one entry, so a page always ends
+// with a cursor while more entries follow.
+func pageLimit(limit int) int {
+	return max(limit, 1)
+}
+
+// ListRegions skips regions that hold nothing, like those whose last
+// gateway was deleted.
+func (mem *MemStore) ListRegions(ctx context.Context, after string, limit int) ([]RegionSummary, string) {
+	_, span := tracing.Child(ctx, "memstore.ListRegions")
+	defer span.End()
+
+	limit = pageLimit(limit)
+
+	var result []RegionSummary
+	for _, name := range mem.sortedRegions("") {
+		if name <= after && after != "" {
+			continue
+		}
+
+		summary := mem.region(name).summary(name)
+		if summary.Gateways == 0 && summary.Agents == 0 && summary.Seeders == 0 {
+			continue
+		}
+		if len(result) == limit {
+			return result, result[len(result)-1].Name
+		}
+		result = append(result, summary)
+	}
+	return result, ""
+}
+
+// GetRegion summarises one region. Like ListRegions it reports regions
+// that hold nothing as missing.
+func (mem *MemStore) GetRegion(ctx context.Context, name string) (RegionSummary, bool) {
+	_, span := tracing.Child(ctx, "memstore.GetRegion", attribute.String("region", name))
+	defer span.End()
+
+	data := mem.region(name)
+	if data == nil {
+		return RegionSummary{}, false
+	}
+	summary := data.summary(name)
+	if summary.Gateways == 0 && summary.Agents == 0 && summary.Seeders == 0 {
+		return RegionSummary{}, false
+	}
+	return summary, true
+}
+
+// Stats summarises every region holding something, like ListRegions.
+func (mem *MemStore) Stats() []RegionSummary {
+	names := mem.sortedRegions("")
+	result := make([]RegionSummary, 0, len(names))
+	for _, name := range names {
+		summary := mem.region(name).summary(name)
+		if summary.Gateways == 0 && summary.Agents == 0 && summary.Seeders == 0 {
+			continue
+		}
+		result = append(result, summary)
+	}
+	return result
+}
+
+func (data *MemData) summary(name string) RegionSummary {
+	data.Mu.RLock()
+	defer data.Mu.RUnlock()
+	return RegionSummary{
+		Name:     name,
+		Gateways: len(data.Gateways),
+		Agents:   len(data.Agents),
+		Seeders:  len(data.Seeders),
+		Ranked:   data.ranked.Len(),
+	}
+}
+
+// ListGateways returns gateways ordered by region and gateway id, plus the
+// cursor of the next page when there is one.
+func (mem *MemStore) ListGateways(ctx context.Context, filter GatewayFilter, after *Cursor, limit int) ([]GatewayData, *Cursor) {
+	_, span := tracing.Child(ctx, "memstore.ListGateways", attribute.String("region", filter.Region))
+	defer span.End()
+
+	limit = pageLimit(limit)
+
+	var result []GatewayData
+	for _, name := range mem.sortedRegions(filter.Region) {
+		if after != nil && name < after.Region {
+			continue
+		}
+
+		data := mem.region(name)
+		data.Mu.RLock()
+		var ids []string
+		for _, id := range data.gatewayKeys(filter) {
+			if after == nil || after.before(name, id) {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			if len(result) == limit {
+				data.Mu.RUnlock()
+				last := result[len(result)-1]
+				return result, &Cursor{Region: last.Region, Key: last.GatewayID}
+			}
+			result = append(result, *data.Gateways[id])
+		}
+		data.Mu.RUnlock()
+	}
+	return result, nil
+}
+
+// ListAgents returns matching agents ordered by region and domain, plus the
+// cursor of the next page when there is one.
+func (mem *MemStore) ListAgents(ctx context.Context, filter AgentFilter, after *Cursor, limit int) ([]AgentData, *Cursor) {
+	_, span := tracing.Child(ctx, "memstore.ListAgents", attribute.String("region", filter.Region))
+	defer span.End()
+
+	limit = pageLimit(limit)
+
+	var result []AgentData
+	for _, name := range mem.sortedRegions(filter.Region) {
+		if after != nil && name < after.Region {
+			continue
+		}
+
+		data := mem.region(name)
+		data.Mu.RLock()
+		var domains []string
+		for _, domain := range data.agentKeys(filter) {
+			if after != nil && !after.before(name, domain) {
+				continue
+			}
+			if !strings.HasPrefix(domain, filter.DomainPrefix) {
+				continue
+			}
+			domains = append(domains, domain)
+		}
+		sort.Strings(domains)
+
+		for _, domain := range domains {
+			if len(result) == limit {
+				data.Mu.RUnlock()
+				last := result[len(result)-1]
+				return result, &Cursor{Region: last.Region, Key: last.AgentDomain}
+			}
+			result = append(result, *data.Agents[domain])
+		}
+		data.Mu.RUnlock()
+	}
+	return result, nil
+}
+
+// gatewayKeys returns the ids of the gateways matching filter, unordered.
+// The region lock must be held.
+func (data *MemData) gatewayKeys(filter GatewayFilter) []string {
+	var ids []string
+	if filter.GatewayIP != "" {
+		for id := range data.gatewaysByIP[filter.GatewayIP] {
+			ids = append(ids, id)
+		}
+		return ids
+	}
+	for id := range data.Gateways {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// agentKeys returns the domains of the agents matching the gateway and
+// credential of filter, unordered, from the smallest index that applies.
+// The region lock must be held.
+func (data *MemData) agentKeys(filter AgentFilter) []string {
+	var domains []string
+	switch {
+	case filter.GatewayID != "":
+		for domain := range data.agentsByGateway[filter.GatewayID] {
+			if filter.VerifiableHash == "" || data.agentsByCredential.has(filter.VerifiableHash, domain) {
+				domains = append(domains, domain)
+			}
+		}
+	case filter.VerifiableHash != "":
+		for domain := range data.agentsByCredential[filter.VerifiableHash] {
+			domains = append(domains, domain)
+		}
+	default:
+		for domain := range data.Agents {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// FindGateway looks the gateway up in region, or in every region when
+// region is empty.
+func (mem *MemStore) FindGateway(ctx context.Context, region, gatewayID string) (GatewayData, bool) {
+	_, span := tracing.Child(ctx, "memstore.FindGateway", attribute.String("region", region))
+	defer span.End()
+
+	for _, name := range mem.sortedRegions(region) {
+		data := mem.region(name)
+		data.Mu.RLock()
+		gateway, exist := data.Gateways[gatewayID]
+		if exist {
+			found := *gateway
+			data.Mu.RUnlock()
+			return found, true
+		}
+		data.Mu.RUnlock()
+	}
+	return GatewayData{}, false
+}
+
+// Export copies every gateway and agent, one region at a time under its
+// read lock, ordered by region and key.
+func (mem *MemStore) Export() ([]GatewayData, []AgentData) {
+	var gateways []GatewayData
+	var agents []AgentData
+	for _, name := range mem.sortedRegions("") {
+		data := mem.region(name)
+		data.Mu.RLock()
+		for _, id := range sortedKeys(data.Gateways) {
+			gateways = append(gateways, *data.Gateways[id])
+		}
+		for _, domain := range sortedKeys(data.Agents) {
+			agents = append(agents, *data.Agents[domain])
+		}
+		data.Mu.RUnlock()
+	}
+	return gateways, agents
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
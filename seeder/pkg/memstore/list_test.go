@@ -0,0 +1,195 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// newListStore holds gateways g1 to g3 in eu, g1 in us and a region ap
+// left empty by a delete.
+func newListStore(t *testing.T) *MemStore {
+	t.Helper()
+	mem := newTestStore()
+	for _, id := range []string{"g3", "g1", "g2"} {
+		addGateway(t, mem, "eu", id, 1)
+	}
+	addGateway(t, mem, "us", "g1", 1)
+	addGateway(t, mem, "ap", "g1", 1)
+	if _, err := mem.DeleteGateway(context.Background(), "ap", "g1", false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, a := range []struct{ region, domain, gateway, hash string }{
+		{"eu", "c.example.com", "g1", "h1"},
+		{"eu", "a.example.com", "g2", "h2"},
+		{"eu", "b.example.org", "g1", "h2"},
+		{"us", "a.example.com", "g1", "h1"},
+	} {
+		if err := addAgent(mem, a.region, a.domain, a.gateway, a.hash, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return mem
+}
+
+func TestListGatewaysPages(t *testing.T) {
+	mem := newListStore(t)
+	ctx := context.Background()
+
+	var got []string
+	var after *Cursor
+	for pages := 0; ; pages++ {
+		if pages > 4 {
+			t.Fatal("ListGateways() never returned the last page")
+		}
+		page, next := mem.ListGateways(ctx, GatewayFilter{}, after, 3)
+		for _, g := range page {
+			got = append(got, g.Region+"/"+g.GatewayID)
+		}
+		if next == nil {
+			break
+		}
+		after = next
+	}
+	want := []string{"eu/g1", "eu/g2", "eu/g3", "us/g1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ListGateways() pages = %v, want %v", got, want)
+	}
+
+	// a cursor stays valid when its item goes away
+	if _, err := mem.DeleteGateway(ctx, "eu", "g3", false); err != nil {
+		t.Fatal(err)
+	}
+	page, next := mem.ListGateways(ctx, GatewayFilter{}, &Cursor{Region: "eu", Key: "g2"}, 3)
+	if len(page) != 1 || page[0].Region != "us" || next != nil {
+		t.Errorf("ListGateways() after eu/g2 = %+v, %v, want us/g1 alone", page, next)
+	}
+
+	page, _ = mem.ListGateways(ctx, GatewayFilter{Region: "us"}, nil, 10)
+	if len(page) != 1 || page[0].Region != "us" {
+		t.Errorf("ListGateways(us) = %+v", page)
+	}
+	if page, _ := mem.ListGateways(ctx, GatewayFilter{Region: "sa"}, nil, 10); len(page) != 0 {
+		t.Errorf("ListGateways(sa) = %+v, want none", page)
+	}
+}
+
+func TestListAgentsFilters(t *testing.T) {
+	mem := newListStore(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		filter AgentFilter
+		after  *Cursor
+		limit  int
+		want   []string
+		next   *Cursor
+	}{
+		{
+			name:  "all",
+			limit: 10,
+			want:  []string{"eu/a.example.com", "eu/b.example.org", "eu/c.example.com", "us/a.example.com"},
+		},
+		{
+			name:  "first page",
+			limit: 2,
+			want:  []string{"eu/a.example.com", "eu/b.example.org"},
+			next:  &Cursor{Region: "eu", Key: "b.example.org"},
+		},
+		{
+			name:  "next page",
+			after: &Cursor{Region: "eu", Key: "b.example.org"},
+			limit: 2,
+			want:  []string{"eu/c.example.com", "us/a.example.com"},
+		},
+		{
+			name:   "by gateway",
+			filter: AgentFilter{Region: "eu", GatewayID: "g1"},
+			limit:  10,
+			want:   []string{"eu/b.example.org", "eu/c.example.com"},
+		},
+		{
+			name:   "by credential",
+			filter: AgentFilter{VerifiableHash: "h1"},
+			limit:  10,
+			want:   []string{"eu/c.example.com", "us/a.example.com"},
+		},
+		{
+			name:   "by gateway and credential",
+			filter: AgentFilter{Region: "eu", GatewayID: "g1", VerifiableHash: "h2"},
+			limit:  10,
+			want:   []string{"eu/b.example.org"},
+		},
+		{
+			name:   "by prefix",
+			filter: AgentFilter{DomainPrefix: "a."},
+			limit:  10,
+			want:   []string{"eu/a.example.com", "us/a.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		page, next := mem.ListAgents(ctx, tt.filter, tt.after, tt.limit)
+		var got []string
+		for _, a := range page {
+			got = append(got, a.Region+"/"+a.AgentDomain)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ListAgents() = %v, want %v", tt.name, got, tt.want)
+		}
+		if (next == nil) != (tt.next == nil) || next != nil && *next != *tt.next {
+			t.Errorf("%s: ListAgents() cursor = %v, want %v", tt.name, next, tt.next)
+		}
+	}
+}
+
+func TestListRegions(t *testing.T) {
+	mem := newListStore(t)
+	ctx := context.Background()
+
+	// ap holds nothing since its gateway was deleted
+	page, next := mem.ListRegions(ctx, "", 1)
+	if len(page) != 1 || page[0].Name != "eu" || next != "eu" {
+		t.Fatalf("ListRegions() = %+v, %q, want eu and a cursor", page, next)
+	}
+	if page[0].Gateways != 3 || page[0].Agents != 3 {
+		t.Errorf("eu summary = %+v", page[0])
+	}
+	page, next = mem.ListRegions(ctx, next, 1)
+	if len(page) != 1 || page[0].Name != "us" || next != "" {
+		t.Errorf("ListRegions(eu) = %+v, %q, want us alone", page, next)
+	}
+
+	if _, ok := mem.GetRegion(ctx, "ap"); ok {
+		t.Error("GetRegion(ap) found an empty region")
+	}
+	var names []string
+	for _, s := range mem.Stats() {
+		names = append(names, s.Name)
+	}
+	if !slices.Equal(names, []string{"eu", "us"}) {
+		t.Errorf("Stats() regions = %v, want eu and us", names)
+	}
+}
+
+// A limit below one is a page of one, so paging still moves on.
+func TestListLimit(t *testing.T) {
+	mem := newListStore(t)
+	ctx := context.Background()
+
+	for _, limit := range []int{0, -1} {
+		regions, nextRegion := mem.ListRegions(ctx, "", limit)
+		if len(regions) != 1 || nextRegion != "eu" {
+			t.Errorf("ListRegions(limit %d) = %v, %q, want eu and a cursor", limit, regions, nextRegion)
+		}
+		gateways, next := mem.ListGateways(ctx, GatewayFilter{}, nil, limit)
+		if len(gateways) != 1 || next == nil || next.Key != "g1" {
+			t.Errorf("ListGateways(limit %d) = %+v, %+v, want eu/g1 and a cursor", limit, gateways, next)
+		}
+		agents, next := mem.ListAgents(ctx, AgentFilter{}, nil, limit)
+		if len(agents) != 1 || next == nil || next.Key != "a.example.com" {
+			t.Errorf("ListAgents(limit %d) = %+v, %+v, want eu/a.example.com and a cursor", limit, agents, next)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package memstore
 
 import (
+	"log/slog"
 	"sync"
+	"sync/atomic"
 
 	"github.com/google/btree"
 )
@@ -14,9 +16,14 @@ const (
 )
 
 type MemStore struct {
-	mu      sync.RWMutex
-	regions map[string]*MemData
-	global  *MemData
+	mu       sync.RWMutex
+	regions  map[string]*MemData
+	global   *MemData
+	quotas   Quotas
+	strategy Strategy
+	journal  Journal
+	changes  *changeLog
+	logger   *slog.Logger
 }
 
 type MemData struct {
@@ -24,10 +31,21 @@ type MemData struct {
 	Agents   map[string]*AgentData
 	Seeders  map[string]*SeederData
 	ranked   *btree.BTree
-	Mu       sync.RWMutex
+	// agentsByGateway and agentsByCredential index the agent domains by
+	// gateway id and credential hash, gatewaysByIP the gateway ids by IP
+	agentsByGateway    reverse
+	agentsByCredential reverse
+	gatewaysByIP       reverse
+	// domains indexes the agent domains by label for wildcard and suffix
+	// lookups
+	domains domainTrie
+	// next is the round robin position of GetTopKGateways
+	next atomic.Uint64
+	Mu   sync.RWMutex
 }
 
 type AgentData struct {
+	Region         string
 	AgentID        string
 	AgentDomain    string
 	GatewayID      string
@@ -36,6 +54,8 @@ type AgentData struct {
 	Wssport        int32
 	GatewayAddress string
 	VerifiableHash string
+	// Subject is the identity verified from the signed registration credential
+	Subject string
 }
 type SeederData struct {
 	SeederID       string
@@ -48,6 +68,7 @@ type SeederData struct {
 }
 
 type GatewayData struct {
+	Region         string
 	GatewayID      string
 	GatewayIP      string
 	GatewayAddress string
@@ -55,6 +76,8 @@ type GatewayData struct {
 	Wssport        int32
 	Capacity       Capacity
 	VerifiableHash string
+	Subject        string
+	State          GatewayState
 }
 
 type Capacity struct {
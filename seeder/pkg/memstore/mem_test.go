@@ -0,0 +1,68 @@
+package memstore
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func newTestStore() *MemStore {
+	return NewMemStore(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// addGateway registers an active gateway ranked by cpu.
+func addGateway(t *testing.T, mem *MemStore, region, id string, cpu int32) {
+	t.Helper()
+	_, _, err := mem.AddGateway(context.Background(), region, &GatewayData{
+		GatewayID:   id,
+		GatewayIP:   "10.0.0.1",
+		GatewayPort: 7000,
+		Capacity:    Capacity{CPU: cpu},
+	})
+	if err != nil {
+		t.Fatalf("AddGateway(%s) error = %v", id, err)
+	}
+}
+
+func addAgent(mem *MemStore, region, domain, gatewayID, hash, subject string) error {
+	_, _, _, err := mem.AddAgent(context.Background(), region, &AgentData{
+		AgentDomain:    domain,
+		GatewayID:      gatewayID,
+		VerifiableHash: hash,
+		Subject:        subject,
+	}, false)
+	return err
+}
+
+func TestPrevious(t *testing.T) {
+	ctx := context.Background()
+	mem := newTestStore()
+
+	_, previous, err := mem.AddGateway(ctx, "eu", &GatewayData{GatewayID: "g1", GatewayIP: "10.0.0.1", GatewayPort: 7000})
+	if err != nil || previous != nil {
+		t.Fatalf("AddGateway(g1) previous = %+v, %v, want none", previous, err)
+	}
+	_, previous, err = mem.AddGateway(ctx, "eu", &GatewayData{GatewayID: "g1", GatewayIP: "10.0.0.2", GatewayPort: 7000})
+	if err != nil || previous == nil || previous.GatewayIP != "10.0.0.1" {
+		t.Errorf("AddGateway(g1) again previous = %+v, %v, want the first registration", previous, err)
+	}
+	addGateway(t, mem, "eu", "g2", 1)
+
+	agent := func() *AgentData { return &AgentData{AgentDomain: "a.example.com", GatewayID: "g1", VerifiableHash: "h1"} }
+	_, _, old, err := mem.AddAgent(ctx, "eu", agent(), false)
+	if err != nil || old != nil {
+		t.Fatalf("AddAgent() previous = %+v, %v, want none", old, err)
+	}
+	moved := agent()
+	moved.GatewayID = "g2"
+	_, _, old, err = mem.AddAgent(ctx, "eu", moved, false)
+	if err != nil || old == nil || old.GatewayID != "g1" || old.GatewayAddress != "10.0.0.2:7000" {
+		t.Errorf("AddAgent() onto g2 previous = %+v, %v, want the agent on g1", old, err)
+	}
+
+	_, _, reassigned, err := mem.ReassignAgent(ctx, "eu", "a.example.com", "g1")
+	if err != nil || reassigned.GatewayID != "g2" {
+		t.Errorf("ReassignAgent() previous = %+v, %v, want the agent on g2", reassigned, err)
+	}
+}
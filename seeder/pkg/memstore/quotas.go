@@ -0,0 +1,67 @@
+package memstore
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quotas are hard caps on the registry size, zero disables a cap.
+type Quotas struct {
+	MaxGatewaysPerRegion   int `yaml:"max_gateways_per_region"`
+	MaxAgentsPerGateway    int `yaml:"max_agents_per_gateway"`
+	MaxAgentsPerCredential int `yaml:"max_agents_per_credential"`
+}
+
+func (mem *MemStore) SetQuotas(quotas Quotas) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.quotas = quotas
+}
+
+func (mem *MemStore) getQuotas() Quotas {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	return mem.quotas
+}
+
+// checkGatewayQuota must be called with the region lock held.
+func (data *MemData) checkGatewayQuota(quotas Quotas, region, gatewayID string) error {
+	if quotas.MaxGatewaysPerRegion <= 0 {
+		return nil
+	}
+	if _, exist := data.Gateways[gatewayID]; exist {
+		return nil
+	}
+	if len(data.Gateways) >= quotas.MaxGatewaysPerRegion {
+		return fmt.Errorf("%w: region %s already has %d gateways", ErrQuotaExceeded, region, len(data.Gateways))
+	}
+	return nil
+}
+
+// checkAgentQuota must be called with the region lock held. Re-registering
+// an agent on the gateway it already uses never counts against the quota.
+func (data *MemData) checkAgentQuota(quotas Quotas, agent *AgentData) error {
+	if quotas.MaxAgentsPerGateway <= 0 && quotas.MaxAgentsPerCredential <= 0 {
+		return nil
+	}
+
+	current, exist := data.Agents[agent.AgentDomain]
+	if exist && current.GatewayID == agent.GatewayID {
+		return nil
+	}
+
+	perGateway, perCredential := data.agentsOn(agent.GatewayID), 0
+	if !exist {
+		perCredential = data.agentsByCredential.count(agent.VerifiableHash)
+	}
+
+	if quotas.MaxAgentsPerGateway > 0 && perGateway >= quotas.MaxAgentsPerGateway {
+		return fmt.Errorf("%w: gateway %s already has %d agents", ErrQuotaExceeded, agent.GatewayID, perGateway)
+	}
+	if quotas.MaxAgentsPerCredential > 0 && perCredential >= quotas.MaxAgentsPerCredential {
+		return fmt.Errorf("%w: credential already registered %d agents", ErrQuotaExceeded, perCredential)
+	}
+	return nil
+}
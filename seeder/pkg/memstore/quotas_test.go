@@ -0,0 +1,54 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGatewayQuota(t *testing.T) {
+	mem := newTestStore()
+	mem.SetQuotas(Quotas{MaxGatewaysPerRegion: 2})
+	addGateway(t, mem, "eu", "g1", 1)
+	addGateway(t, mem, "eu", "g2", 1)
+
+	_, _, err := mem.AddGateway(context.Background(), "eu", &GatewayData{GatewayID: "g3"})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("third gateway error = %v, want %v", err, ErrQuotaExceeded)
+	}
+	// re-registering counts nothing, other regions have their own quota
+	addGateway(t, mem, "eu", "g1", 2)
+	addGateway(t, mem, "us", "g3", 1)
+
+	mem.SetQuotas(Quotas{})
+	addGateway(t, mem, "eu", "g3", 1)
+}
+
+func TestAgentQuotas(t *testing.T) {
+	mem := newTestStore()
+	addGateway(t, mem, "eu", "g1", 1)
+	addGateway(t, mem, "eu", "g2", 1)
+	mem.SetQuotas(Quotas{MaxAgentsPerGateway: 2, MaxAgentsPerCredential: 3})
+
+	steps := []struct {
+		name    string
+		domain  string
+		gateway string
+		hash    string
+		want    error
+	}{
+		{name: "first", domain: "a.example.com", gateway: "g1", hash: "h1"},
+		{name: "second", domain: "b.example.com", gateway: "g1", hash: "h1"},
+		{name: "gateway full", domain: "c.example.com", gateway: "g1", hash: "h2", want: ErrQuotaExceeded},
+		{name: "re-registration on a full gateway", domain: "a.example.com", gateway: "g1", hash: "h1"},
+		{name: "other gateway", domain: "c.example.com", gateway: "g2", hash: "h1"},
+		{name: "credential full", domain: "d.example.com", gateway: "g2", hash: "h1", want: ErrQuotaExceeded},
+		{name: "other credential", domain: "d.example.com", gateway: "g2", hash: "h2"},
+	}
+	for _, s := range steps {
+		err := addAgent(mem, "eu", s.domain, s.gateway, s.hash, "")
+		if !errors.Is(err, s.want) {
+			t.Errorf("%s: AddAgent(%s) error = %v, want %v", s.name, s.domain, err, s.want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/odio4u/memstore/seeder/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/time/rate"
+)
+
+// ErrNoGateway means no active gateway of the region can take an agent.
+var ErrNoGateway = errors.New("no gateway can take the agent")
+
+// errStale is a planned move the store changed under, it is skipped.
+var errStale = errors.New("agent moved since the pass was planned")
+
+// Reasons for a move besides the state of the gateway the agent was on.
+const (
+	MoveGatewayGone = "gateway_gone"
+	MoveOverloaded  = "overloaded"
+)
+
+// RebalanceConfig tunes the rebalancer.
+type RebalanceConfig struct {
+	// Interval is the time between passes, 0 disables the rebalancer
+	Interval time.Duration `yaml:"interval"`
+	// Rate caps the moves per second, Burst how many go back to back
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+	// Overload moves agents off an active gateway holding more than this
+	// factor of its capacity share of the region's agents. 0 only moves
+	// agents off gateways that are gone or not active.
+	Overload float64 `yaml:"overload"`
+}
+
+// Move is one agent the rebalancer moved.
+type Move struct {
+	// Agent and Gateway are the agent on its new gateway
+	Agent   AgentData
+	Gateway GatewayData
+	From    string
+	// Reason is the state of the old gateway, gateway_gone or overloaded
+	Reason string
+}
+
+// Rebalancer moves agents off gateways that are gone, not active or
+// overloaded, onto active gateways picked by the selection strategy.
+type Rebalancer struct {
+	store   *MemStore
+	config  RebalanceConfig
+	limiter *rate.Limiter
+	// OnMove is called with every move once it is made. The store's
+	// journal persisted it before, a move it failed to persist ends the
+	// pass and is not made.
+	OnMove func(move Move)
+	logger *slog.Logger
+}
+
+func NewRebalancer(store *MemStore, config RebalanceConfig, logger *slog.Logger) *Rebalancer {
+	burst := config.Burst
+	if burst <= 0 {
+		burst = max(1, int(config.Rate))
+	}
+	return &Rebalancer{
+		store:   store,
+		config:  config,
+		limiter: rate.NewLimiter(rate.Limit(config.Rate), burst),
+		logger:  logger.With("component", "rebalancer"),
+	}
+}
+
+// Run makes a pass every interval until ctx is done.
+func (r *Rebalancer) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Pass(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Error("rebalance pass failed", "err", err)
+			}
+		}
+	}
+}
+
+// Pass moves every agent that needs it, region by region, waiting for the
+// rate limit before each move. It returns how many agents moved.
+func (r *Rebalancer) Pass(ctx context.Context) (moved int, err error) {
+	ctx, span := tracing.Child(ctx, "memstore.Rebalance")
+	defer func() {
+		span.SetAttributes(attribute.Int("moved", moved))
+		tracing.End(span, err)
+	}()
+
+	quota := r.store.getQuotas().MaxAgentsPerGateway
+	for _, region := range r.store.sortedRegions("") {
+		for _, c := range r.store.region(region).plan(r.config.Overload, quota) {
+			if err := r.limiter.Wait(ctx); err != nil {
+				return moved, err
+			}
+
+			move, err := r.store.migrate(ctx, region, c, r.config.Overload)
+			if errors.Is(err, errStale) {
+				continue
+			}
+			if errors.Is(err, ErrNoGateway) {
+				r.logger.Warn("agents left on their gateway, no gateway can take them", "region", region, "gateway_id", c.from, "reason", c.reason)
+				break
+			}
+			if err != nil {
+				return moved, err
+			}
+
+			moved++
+			r.logger.Info("moved agent",
+				"region", region,
+				"agent_domain", move.Agent.AgentDomain,
+				"from", move.From,
+				"to", move.Gateway.GatewayID,
+				"reason", move.Reason,
+			)
+			if r.OnMove != nil {
+				r.OnMove(move)
+			}
+		}
+	}
+	return moved, nil
+}
+
+// candidate is an agent a pass plans to move.
+type candidate struct {
+	domain string
+	from   string
+	reason string
+}
+
+// balance is the load of the gateways of a region and the limits derived
+// from it. It reads the region's index, so it is only valid while the
+// region lock is held.
+type balance struct {
+	byGateway reverse
+	agents    int
+	rank      float64
+	overload  float64
+	quota     int
+}
+
+func (data *MemData) balance(overload float64, quota int) balance {
+	b := balance{byGateway: data.agentsByGateway, agents: len(data.Agents), overload: overload, quota: quota}
+	for _, g := range data.Gateways {
+		if g.State.Assignable() {
+			b.rank += g.Capacity.Rank()
+		}
+	}
+	return b
+}
+
+// limit is the most agents gateway should hold, -1 when nothing limits it.
+func (b balance) limit(gateway *GatewayData) int {
+	limit := -1
+	if b.overload > 0 && b.rank > 0 {
+		limit = int(math.Ceil(b.overload * float64(b.agents) * gateway.Capacity.Rank() / b.rank))
+	}
+	if b.quota > 0 && (limit < 0 || b.quota < limit) {
+		limit = b.quota
+	}
+	return limit
+}
+
+// full reports whether gateway cannot take one more agent.
+func (b balance) full(gateway *GatewayData) bool {
+	limit := b.limit(gateway)
+	return limit >= 0 && b.byGateway.count(gateway.GatewayID) >= limit
+}
+
+// over reports whether gateway holds more agents than it should.
+func (b balance) over(gateway *GatewayData) bool {
+	limit := b.limit(gateway)
+	return limit >= 0 && b.byGateway.count(gateway.GatewayID) > limit
+}
+
+// plan lists the agents to move, gateway by gateway: all of those on
+// missing or inactive gateways and the excess of overloaded ones.
+func (data *MemData) plan(overload float64, quota int) []candidate {
+	data.Mu.RLock()
+	defer data.Mu.RUnlock()
+
+	b := data.balance(overload, quota)
+	var plan []candidate
+	for _, from := range sortedKeys(data.agentsByGateway) {
+		domains := data.agentsByGateway.sorted(from)
+		gateway, exist := data.Gateways[from]
+		reason := MoveGatewayGone
+		switch {
+		case exist && gateway.State.Assignable():
+			if !b.over(gateway) {
+				continue
+			}
+			reason = MoveOverloaded
+			domains = domains[:len(domains)-b.limit(gateway)]
+		case exist:
+			reason = string(gateway.State)
+		}
+		for _, domain := range domains {
+			plan = append(plan, candidate{domain, from, reason})
+		}
+	}
+	return plan
+}
+
+// migrate moves a planned agent onto an active gateway that is not full,
+// picked by the selection strategy among the others.
+func (mem *MemStore) migrate(ctx context.Context, region string, c candidate, overload float64) (_ Move, err error) {
+	_, span := tracing.Child(ctx, "memstore.MigrateAgent",
+		attribute.String("region", region),
+		attribute.String("reason", c.reason),
+	)
+	defer func() {
+		// a skipped move is no failure
+		failed := err
+		if errors.Is(err, errStale) {
+			failed = nil
+		}
+		tracing.End(span, failed)
+	}()
+
+	strategy := mem.getStrategy()
+	quotas := mem.getQuotas()
+	data := mem.region(region)
+
+	data.Mu.Lock()
+	defer data.Mu.Unlock()
+	span.AddEvent("region lock acquired")
+
+	agent, exist := data.Agents[c.domain]
+	if !exist || agent.GatewayID != c.from {
+		return Move{}, errStale
+	}
+	b := data.balance(overload, quotas.MaxAgentsPerGateway)
+	if source, exist := data.Gateways[c.from]; c.reason == MoveOverloaded && (!exist || !b.over(source)) {
+		return Move{}, errStale
+	}
+
+	targets := data.assignable(0, func(g *GatewayData) bool {
+		return g.GatewayID == c.from || b.full(g)
+	})
+	if len(targets) == 0 {
+		return Move{}, fmt.Errorf("%w: %s in region %s", ErrNoGateway, c.domain, region)
+	}
+	gateway := pick(targets, 1, strategy, data.next.Add(1)-1)[0]
+	moved := *agent
+	moved.assign(gateway)
+	if err := mem.persist(func(j Journal) error { return j.PutAgent(ctx, moved, *gateway) }); err != nil {
+		return Move{}, err
+	}
+
+	data.moveAgent(agent, gateway)
+	mem.recordAgent(ChangePut, *agent)
+	mem.leftGateway(data, c.from, gateway.GatewayID)
+	return Move{Agent: *agent, Gateway: *gateway, From: c.from, Reason: c.reason}, nil
+}
@@ -0,0 +1,181 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// newRebalanceStore holds g1 and g2 active with the same capacity, g3
+// draining, and g4 deleted with its agent left behind. g1 holds four agents,
+// g3 and g4 one each.
+func newRebalanceStore(t *testing.T) *MemStore {
+	t.Helper()
+	mem := newTestStore()
+	ctx := context.Background()
+	for _, id := range []string{"g1", "g2", "g3", "g4"} {
+		addGateway(t, mem, "eu", id, 1)
+	}
+	for i := range 4 {
+		if err := addAgent(mem, "eu", fmt.Sprintf("a%d.example.com", i), "g1", "h1", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"g3", "g4"} {
+		if err := addAgent(mem, "eu", id+".example.com", id, "h1", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := mem.SetGatewayState(ctx, "eu", "g3", GatewayDraining, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.DeleteGateway(ctx, "eu", "g4", true); err != nil {
+		t.Fatal(err)
+	}
+	return mem
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		overload float64
+		quota    int
+		want     []candidate
+	}{
+		{
+			name: "inactive and gone only",
+			want: []candidate{
+				{"g3.example.com", "g3", "draining"},
+				{"g4.example.com", "g4", MoveGatewayGone},
+			},
+		},
+		{
+			// six agents over two active gateways of equal capacity
+			name:     "overloaded",
+			overload: 1,
+			want: []candidate{
+				{"a0.example.com", "g1", MoveOverloaded},
+				{"g3.example.com", "g3", "draining"},
+				{"g4.example.com", "g4", MoveGatewayGone},
+			},
+		},
+		{
+			name:     "within the overload factor",
+			overload: 1.5,
+			want: []candidate{
+				{"g3.example.com", "g3", "draining"},
+				{"g4.example.com", "g4", MoveGatewayGone},
+			},
+		},
+		{
+			name:  "over the agent quota",
+			quota: 2,
+			want: []candidate{
+				{"a0.example.com", "g1", MoveOverloaded},
+				{"a1.example.com", "g1", MoveOverloaded},
+				{"g3.example.com", "g3", "draining"},
+				{"g4.example.com", "g4", MoveGatewayGone},
+			},
+		},
+	}
+	mem := newRebalanceStore(t)
+	for _, tt := range tests {
+		got := mem.region("eu").plan(tt.overload, tt.quota)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: plan() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func newTestRebalancer(mem *MemStore, overload float64) *Rebalancer {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewRebalancer(mem, RebalanceConfig{Rate: 1000, Burst: 10, Overload: overload}, logger)
+}
+
+func TestPass(t *testing.T) {
+	mem := newRebalanceStore(t)
+	ctx := context.Background()
+	start := mem.LastChange()
+
+	r := newTestRebalancer(mem, 1)
+	var moves []Move
+	r.OnMove = func(move Move) {
+		moves = append(moves, move)
+	}
+	moved, err := r.Pass(ctx)
+	if err != nil {
+		t.Fatalf("Pass() error = %v", err)
+	}
+	if moved != 3 || len(moves) != 3 {
+		t.Fatalf("Pass() moved %d agents, OnMove saw %d, want 3", moved, len(moves))
+	}
+	for _, move := range moves {
+		// g1 is full once it is down to its share, everything lands on g2
+		if move.Gateway.GatewayID != "g2" || move.Agent.GatewayID != "g2" {
+			t.Errorf("moved %s from %s to %s, want g2", move.Agent.AgentDomain, move.From, move.Gateway.GatewayID)
+		}
+	}
+	for id, want := range map[string]int{"g1": 3, "g2": 3, "g3": 0, "g4": 0} {
+		agents, _ := mem.ListAgents(ctx, AgentFilter{Region: "eu", GatewayID: id}, nil, 10)
+		if len(agents) != want {
+			t.Errorf("%s holds %d agents, want %d", id, len(agents), want)
+		}
+	}
+	if got := drained(t, mem, start); !slices.Equal(got, []string{"g3"}) {
+		t.Errorf("drained = %v, want g3", got)
+	}
+
+	if moved, err := r.Pass(ctx); moved != 0 || err != nil {
+		t.Errorf("second Pass() = %d, %v, want nothing to move", moved, err)
+	}
+}
+
+func TestPassWithoutTargets(t *testing.T) {
+	mem := newRebalanceStore(t)
+	ctx := context.Background()
+	for _, id := range []string{"g1", "g2"} {
+		if _, err := mem.SetGatewayState(ctx, "eu", id, GatewayOffline, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moved, err := newTestRebalancer(mem, 0).Pass(ctx)
+	if moved != 0 || err != nil {
+		t.Errorf("Pass() = %d, %v, want nothing moved and no error", moved, err)
+	}
+}
+
+func TestPassJournal(t *testing.T) {
+	mem := newRebalanceStore(t)
+	ctx := context.Background()
+	want, _ := mem.ListAgents(ctx, AgentFilter{Region: "eu"}, nil, 10)
+
+	// a move that is not persisted is not made and ends the pass
+	mem.SetJournal(&testJournal{err: errors.New("disk full")})
+	r := newTestRebalancer(mem, 0)
+	if moved, err := r.Pass(ctx); moved != 0 || !errors.Is(err, ErrNotPersisted) {
+		t.Fatalf("Pass() = %d, %v, want %v", moved, err, ErrNotPersisted)
+	}
+	if got, _ := mem.ListAgents(ctx, AgentFilter{Region: "eu"}, nil, 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("agents = %+v, want %+v", got, want)
+	}
+
+	journal := &testJournal{}
+	mem.SetJournal(journal)
+	var made []string
+	r.OnMove = func(move Move) {
+		made = append(made, fmt.Sprintf("put agent %s %s %s", move.Agent.AgentDomain, move.Gateway.GatewayID, move.Gateway.GatewayAddress))
+	}
+	moved, err := r.Pass(ctx)
+	if err != nil || moved != 2 {
+		t.Fatalf("Pass() = %d, %v, want 2 moved", moved, err)
+	}
+	if !slices.Equal(journal.changes, made) {
+		t.Errorf("journal = %q, want the moves %q", journal.changes, made)
+	}
+}
@@ -0,0 +1,88 @@
+package memstore
+
+import "sort"
+
+// keySet holds the primary keys an index entry points at.
+type keySet map[string]struct{}
+
+// reverse is a secondary index from a field to the primary keys holding it.
+// Every change goes through the MemData helpers below, under the region
+// lock, together with the primary map it follows.
+type reverse map[string]keySet
+
+func (r reverse) add(value, key string) {
+	keys, ok := r[value]
+	if !ok {
+		keys = make(keySet)
+		r[value] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+func (r reverse) remove(value, key string) {
+	keys, ok := r[value]
+	if !ok {
+		return
+	}
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(r, value)
+	}
+}
+
+func (r reverse) count(value string) int {
+	return len(r[value])
+}
+
+func (r reverse) has(value, key string) bool {
+	_, ok := r[value][key]
+	return ok
+}
+
+// sorted returns the keys holding value in order.
+func (r reverse) sorted(value string) []string {
+	keys := make([]string, 0, len(r[value]))
+	for key := range r[value] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// putAgent stores a new agent. The region lock must be held.
+func (data *MemData) putAgent(agent *AgentData) {
+	data.Agents[agent.AgentDomain] = agent
+	data.agentsByGateway.add(agent.GatewayID, agent.AgentDomain)
+	data.agentsByCredential.add(agent.VerifiableHash, agent.AgentDomain)
+	if d, err := ParseDomain(agent.AgentDomain); err == nil {
+		data.domains.insert(d, agent.AgentDomain)
+	}
+}
+
+// moveAgent points a stored agent at gateway. The region lock must be held.
+func (data *MemData) moveAgent(agent *AgentData, gateway *GatewayData) {
+	data.agentsByGateway.remove(agent.GatewayID, agent.AgentDomain)
+	agent.assign(gateway)
+	data.agentsByGateway.add(agent.GatewayID, agent.AgentDomain)
+}
+
+// putGateway stores a new or updated gateway. The region lock must be held.
+func (data *MemData) putGateway(gateway *GatewayData) {
+	if old, exist := data.Gateways[gateway.GatewayID]; exist {
+		data.gatewaysByIP.remove(old.GatewayIP, old.GatewayID)
+	}
+	data.Gateways[gateway.GatewayID] = gateway
+	data.gatewaysByIP.add(gateway.GatewayIP, gateway.GatewayID)
+}
+
+// deleteGateway removes a gateway. Agents still pointing at it keep their
+// index entry until they move. The region lock must be held.
+func (data *MemData) deleteGateway(gateway *GatewayData) {
+	delete(data.Gateways, gateway.GatewayID)
+	data.gatewaysByIP.remove(gateway.GatewayIP, gateway.GatewayID)
+}
+
+// agentsOn counts the agents assigned to a gateway, the region lock must be held.
+func (data *MemData) agentsOn(gatewayID string) int {
+	return data.agentsByGateway.count(gatewayID)
+}
@@ -0,0 +1,94 @@
+package memstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// checkIndexes rebuilds the indexes of region from its agents and gateways
+// and compares them with the ones the store kept up to date.
+func checkIndexes(t *testing.T, mem *MemStore, region, step string) {
+	t.Helper()
+	data := mem.RegionExist(region)
+	data.Mu.RLock()
+	defer data.Mu.RUnlock()
+
+	byGateway, byCredential, byIP := make(reverse), make(reverse), make(reverse)
+	for domain, a := range data.Agents {
+		byGateway.add(a.GatewayID, domain)
+		byCredential.add(a.VerifiableHash, domain)
+	}
+	for id, g := range data.Gateways {
+		byIP.add(g.GatewayIP, id)
+	}
+
+	if !reflect.DeepEqual(data.agentsByGateway, byGateway) {
+		t.Errorf("%s: agentsByGateway = %v, want %v", step, data.agentsByGateway, byGateway)
+	}
+	if !reflect.DeepEqual(data.agentsByCredential, byCredential) {
+		t.Errorf("%s: agentsByCredential = %v, want %v", step, data.agentsByCredential, byCredential)
+	}
+	if !reflect.DeepEqual(data.gatewaysByIP, byIP) {
+		t.Errorf("%s: gatewaysByIP = %v, want %v", step, data.gatewaysByIP, byIP)
+	}
+}
+
+func TestIndexesFollowChanges(t *testing.T) {
+	mem := newTestStore()
+	ctx := context.Background()
+	addGateway(t, mem, "eu", "g1", 4)
+	addGateway(t, mem, "eu", "g2", 4)
+	for _, a := range []struct{ domain, gateway, hash string }{
+		{"a.example.com", "g1", "h1"},
+		{"b.example.com", "g1", "h2"},
+		{"c.example.com", "g2", "h1"},
+	} {
+		if err := addAgent(mem, "eu", a.domain, a.gateway, a.hash, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkIndexes(t, mem, "eu", "registered")
+
+	// re-registering moves the agent, its credential stays
+	if err := addAgent(mem, "eu", "a.example.com", "g2", "h1", ""); err != nil {
+		t.Fatal(err)
+	}
+	checkIndexes(t, mem, "eu", "re-registered agent")
+
+	if _, _, _, err := mem.ReassignAgent(ctx, "eu", "b.example.com", "g2"); err != nil {
+		t.Fatal(err)
+	}
+	checkIndexes(t, mem, "eu", "reassigned agent")
+	if n := mem.RegionExist("eu").agentsOn("g1"); n != 0 {
+		t.Errorf("g1 holds %d agents after they all moved", n)
+	}
+
+	_, _, err := mem.AddGateway(ctx, "eu", &GatewayData{GatewayID: "g1", GatewayIP: "10.0.0.9", GatewayPort: 7000, Capacity: Capacity{CPU: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIndexes(t, mem, "eu", "gateway with a new IP")
+	for ip, want := range map[string]string{"10.0.0.1": "g2", "10.0.0.9": "g1"} {
+		page, _ := mem.ListGateways(ctx, GatewayFilter{Region: "eu", GatewayIP: ip}, nil, 10)
+		if len(page) != 1 || page[0].GatewayID != want {
+			t.Errorf("ListGateways(%s) = %+v, want %s alone", ip, page, want)
+		}
+	}
+
+	// agents of a deleted gateway keep their entry until they move
+	if _, err := mem.DeleteGateway(ctx, "eu", "g2", true); err != nil {
+		t.Fatal(err)
+	}
+	checkIndexes(t, mem, "eu", "deleted gateway")
+
+	moved, err := newTestRebalancer(mem, 0).Pass(ctx)
+	if err != nil || moved != 3 {
+		t.Fatalf("Pass() = %d, %v, want 3 agents moved", moved, err)
+	}
+	checkIndexes(t, mem, "eu", "rebalanced")
+	agents, _ := mem.ListAgents(ctx, AgentFilter{Region: "eu", VerifiableHash: "h1"}, nil, 10)
+	if len(agents) != 2 || agents[0].GatewayID != "g1" || agents[1].GatewayID != "g1" {
+		t.Errorf("agents of h1 = %+v, want both on g1", agents)
+	}
+}
@@ -13,8 +13,11 @@ func (mem *MemStore) AddSeeder(seeder *SeederData) bool {
 }
 
 func (mem *MemStore) GetSeeders(region string) []*SeederData {
-	data := mem.RegionExist(region)
+	data := mem.region(region)
 	result := make([]*SeederData, 0, 5)
+	if data == nil {
+		return result
+	}
 
 	for _, v := range data.Seeders {
 		result = append(result, v)
@@ -0,0 +1,43 @@
+package memstore
+
+import "fmt"
+
+// GatewayState decides whether a gateway takes new agents. Agents already
+// on a gateway keep resolving to it in every state.
+type GatewayState string
+
+const (
+	GatewayActive GatewayState = "active"
+	// GatewayDraining keeps routing existing agents but is skipped when
+	// picking gateways for new ones.
+	GatewayDraining GatewayState = "draining"
+	// GatewayMaintenance is a gateway taken out for work on its host. Only
+	// a gateway without agents enters it, drain it first.
+	GatewayMaintenance GatewayState = "maintenance"
+	// GatewayOffline is a gateway known to be down, its agents should be
+	// moved elsewhere.
+	GatewayOffline GatewayState = "offline"
+)
+
+func ParseGatewayState(name string) (GatewayState, error) {
+	switch s := GatewayState(name); s {
+	case GatewayActive, GatewayDraining, GatewayMaintenance, GatewayOffline:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown gateway state %q, use active, draining, maintenance or offline", name)
+}
+
+// Assignable reports whether new agents may be placed on the gateway.
+func (s GatewayState) Assignable() bool {
+	return s == GatewayActive
+}
+
+// checkDrained records a drained change once a draining gateway has no
+// agents left. The region lock must be held.
+func (mem *MemStore) checkDrained(data *MemData, gateway *GatewayData) {
+	if gateway.State != GatewayDraining || data.agentsOn(gateway.GatewayID) > 0 {
+		return
+	}
+	mem.recordGateway(ChangeDrained, *gateway)
+	mem.logger.Info("gateway drained", "region", gateway.Region, "gateway_id", gateway.GatewayID)
+}
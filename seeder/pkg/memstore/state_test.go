@@ -0,0 +1,144 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseGatewayState(t *testing.T) {
+	for _, name := range []string{"active", "draining", "maintenance", "offline"} {
+		state, err := ParseGatewayState(name)
+		if err != nil || string(state) != name {
+			t.Errorf("ParseGatewayState(%q) = %q, %v", name, state, err)
+		}
+	}
+	for _, name := range []string{"", "Active", "down"} {
+		if _, err := ParseGatewayState(name); err == nil {
+			t.Errorf("ParseGatewayState(%q) succeeded", name)
+		}
+	}
+
+	if !GatewayActive.Assignable() {
+		t.Error("active gateways take no agents")
+	}
+	for _, state := range []GatewayState{GatewayDraining, GatewayMaintenance, GatewayOffline} {
+		if state.Assignable() {
+			t.Errorf("%s gateways take agents", state)
+		}
+	}
+}
+
+func setState(t *testing.T, mem *MemStore, id string, state GatewayState) error {
+	t.Helper()
+	_, err := mem.SetGatewayState(context.Background(), "eu", id, state, false)
+	return err
+}
+
+// drained returns the gateways a drained change was recorded for since id.
+func drained(t *testing.T, mem *MemStore, id uint64) []string {
+	t.Helper()
+	changes, _, ok := mem.ChangesSince(id)
+	if !ok {
+		t.Fatalf("ChangesSince(%d) lost changes", id)
+	}
+	var ids []string
+	for _, c := range changes {
+		if c.Kind == ChangeDrained {
+			ids = append(ids, c.Key)
+		}
+	}
+	return ids
+}
+
+func TestGatewayStates(t *testing.T) {
+	mem := newTestStore()
+	ctx := context.Background()
+	addGateway(t, mem, "eu", "g1", 4)
+	addGateway(t, mem, "eu", "g2", 2)
+	if err := addAgent(mem, "eu", "a.example.com", "g1", "h1", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setState(t, mem, "g1", GatewayMaintenance); !errors.Is(err, ErrGatewayHasAgents) {
+		t.Fatalf("maintenance with agents error = %v, want %v", err, ErrGatewayHasAgents)
+	}
+	if err := setState(t, mem, "g1", GatewayDraining); err != nil {
+		t.Fatal(err)
+	}
+
+	// a draining gateway takes no new agents but keeps its own
+	if err := addAgent(mem, "eu", "b.example.com", "g1", "h1", ""); !errors.Is(err, ErrGatewayNotActive) {
+		t.Errorf("AddAgent() on a draining gateway error = %v, want %v", err, ErrGatewayNotActive)
+	}
+	if err := addAgent(mem, "eu", "a.example.com", "g1", "h1", ""); err != nil {
+		t.Errorf("re-registering on the draining gateway error = %v", err)
+	}
+	if _, _, _, err := mem.ReassignAgent(ctx, "eu", "a.example.com", "g1"); !errors.Is(err, ErrGatewayNotActive) {
+		t.Errorf("ReassignAgent() onto a draining gateway error = %v, want %v", err, ErrGatewayNotActive)
+	}
+	if top := mem.GetTopKGateways(ctx, "eu", 2); len(top) != 1 || top[0].GatewayID != "g2" {
+		t.Errorf("GetTopKGateways() = %+v, want g2 alone", top)
+	}
+
+	// re-registering the gateway does not end the drain
+	addGateway(t, mem, "eu", "g1", 8)
+	if g, _ := mem.GetGateway(ctx, "eu", "g1"); g.State != GatewayDraining {
+		t.Errorf("state after re-registration = %s, want %s", g.State, GatewayDraining)
+	}
+
+	if _, err := mem.SetGatewayState(ctx, "eu", "missing", GatewayActive, false); !errors.Is(err, ErrGatewayNotFound) {
+		t.Errorf("SetGatewayState() on a missing gateway error = %v, want %v", err, ErrGatewayNotFound)
+	}
+	if _, err := mem.SetGatewayState(ctx, "ap", "g1", GatewayActive, false); !errors.Is(err, ErrGatewayNotFound) {
+		t.Errorf("SetGatewayState() in a missing region error = %v, want %v", err, ErrGatewayNotFound)
+	}
+}
+
+func TestGatewayDrained(t *testing.T) {
+	mem := newTestStore()
+	ctx := context.Background()
+	addGateway(t, mem, "eu", "g1", 4)
+	addGateway(t, mem, "eu", "g2", 2)
+	addGateway(t, mem, "eu", "g3", 1)
+	for _, domain := range []string{"a.example.com", "b.example.com"} {
+		if err := addAgent(mem, "eu", domain, "g1", "h1", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := mem.LastChange()
+	if err := setState(t, mem, "g1", GatewayDraining); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := mem.ReassignAgent(ctx, "eu", "a.example.com", "g2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := drained(t, mem, start); len(got) != 0 {
+		t.Fatalf("drained %v with an agent left", got)
+	}
+
+	// the last agent leaves by registering elsewhere
+	last := mem.LastChange()
+	if err := addAgent(mem, "eu", "b.example.com", "g2", "h1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := drained(t, mem, last); len(got) != 1 || got[0] != "g1" {
+		t.Fatalf("drained = %v, want g1", got)
+	}
+	if err := setState(t, mem, "g1", GatewayMaintenance); err != nil {
+		t.Errorf("maintenance once drained error = %v", err)
+	}
+
+	// an empty gateway is drained as soon as it starts draining, and only
+	// once
+	last = mem.LastChange()
+	for range 2 {
+		if err := setState(t, mem, "g3", GatewayDraining); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := drained(t, mem, last); len(got) != 1 || got[0] != "g3" {
+		t.Errorf("drained = %v, want g3 once", got)
+	}
+}
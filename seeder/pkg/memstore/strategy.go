@@ -0,0 +1,59 @@
+package memstore
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+// Strategy orders the gateways offered to a new agent.
+type Strategy string
+
+const (
+	// StrategyRank offers the gateways in capacity rank order
+	StrategyRank Strategy = "rank"
+	// StrategyRoundRobin rotates the start of the ranked list on every
+	// call, spreading new agents over all gateways of a region
+	StrategyRoundRobin Strategy = "round_robin"
+	// StrategyRandom offers the gateways in random order
+	StrategyRandom Strategy = "random"
+)
+
+func ParseStrategy(name string) (Strategy, error) {
+	switch s := Strategy(name); s {
+	case "":
+		return StrategyRank, nil
+	case StrategyRank, StrategyRoundRobin, StrategyRandom:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown gateway strategy %q, use rank, round_robin or random", name)
+}
+
+func (mem *MemStore) SetStrategy(strategy Strategy) {
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.strategy = strategy
+}
+
+func (mem *MemStore) getStrategy() Strategy {
+	mem.mu.RLock()
+	defer mem.mu.RUnlock()
+	return mem.strategy
+}
+
+// pick returns up to k of the ranked gateways in the order of strategy.
+// next is the round robin position of the region.
+func pick(ranked []*GatewayData, k int, strategy Strategy, next uint64) []*GatewayData {
+	switch strategy {
+	case StrategyRoundRobin:
+		if n := len(ranked); n > 0 {
+			start := int(next % uint64(n))
+			ranked = slices.Concat(ranked[start:], ranked[:start])
+		}
+	case StrategyRandom:
+		rand.Shuffle(len(ranked), func(i, j int) {
+			ranked[i], ranked[j] = ranked[j], ranked[i]
+		})
+	}
+	return ranked[:min(k, len(ranked))]
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"time"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "seeder"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	RPCRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "gRPC requests by method and status code.",
+	}, []string{"method", "code"})
+
+	RPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "gRPC request latency by method.",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"method"})
+
+	// Errors counts failures by error code, including the in-band errors
+	// returned to legacy clients with an OK status.
+	Errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Failed requests by method and error code.",
+	}, []string{"method", "code"})
+
+	WALAppendDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "wal",
+		Name:      "append_duration_seconds",
+		Help:      "Time to encode and flush one WAL record.",
+		Buckets:   prometheus.ExponentialBuckets(.00001, 4, 10),
+	})
+
+	WALBytesWritten = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "wal",
+		Name:      "bytes_written_total",
+		Help:      "Bytes appended to the WAL, framing included.",
+	})
+
+	WALFsyncDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "wal",
+		Name:      "fsync_duration_seconds",
+		Help:      "Time to fsync the WAL file.",
+		Buckets:   prometheus.ExponentialBuckets(.0001, 4, 10),
+	})
+
+	WALReplayDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "wal",
+		Name:      "replay_duration_seconds",
+		Help:      "Duration of the last WAL replay.",
+	})
+
+	WALReplayRecords = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "wal",
+		Name:      "replay_records",
+		Help:      "Records applied by the last WAL replay.",
+	})
+
+	WALReplaySkipped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "wal",
+		Name:      "replay_skipped_records_total",
+		Help:      "Records of the snapshot or the WAL replay left out because the store refused them.",
+	})
+
+	ConfigReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "config",
+		Name:      "reloads_total",
+		Help:      "Config reloads by result: applied, unchanged or failed.",
+	}, []string{"result"})
+
+	ConfigLastReload = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "config",
+		Name:      "last_reload_success_timestamp_seconds",
+		Help:      "Time of the last reload that did not fail.",
+	})
+
+	ConfigRestartPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "config",
+		Name:      "restart_pending_keys",
+		Help:      "Changed config keys that only apply after a restart.",
+	})
+
+	RebalanceMoves = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rebalance",
+		Name:      "moves_total",
+		Help:      "Agents moved by the rebalancer by reason.",
+	}, []string{"reason"})
+)
+
+func init() {
+	registry.MustRegister(
+		RPCRequests,
+		RPCDuration,
+		Errors,
+		WALAppendDuration,
+		WALBytesWritten,
+		WALFsyncDuration,
+		WALReplayDuration,
+		WALReplayRecords,
+		WALReplaySkipped,
+		ConfigReloads,
+		ConfigLastReload,
+		ConfigRestartPending,
+		RebalanceMoves,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// MustRegister adds collectors, such as the store collector, to the
+// registry served on /metrics.
+func MustRegister(cs ...prometheus.Collector) {
+	registry.MustRegister(cs...)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// inBandError is implemented by every maps response carrying an Error.
+type inBandError interface {
+	GetError() *mapper.Error
+}
+
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		method := path.Base(info.FullMethod)
+		code := status.Code(err)
+		if r, ok := resp.(inBandError); ok && err == nil && r.GetError() != nil {
+			// an in-band failure counts under the code it stands for
+			code = rpcerr.InBandCode(r.GetError().Code)
+		}
+		RPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		RPCRequests.WithLabelValues(method, code.String()).Inc()
+
+		if code != codes.OK {
+			Errors.WithLabelValues(method, code.String()).Inc()
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		resp   any
+		err    error
+		code   string
+		errors string
+	}{
+		{
+			name:   "ok",
+			method: "RegisterGateway",
+			resp:   &mapper.GatewayResponse{},
+			code:   "OK",
+		},
+		{
+			name:   "status error",
+			method: "RegisterAgent",
+			err:    rpcerr.Unauthenticated("bad signature"),
+			code:   "Unauthenticated",
+			errors: "Unauthenticated",
+		},
+		{
+			name:   "in-band error",
+			method: "ResolveGatewayForProxy",
+			resp:   &mapper.AgentResponse{Error: &mapper.Error{Code: mapper.ErrorCode_ERROR_CODE_NOT_FOUND}},
+			code:   "NotFound",
+			errors: "NotFound",
+		},
+	}
+	interceptor := UnaryServerInterceptor()
+	for _, tt := range tests {
+		info := &grpc.UnaryServerInfo{FullMethod: "/maps.Maps/" + tt.method}
+		requests := testutil.ToFloat64(RPCRequests.WithLabelValues(tt.method, tt.code))
+		var failed float64
+		if tt.errors != "" {
+			failed = testutil.ToFloat64(Errors.WithLabelValues(tt.method, tt.errors))
+		}
+
+		interceptor(context.Background(), nil, info, func(context.Context, any) (any, error) {
+			return tt.resp, tt.err
+		})
+
+		if got := testutil.ToFloat64(RPCRequests.WithLabelValues(tt.method, tt.code)); got != requests+1 {
+			t.Errorf("%s: requests_total{%s} = %v, want %v", tt.name, tt.code, got, requests+1)
+		}
+		if tt.errors != "" {
+			if got := testutil.ToFloat64(Errors.WithLabelValues(tt.method, tt.errors)); got != failed+1 {
+				t.Errorf("%s: errors_total{%s} = %v, want %v", tt.name, tt.errors, got, failed+1)
+			}
+		}
+	}
+}
+
+func TestStoreCollector(t *testing.T) {
+	mem := memstore.NewMemStore(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, id := range []string{"g1", "g2"} {
+		_, _, err := mem.AddGateway(context.Background(), "eu", &memstore.GatewayData{GatewayID: id, GatewayIP: "10.0.0.1", GatewayPort: 7000})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := `
+# HELP seeder_store_gateways Gateways per region.
+# TYPE seeder_store_gateways gauge
+seeder_store_gateways{region="eu"} 2
+# HELP seeder_store_rank_tree_items Items in the gateway rank tree per region.
+# TYPE seeder_store_rank_tree_items gauge
+seeder_store_rank_tree_items{region="eu"} 2
+`
+	if err := testutil.CollectAndCompare(NewStoreCollector(mem), strings.NewReader(want), "seeder_store_gateways", "seeder_store_rank_tree_items"); err != nil {
+		t.Error(err)
+	}
+}
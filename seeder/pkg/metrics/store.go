@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	gatewaysDesc = prometheus.NewDesc(
+		namespace+"_store_gateways", "Gateways per region.", []string{"region"}, nil,
+	)
+	agentsDesc = prometheus.NewDesc(
+		namespace+"_store_agents", "Agents per region.", []string{"region"}, nil,
+	)
+	rankedDesc = prometheus.NewDesc(
+		namespace+"_store_rank_tree_items", "Items in the gateway rank tree per region.", []string{"region"}, nil,
+	)
+)
+
+// StoreCollector reads region sizes from the store at scrape time, so the
+// hot path carries no bookkeeping for them.
+type StoreCollector struct {
+	store *memstore.MemStore
+}
+
+func NewStoreCollector(store *memstore.MemStore) *StoreCollector {
+	return &StoreCollector{store: store}
+}
+
+func (c *StoreCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- gatewaysDesc
+	ch <- agentsDesc
+	ch <- rankedDesc
+}
+
+func (c *StoreCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, r := range c.store.Stats() {
+		ch <- prometheus.MustNewConstMetric(gatewaysDesc, prometheus.GaugeValue, float64(r.Gateways), r.Name)
+		ch <- prometheus.MustNewConstMetric(agentsDesc, prometheus.GaugeValue, float64(r.Agents), r.Name)
+		ch <- prometheus.MustNewConstMetric(rankedDesc, prometheus.GaugeValue, float64(r.Ranked), r.Name)
+	}
+}
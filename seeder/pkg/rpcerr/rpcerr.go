@@ -0,0 +1,177 @@
+package rpcerr
+
+import (
+	"errors"
+	"time"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Error is the typed error every maps handler returns. It converts to a
+// gRPC status with details, or to the legacy in-band mapper.Error.
+type Error struct {
+	Code    codes.Code
+	Message string
+	Details []protoadapt.MessageV1
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.Code, e.Message)
+	if len(e.Details) == 0 {
+		return st
+	}
+	detailed, err := st.WithDetails(e.Details...)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+// Field is a single request field violation.
+type Field struct {
+	Name        string
+	Description string
+}
+
+func InvalidArgument(message string, fields ...Field) *Error {
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(fields))
+	for _, f := range fields {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       f.Name,
+			Description: f.Description,
+		})
+	}
+
+	e := &Error{Code: codes.InvalidArgument, Message: message}
+	if len(violations) > 0 {
+		e.Details = append(e.Details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+	return e
+}
+
+func NotFound(resourceType, name, message string) *Error {
+	return &Error{
+		Code:    codes.NotFound,
+		Message: message,
+		Details: []protoadapt.MessageV1{&errdetails.ResourceInfo{
+			ResourceType: resourceType,
+			ResourceName: name,
+			Description:  message,
+		}},
+	}
+}
+
+func AlreadyExists(resourceType, name, message string) *Error {
+	return &Error{
+		Code:    codes.AlreadyExists,
+		Message: message,
+		Details: []protoadapt.MessageV1{&errdetails.ResourceInfo{
+			ResourceType: resourceType,
+			ResourceName: name,
+			Description:  message,
+		}},
+	}
+}
+
+func FailedPrecondition(kind, subject, message string) *Error {
+	return &Error{
+		Code:    codes.FailedPrecondition,
+		Message: message,
+		Details: []protoadapt.MessageV1{&errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{{
+				Type:        kind,
+				Subject:     subject,
+				Description: message,
+			}},
+		}},
+	}
+}
+
+func Unavailable(message string) *Error {
+	return &Error{Code: codes.Unavailable, Message: message}
+}
+
+func Unauthenticated(message string) *Error {
+	return &Error{Code: codes.Unauthenticated, Message: message}
+}
+
+func PermissionDenied(message string) *Error {
+	return &Error{Code: codes.PermissionDenied, Message: message}
+}
+
+// Exhausted carries a RetryInfo hint when retryAfter is positive.
+func Exhausted(message string, retryAfter time.Duration) *Error {
+	e := &Error{Code: codes.ResourceExhausted, Message: message}
+	if retryAfter > 0 {
+		e.Details = append(e.Details, &errdetails.RetryInfo{
+			RetryDelay: durationpb.New(retryAfter),
+		})
+	}
+	return e
+}
+
+func Internal(message string) *Error {
+	return &Error{Code: codes.Internal, Message: message}
+}
+
+// From returns err as a catalogue error, treating unknown errors as internal.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal(err.Error())
+}
+
+// legacyCodes keeps the in-band codes older clients already branch on: a
+// missing gateway or agent is NOT_FOUND, every other failure they knew,
+// validation, store and WAL errors alike, INVALID_ARGUMENT.
+var legacyCodes = map[codes.Code]mapper.ErrorCode{
+	codes.NotFound:           mapper.ErrorCode_ERROR_CODE_NOT_FOUND,
+	codes.InvalidArgument:    mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT,
+	codes.FailedPrecondition: mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT,
+	codes.AlreadyExists:      mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT,
+	codes.Unavailable:        mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT,
+	codes.Internal:           mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT,
+	codes.Unauthenticated:    mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT,
+	codes.PermissionDenied:   mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT,
+}
+
+// Legacy converts err to the in-band error. ok is false for errors that have
+// no in-band equivalent and must always travel as a status.
+func Legacy(err error) (*mapper.Error, bool) {
+	e := From(err)
+	code, ok := legacyCodes[e.Code]
+	if !ok {
+		return nil, false
+	}
+	return &mapper.Error{Code: code, Message: e.Message}, true
+}
+
+// inBandCodes is the gRPC code an in-band error stands for.
+var inBandCodes = map[mapper.ErrorCode]codes.Code{
+	mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT: codes.InvalidArgument,
+	mapper.ErrorCode_ERROR_CODE_NOT_FOUND:        codes.NotFound,
+	mapper.ErrorCode_ERROR_CODE_ALREADY_EXISTS:   codes.AlreadyExists,
+	mapper.ErrorCode_ERROR_CODE_UNAVAILABLE:      codes.Unavailable,
+	mapper.ErrorCode_ERROR_CODE_INTERNAL:         codes.Internal,
+	mapper.ErrorCode_ERROR_CODE_UNAUTHORIZED:     codes.Unauthenticated,
+}
+
+// InBandCode returns the gRPC code of an in-band error, unknown codes read
+// as codes.Unknown.
+func InBandCode(code mapper.ErrorCode) codes.Code {
+	if c, ok := inBandCodes[code]; ok {
+		return c
+	}
+	return codes.Unknown
+}
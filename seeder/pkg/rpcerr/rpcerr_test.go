@@ -0,0 +1,116 @@
+package rpcerr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mapper "github.com/odio4u/agni-schema/maps"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFrom(t *testing.T) {
+	notFound := NotFound("agent", "api.example.com", "agent not found")
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{"typed", notFound, codes.NotFound, "agent not found"},
+		{"wrapped", fmt.Errorf("resolve: %w", notFound), codes.NotFound, "agent not found"},
+		{"unavailable", Unavailable("wal down"), codes.Unavailable, "wal down"},
+		{"exhausted", Exhausted("slow down", time.Second), codes.ResourceExhausted, "slow down"},
+		{"unknown", errors.New("disk on fire"), codes.Internal, "disk on fire"},
+		{"wrapped unknown", fmt.Errorf("append: %w", errors.New("disk on fire")), codes.Internal, "append: disk on fire"},
+	}
+	for _, tt := range tests {
+		e := From(tt.err)
+		if e.Code != tt.code || e.Message != tt.message {
+			t.Errorf("%s: From() = %v %q, want %v %q", tt.name, e.Code, e.Message, tt.code, tt.message)
+		}
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	tests := []struct {
+		err  error
+		want mapper.ErrorCode
+		ok   bool
+	}{
+		// older clients only tell not found (2) from every other failure (1)
+		{NotFound("agent", "a.example.com", "agent not found"), mapper.ErrorCode_ERROR_CODE_NOT_FOUND, true},
+		{InvalidArgument("bad request"), mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT, true},
+		{FailedPrecondition("GATEWAY_REGISTERED", "gateway", "no such gateway"), mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT, true},
+		{AlreadyExists("agent", "a.example.com", "agent exists"), mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT, true},
+		{Unavailable("wal down"), mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT, true},
+		{Internal("bug"), mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT, true},
+		{errors.New("bug"), mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT, true},
+		{Unauthenticated("bad signature"), mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT, true},
+		{PermissionDenied("not allowed"), mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT, true},
+		// rate limits have no in-band code
+		{Exhausted("slow down", time.Second), 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := Legacy(tt.err)
+		if ok != tt.ok {
+			t.Errorf("Legacy(%v) ok = %v, want %v", tt.err, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if got.Code != tt.want || got.Message != tt.err.Error() {
+			t.Errorf("Legacy(%v) = %v %q, want %v", tt.err, got.Code, got.Message, tt.want)
+		}
+	}
+}
+
+func TestGRPCStatusDetails(t *testing.T) {
+	st := status.Convert(InvalidArgument("invalid agent registration request",
+		Field{Name: "agent_domain", Description: "agent_domain is required"},
+		Field{Name: "region", Description: "region is required"},
+	))
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("code = %v", st.Code())
+	}
+	bad, ok := st.Details()[0].(*errdetails.BadRequest)
+	if !ok || len(bad.FieldViolations) != 2 || bad.FieldViolations[1].Field != "region" {
+		t.Errorf("details = %v, want both field violations", st.Details())
+	}
+
+	st = status.Convert(Exhausted("slow down", 2*time.Second))
+	retry, ok := st.Details()[0].(*errdetails.RetryInfo)
+	if !ok || retry.RetryDelay.AsDuration() != 2*time.Second {
+		t.Errorf("details = %v, want a 2s RetryInfo", st.Details())
+	}
+	if st := status.Convert(Exhausted("slow down", 0)); len(st.Details()) != 0 {
+		t.Errorf("details = %v, want no RetryInfo without a delay", st.Details())
+	}
+
+	st = status.Convert(NotFound("agent", "a.example.com", "agent not found"))
+	info, ok := st.Details()[0].(*errdetails.ResourceInfo)
+	if !ok || info.ResourceType != "agent" || info.ResourceName != "a.example.com" {
+		t.Errorf("details = %v, want the agent's ResourceInfo", st.Details())
+	}
+}
+
+func TestInBandCode(t *testing.T) {
+	tests := []struct {
+		code mapper.ErrorCode
+		want codes.Code
+	}{
+		{mapper.ErrorCode_ERROR_CODE_INVALID_ARGUMENT, codes.InvalidArgument},
+		{mapper.ErrorCode_ERROR_CODE_NOT_FOUND, codes.NotFound},
+		{mapper.ErrorCode_ERROR_CODE_UNAUTHORIZED, codes.Unauthenticated},
+		{mapper.ErrorCode_ERROR_CODE_UNSPECIFIED, codes.Unknown},
+	}
+	for _, tt := range tests {
+		if got := InBandCode(tt.code); got != tt.want {
+			t.Errorf("InBandCode(%v) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
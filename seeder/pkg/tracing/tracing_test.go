@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/odio4u/memstore/seeder/pkg/rpcerr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return recorder
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	recorder := newRecorder(t)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceparent))
+	info := &grpc.UnaryServerInfo{FullMethod: "/maps.Maps/RegisterAgent"}
+
+	_, err := UnaryServerInterceptor()(ctx, nil, info, func(ctx context.Context, _ any) (any, error) {
+		_, span := Child(ctx, "memstore.AddAgent")
+		End(span, nil)
+		return nil, rpcerr.Unauthenticated("bad signature")
+	})
+	if err == nil {
+		t.Fatal("interceptor swallowed the handler error")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want the store and server spans", len(spans))
+	}
+	child, server := spans[0], spans[1]
+	if server.Name() != "maps.Maps/RegisterAgent" {
+		t.Errorf("server span = %q", server.Name())
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span trace = %s, want the caller's", got)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("server span parent = %s, want the caller's span", got)
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("server span status = %v, want Error", server.Status())
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("store span is not a child of the server span")
+	}
+}
+
+func TestChildOutsideRequest(t *testing.T) {
+	recorder := newRecorder(t)
+
+	// replay runs without a request span and must not start traces
+	_, span := Child(context.Background(), "wal.Append")
+	End(span, nil)
+	if n := len(recorder.Ended()); n != 0 {
+		t.Errorf("recorded %d spans outside a request", n)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	if _, err := Setup(context.Background(), Config{Exporter: "zipkin"}, "seed-1"); err == nil {
+		t.Error("Setup() took an unknown exporter")
+	}
+	if _, err := Setup(context.Background(), Config{Exporter: "file"}, "seed-1"); err == nil {
+		t.Error("Setup() took the file exporter without a file")
+	}
+	shutdown, err := Setup(context.Background(), Config{}, "seed-1")
+	if err != nil || shutdown(context.Background()) != nil {
+		t.Errorf("Setup() without an exporter error = %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writeFileSync replaces path with data through a temporary file, fsyncing
+// the file before the rename and the directory after it, so a crash leaves
+// either the old or the new content on disk.
+func writeFileSync(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return renameSync(tmp, path)
+}
+
+// renameSync renames an fsynced file into place and fsyncs the directory,
+// the rename itself is only durable once the directory is.
+func renameSync(from, to string) error {
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+	return syncDir(to)
+}
+
+// syncDir fsyncs the directory of path, making a rename into it durable.
+func syncDir(path string) error {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
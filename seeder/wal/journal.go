@@ -0,0 +1,88 @@
+package wal
+
+import (
+	"context"
+
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+)
+
+// Journal persists the changes of a store to w. The store appends with the
+// region lock held, so the WAL holds them in the order they were applied.
+func (w *WALer) Journal() memstore.Journal {
+	return journal{w}
+}
+
+type journal struct {
+	w *WALer
+}
+
+func (j journal) PutGateway(ctx context.Context, gateway memstore.GatewayData) error {
+	return j.w.Append(ctx, putGatewayRecord(gateway))
+}
+
+func (j journal) DeleteGateway(ctx context.Context, region, gatewayID string) error {
+	return j.w.Append(ctx, &walpb.WalRecord{
+		Op: walpb.Operation_OP_DELETE_GATEWAY,
+		GatewayRef: &walpb.GatewayRef{
+			Region:    region,
+			GatewayId: gatewayID,
+		},
+	})
+}
+
+func (j journal) SetGatewayState(ctx context.Context, region, gatewayID string, state memstore.GatewayState) error {
+	return j.w.Append(ctx, setStateRecord(region, gatewayID, state))
+}
+
+func (j journal) PutAgent(ctx context.Context, agent memstore.AgentData, gateway memstore.GatewayData) error {
+	return j.w.Append(ctx, putAgentRecord(agent, gateway.GatewayAddress))
+}
+
+func putGatewayRecord(g memstore.GatewayData) *walpb.WalRecord {
+	return &walpb.WalRecord{
+		Op: walpb.Operation_OP_PUT_GATEWAY,
+		Gateway: &walpb.GatewayPutRequest{
+			Region:             g.Region,
+			GatewayIp:          g.GatewayIP,
+			GatewayId:          g.GatewayID,
+			GatewayPort:        g.GatewayPort,
+			GatewayAddress:     g.GatewayAddress,
+			WssPort:            g.Wssport,
+			VerifiableCredHash: g.VerifiableHash,
+			Subject:            g.Subject,
+			Capacity: &walpb.Capacity{
+				Cpu:       g.Capacity.CPU,
+				Memory:    g.Capacity.Memory,
+				Storage:   g.Capacity.Storage,
+				Bandwidth: g.Capacity.Bandwidth,
+			},
+		},
+	}
+}
+
+func setStateRecord(region, gatewayID string, state memstore.GatewayState) *walpb.WalRecord {
+	return &walpb.WalRecord{
+		Op: walpb.Operation_OP_SET_GATEWAY_STATE,
+		GatewayRef: &walpb.GatewayRef{
+			Region:    region,
+			GatewayId: gatewayID,
+			State:     GatewayStateRecord(state),
+		},
+	}
+}
+
+func putAgentRecord(a memstore.AgentData, gatewayAddress string) *walpb.WalRecord {
+	return &walpb.WalRecord{
+		Op: walpb.Operation_OP_PUT_AGENT,
+		Agent: &walpb.AgentConnectionRequest{
+			VerifiableCredHash: a.VerifiableHash,
+			AgentDomain:        a.AgentDomain,
+			GatewayId:          a.GatewayID,
+			Region:             a.Region,
+			GatewayAddress:     gatewayAddress,
+			AgentId:            a.AgentID,
+			Subject:            a.Subject,
+		},
+	}
+}
@@ -0,0 +1,238 @@
+package wal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	keyringFile = "wal.keys"
+	keySize     = 32
+)
+
+var ErrUnknownKey = errors.New("wal data key not found in keyring")
+
+// Keyring holds the data keys used to encrypt WAL records and snapshots.
+// Data keys are persisted wrapped by the key-encryption key from a local
+// keyfile, so the keyring file alone is useless without it.
+type Keyring struct {
+	path   string
+	kek    []byte
+	active uint32
+	keys   map[uint32][]byte
+}
+
+type keyringDisk struct {
+	Active uint32           `json:"active"`
+	Keys   []wrappedDataKey `json:"keys"`
+}
+
+type wrappedDataKey struct {
+	ID      uint32 `json:"id"`
+	Wrapped string `json:"wrapped"`
+}
+
+// GenerateKeyFile writes a new random key-encryption key to path.
+func GenerateKeyFile(path string) error {
+	kek := make([]byte, keySize)
+	if _, err := rand.Read(kek); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(kek)+"\n"), 0600)
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	if len(kek) != keySize {
+		return nil, fmt.Errorf("invalid key file %s: key must be %d bytes", path, keySize)
+	}
+	return kek, nil
+}
+
+// LoadKeyring unwraps the keyring next to the WAL in dir with the key in
+// keyFile. A fresh keyring with one data key is created when none exists yet.
+func LoadKeyring(keyFile, dir string) (*Keyring, error) {
+	return LoadKeyringPath(keyFile, filepath.Join(dir, keyringFile))
+}
+
+func LoadKeyringPath(keyFile, path string) (*Keyring, error) {
+	kek, err := readKeyFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	kr := &Keyring{
+		path: path,
+		kek:  kek,
+		keys: make(map[uint32][]byte),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if _, err := kr.Rotate(); err != nil {
+			return nil, err
+		}
+		return kr, kr.Save()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var disk keyringDisk
+	if err := json.Unmarshal(data, &disk); err != nil {
+		return nil, fmt.Errorf("invalid keyring %s: %w", path, err)
+	}
+
+	for _, k := range disk.Keys {
+		wrapped, err := base64.StdEncoding.DecodeString(k.Wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keyring %s: %w", path, err)
+		}
+		dek, err := openGCM(kek, wrapped, dataKeyAAD(k.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key %d, wrong key file?: %w", k.ID, err)
+		}
+		kr.keys[k.ID] = dek
+	}
+	if _, ok := kr.keys[disk.Active]; !ok {
+		return nil, fmt.Errorf("%w: active key %d", ErrUnknownKey, disk.Active)
+	}
+	kr.active = disk.Active
+	return kr, nil
+}
+
+// Rotate adds a new data key and makes it the active one.
+func (kr *Keyring) Rotate() (uint32, error) {
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return 0, err
+	}
+
+	var next uint32
+	for id := range kr.keys {
+		next = max(next, id)
+	}
+	next++
+
+	kr.keys[next] = dek
+	kr.active = next
+	return next, nil
+}
+
+// Rewrap replaces the key-encryption key with the one in keyFile. Call Save
+// to persist.
+func (kr *Keyring) Rewrap(keyFile string) error {
+	kek, err := readKeyFile(keyFile)
+	if err != nil {
+		return err
+	}
+	kr.kek = kek
+	return nil
+}
+
+// Retain drops every data key but the active one. Only safe once nothing
+// on disk is encrypted under the older keys.
+func (kr *Keyring) Retain() {
+	for id := range kr.keys {
+		if id != kr.active {
+			delete(kr.keys, id)
+		}
+	}
+}
+
+func (kr *Keyring) Save() error {
+	disk := keyringDisk{Active: kr.active}
+	for id, dek := range kr.keys {
+		wrapped, err := sealGCM(kr.kek, dek, dataKeyAAD(id))
+		if err != nil {
+			return err
+		}
+		disk.Keys = append(disk.Keys, wrappedDataKey{
+			ID:      id,
+			Wrapped: base64.StdEncoding.EncodeToString(wrapped),
+		})
+	}
+
+	data, err := json.MarshalIndent(disk, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileSync(kr.path, data, 0600)
+}
+
+func (kr *Keyring) ActiveID() uint32 {
+	return kr.active
+}
+
+// Seal encrypts plaintext under the active data key.
+func (kr *Keyring) Seal(plaintext, aad []byte) ([]byte, error) {
+	return sealGCM(kr.keys[kr.active], plaintext, aad)
+}
+
+// SealedSize is the ciphertext size Seal produces for n plaintext bytes.
+func SealedSize(n int) int {
+	return n + 12 + 16
+}
+
+func (kr *Keyring) Open(id uint32, sealed, aad []byte) ([]byte, error) {
+	dek, ok := kr.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKey, id)
+	}
+	return openGCM(dek, sealed, aad)
+}
+
+func dataKeyAAD(id uint32) []byte {
+	return []byte("agni-wal-dek-" + strconv.FormatUint(uint64(id), 10))
+}
+
+// sealGCM returns nonce || ciphertext.
+func sealGCM(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func openGCM(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: sealed payload too short", ErrCorrupt)
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,132 @@
+package wal
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+)
+
+func newKeyFile(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := GenerateKeyFile(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestKeyring(t *testing.T, dir string) *Keyring {
+	t.Helper()
+	kr, err := LoadKeyring(newKeyFile(t, "wal.kek"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kr
+}
+
+func TestSealOpen(t *testing.T) {
+	kr := newTestKeyring(t, t.TempDir())
+	aad := []byte("header")
+
+	sealed, err := kr.Seal([]byte("record"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sealed) != SealedSize(len("record")) {
+		t.Errorf("sealed %d bytes, SealedSize says %d", len(sealed), SealedSize(len("record")))
+	}
+
+	got, err := kr.Open(kr.ActiveID(), sealed, aad)
+	if err != nil || string(got) != "record" {
+		t.Fatalf("Open() = %q, %v", got, err)
+	}
+	if _, err := kr.Open(kr.ActiveID(), sealed, []byte("other header")); err == nil {
+		t.Error("Open() with another header succeeded")
+	}
+	if _, err := kr.Open(kr.ActiveID()+1, sealed, aad); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open() with an unknown key error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := newKeyFile(t, "wal.kek")
+
+	kr, err := LoadKeyring(keyFile, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := kr.Seal([]byte("record"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := LoadKeyring(keyFile, dir)
+	if err != nil {
+		t.Fatalf("reload error = %v", err)
+	}
+	if got, err := again.Open(kr.ActiveID(), sealed, nil); err != nil || string(got) != "record" {
+		t.Errorf("Open() after reload = %q, %v", got, err)
+	}
+
+	if _, err := LoadKeyring(newKeyFile(t, "other.kek"), dir); err == nil {
+		t.Error("LoadKeyring() with another key file succeeded")
+	}
+}
+
+func TestRekey(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, newKey := newKeyFile(t, "old.kek"), newKeyFile(t, "new.kek")
+
+	kr, err := LoadKeyring(oldKey, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldID := kr.ActiveID()
+	walRecs := []*walpb.WalRecord{gatewayRecord("g1"), gatewayRecord("g2")}
+	appendAll(t, dir, kr, walRecs...)
+	_, err = WriteSnapshot(SnapshotFile(dir), kr, func(emit func(*walpb.WalRecord) error) error {
+		return emit(gatewayRecord("g0"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Rekey(dir, oldKey, newKey); err != nil {
+		t.Fatalf("Rekey() error = %v", err)
+	}
+
+	if _, err := LoadKeyring(oldKey, dir); err == nil {
+		t.Error("the old key file still opens the keyring")
+	}
+	kr, err = LoadKeyring(newKey, dir)
+	if err != nil {
+		t.Fatalf("LoadKeyring() with the new key file error = %v", err)
+	}
+	if kr.ActiveID() == oldID {
+		t.Error("Rekey() kept the old data key active")
+	}
+	if _, err := kr.Open(oldID, nil, nil); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("the old data key is still in the keyring: %v", err)
+	}
+
+	for path, want := range map[string][]*walpb.WalRecord{
+		File(dir):         walRecs,
+		SnapshotFile(dir): {gatewayRecord("g0")},
+	} {
+		var got []*walpb.WalRecord
+		_, err := Scan(path, kr, func(e Entry) error {
+			if e.KeyID != kr.ActiveID() {
+				t.Errorf("%s: record at %d sealed with key %d, want %d", path, e.Offset, e.KeyID, kr.ActiveID())
+			}
+			got = append(got, e.Record)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: Scan() error = %v", path, err)
+		}
+		checkRecords(t, got, want...)
+	}
+}
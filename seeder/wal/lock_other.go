@@ -0,0 +1,10 @@
+//go:build !unix
+
+package wal
+
+import "os"
+
+// flock is a no-op where flock(2) is missing, the data dir is unguarded.
+func flock(f *os.File) error {
+	return nil
+}
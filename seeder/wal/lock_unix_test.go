@@ -0,0 +1,26 @@
+//go:build unix
+
+package wal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRekeyRefusesLockedDir(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := newKeyFile(t, "wal.kek")
+	if _, err := LoadKeyring(keyFile, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := LockDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Close()
+
+	if err := Rekey(dir, keyFile, ""); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Rekey() while locked error = %v, want %v", err, ErrLocked)
+	}
+}
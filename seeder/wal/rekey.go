@@ -0,0 +1,95 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+)
+
+// Rekey rewrites the WAL and the snapshot in dir under a freshly generated
+// data key. When newKeyFile is set the keyring is also rewrapped under that
+// key-encryption key, which must be used from then on. A plaintext WAL is
+// encrypted by the rewrite. It refuses while a seeder holds dir.
+//
+// Until the last Save the keyring stays wrapped by the old key-encryption
+// key, so a crash at any point leaves a data dir keyFile opens; after it,
+// only newKeyFile does.
+func Rekey(dir, keyFile, newKeyFile string) error {
+	lock, err := LockDir(dir)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	kr, err := LoadKeyring(keyFile, dir)
+	if err != nil {
+		return err
+	}
+	if newKeyFile != "" {
+		// read it now rather than fail after the rewrite
+		if _, err := readKeyFile(newKeyFile); err != nil {
+			return err
+		}
+	}
+
+	// persist the new data key next to the old ones first, so the files
+	// rewritten below can be opened whenever the rekey stops
+	if _, err := kr.Rotate(); err != nil {
+		return err
+	}
+	if err := kr.Save(); err != nil {
+		return err
+	}
+
+	for _, path := range []string{File(dir), SnapshotFile(dir)} {
+		if err := rewrite(path, kr); err != nil {
+			return err
+		}
+	}
+
+	kr.Retain()
+	if newKeyFile != "" {
+		if err := kr.Rewrap(newKeyFile); err != nil {
+			return err
+		}
+	}
+	return kr.Save()
+}
+
+// rewrite re-encrypts a WAL formatted file under the active data key. A
+// missing file is left alone.
+func rewrite(path string, kr *Keyring) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	tmp := path + ".rekey"
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	dst, err := OpenWALPath(tmp, kr, slog.Default())
+	if err != nil {
+		return err
+	}
+
+	_, _, err = replayFile(path, kr, slog.Default(), func(rec *walpb.WalRecord) error {
+		return dst.Append(context.Background(), rec)
+	})
+	if err != nil {
+		dst.Close()
+		return err
+	}
+
+	if err := dst.Sync(context.Background()); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return renameSync(tmp, path)
+}
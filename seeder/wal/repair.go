@@ -0,0 +1,76 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+type RepairResult struct {
+	Records int
+	// Kept and Dropped are byte counts
+	Kept    int64
+	Dropped int64
+	// Backup is the copy of the damaged file, empty when nothing was cut
+	Backup string
+}
+
+// Repair cuts a damaged WAL formatted file after its last intact record and
+// keeps a copy of the original next to it. It refuses when not a single
+// record is readable, which points at a wrong key file rather than damage.
+func Repair(path string, keyring *Keyring) (RepairResult, error) {
+	var res RepairResult
+	good, err := Scan(path, keyring, func(Entry) error {
+		res.Records++
+		return nil
+	})
+	res.Kept = good
+	if err == nil {
+		return res, nil
+	}
+	if !errors.Is(err, ErrCorrupt) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return res, err
+	}
+	if res.Records == 0 {
+		return res, fmt.Errorf("no readable record in %s, check the key file: %w", path, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return res, err
+	}
+	res.Dropped = info.Size() - good
+
+	res.Backup = fmt.Sprintf("%s.corrupt-%d", path, time.Now().Unix())
+	if err := copyFile(path, res.Backup); err != nil {
+		return res, err
+	}
+	if err := os.Truncate(path, good); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
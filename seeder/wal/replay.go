@@ -2,42 +2,112 @@ package wal
 
 import (
 	"bufio"
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
+	"log/slog"
 	"os"
+	"time"
 
-	walpb "github.com/odio4u/agni-schema/wal"
 	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/metrics"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
 	"google.golang.org/protobuf/proto"
 )
 
+// ErrSkipped is a record replay leaves out because the store refused it,
+// such as an agent of a gateway deleted meanwhile. Replay logs and counts it
+// and goes on.
+var ErrSkipped = errors.New("wal record skipped")
+
 func (w *WALer) Replay(apply func(*walpb.WalRecord) error) error {
+	start := time.Now()
+	records, skipped, err := replayFile(w.path, w.keyring, w.logger, apply)
+
+	w.mu.Lock()
+	w.sinceSnapshot += uint64(records + skipped)
+	w.mu.Unlock()
+
+	metrics.WALReplayDuration.Set(time.Since(start).Seconds())
+	metrics.WALReplayRecords.Set(float64(records))
+	w.logger.Info("wal replayed", "records", records, "skipped", skipped, "duration", time.Since(start))
+	return err
+}
+
+// replayFile applies every record of a WAL formatted file and returns how
+// many were applied and how many skipped.
+func replayFile(path string, keyring *Keyring, logger *slog.Logger, apply func(*walpb.WalRecord) error) (records, skipped int, err error) {
+	_, err = Scan(path, keyring, func(e Entry) error {
+		err := apply(e.Record)
+		if errors.Is(err, ErrSkipped) {
+			skipped++
+			metrics.WALReplaySkipped.Inc()
+			logger.Warn("wal record skipped", "op", e.Record.Op, "offset", e.Offset, "reason", err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		records++
+		return nil
+	})
+	return records, skipped, err
+}
 
-	f, err := os.Open(w.path)
+// Entry is a record with where and how it is stored.
+type Entry struct {
+	Offset  int64
+	Version byte
+	// KeyID is the data key of a sealed record
+	KeyID  uint32
+	Size   uint32
+	Record *walpb.WalRecord
+}
+
+// Scan calls fn with every record of a WAL formatted file, in order. good
+// is the offset just past the last record read intact, where a damaged
+// file can be cut.
+func Scan(path string, keyring *Keyring, fn func(Entry) error) (good int64, err error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer f.Close()
 
 	r := bufio.NewReader(f)
 
 	for {
-		header := make([]byte, 8)
+		header := make([]byte, headerSize)
 		_, err := io.ReadFull(r, header)
 		if err == io.EOF {
-			return nil
+			return good, nil
 		}
 		if err != nil {
-			return err
+			return good, err
 		}
 
 		magic := binary.BigEndian.Uint16(header[0:])
 		if magic != Magic {
-			return fmt.Errorf("%w: invalid magic", ErrCorrupt)
+			return good, fmt.Errorf("%w: invalid magic", ErrCorrupt)
+		}
+
+		var keyID uint32
+		switch header[2] {
+		case versionPlain:
+		case versionSealed:
+			ext := make([]byte, sealedHeaderSize-headerSize)
+			if _, err := io.ReadFull(r, ext); err != nil {
+				return good, err
+			}
+			header = append(header, ext...)
+			keyID = binary.BigEndian.Uint32(ext)
+		default:
+			return good, fmt.Errorf("%w: unsupported record version %d", ErrCorrupt, header[2])
 		}
 
 		op := walpb.Operation(header[3])
@@ -45,30 +115,48 @@ func (w *WALer) Replay(apply func(*walpb.WalRecord) error) error {
 
 		payload := make([]byte, size)
 		if _, err := io.ReadFull(r, payload); err != nil {
-			return err
+			return good, err
 		}
 
 		crcBuf := make([]byte, 4)
 		if _, err := io.ReadFull(r, crcBuf); err != nil {
-			return err
+			return good, err
 		}
 
 		expectedCRC := binary.BigEndian.Uint32(crcBuf)
 		actualCRC := crc32.ChecksumIEEE(payload)
 		if expectedCRC != actualCRC {
-			return fmt.Errorf("%w: crc mismatch", ErrCorrupt)
+			return good, fmt.Errorf("%w: crc mismatch", ErrCorrupt)
+		}
+
+		if header[2] == versionSealed {
+			if keyring == nil {
+				return good, ErrNoKeyring
+			}
+			payload, err = keyring.Open(keyID, payload, header)
+			if err != nil {
+				return good, fmt.Errorf("%w: %v", ErrCorrupt, err)
+			}
 		}
 
 		rec := &walpb.WalRecord{
 			Op: op,
 		}
 		if err := proto.Unmarshal(payload, rec); err != nil {
-			return err
+			return good, fmt.Errorf("%w: %v", ErrCorrupt, err)
 		}
 
-		if err := apply(rec); err != nil {
-			return err
+		entry := Entry{
+			Offset:  good,
+			Version: header[2],
+			KeyID:   keyID,
+			Size:    size,
+			Record:  rec,
+		}
+		if err := fn(entry); err != nil {
+			return good, err
 		}
+		good += int64(len(header)) + int64(size) + int64(len(crcBuf))
 	}
 }
 
@@ -89,6 +177,7 @@ func ApplyRecord(store *memstore.MemStore, rec *walpb.WalRecord) error {
 			GatewayAddress: rec.Gateway.GatewayAddress,
 			VerifiableHash: rec.Gateway.VerifiableCredHash,
 			Wssport:        rec.Gateway.WssPort,
+			Subject:        rec.Gateway.Subject,
 			Capacity: memstore.Capacity{
 				CPU:       rec.Gateway.Capacity.Cpu,
 				Memory:    rec.Gateway.Capacity.Memory,
@@ -97,7 +186,7 @@ func ApplyRecord(store *memstore.MemStore, rec *walpb.WalRecord) error {
 			},
 		}
 
-		_, err := store.AddGateway(region, gatewayData)
+		_, _, err := store.AddGateway(context.Background(), region, gatewayData)
 		if err != nil {
 			return err
 		}
@@ -115,14 +204,67 @@ func ApplyRecord(store *memstore.MemStore, rec *walpb.WalRecord) error {
 			AgentDomain:    rec.Agent.AgentDomain,
 			GatewayAddress: rec.Agent.GatewayAddress,
 			GatewayID:      rec.Agent.GatewayId,
+			VerifiableHash: rec.Agent.VerifiableCredHash,
+			Subject:        rec.Agent.Subject,
 		}
 
-		_, _, err := store.AddAgent(region, agentData)
-		if err != nil {
-			return err
+		_, _, _, err := store.AddAgent(context.Background(), region, agentData, true)
+		var conflict *memstore.DomainConflict
+		if errors.As(err, &conflict) {
+			// a takeover accepted before ownership was checked, the
+			// owner's registration stands
+			return fmt.Errorf("%w: %v", ErrSkipped, err)
 		}
-		return nil
+		if errors.Is(err, memstore.ErrGatewayNotFound) {
+			// registered while the gateway was deleted, from before both
+			// took the region lock for the whole check
+			return fmt.Errorf("%w: %v", ErrSkipped, err)
+		}
+		return err
+
+	case walpb.Operation_OP_DELETE_GATEWAY:
+		ref := rec.GatewayRef
+		_, err := store.DeleteGateway(context.Background(), ref.Region, ref.GatewayId, true)
+		if errors.Is(err, memstore.ErrGatewayNotFound) {
+			// deleted twice, or the delete raced a snapshot
+			return fmt.Errorf("%w: %v", ErrSkipped, err)
+		}
+		return err
+
+	case walpb.Operation_OP_SET_GATEWAY_STATE:
+		ref := rec.GatewayRef
+		_, err := store.SetGatewayState(context.Background(), ref.Region, ref.GatewayId, GatewayState(ref.State), true)
+		if errors.Is(err, memstore.ErrGatewayNotFound) {
+			// the state of a gateway deleted later in a snapshotted WAL
+			return fmt.Errorf("%w: %v", ErrSkipped, err)
+		}
+		return err
 	}
 
 	return fmt.Errorf("unknown op: %v", rec.Op)
 }
+
+var gatewayStates = map[walpb.GatewayState]memstore.GatewayState{
+	walpb.GatewayState_GATEWAY_STATE_ACTIVE:      memstore.GatewayActive,
+	walpb.GatewayState_GATEWAY_STATE_DRAINING:    memstore.GatewayDraining,
+	walpb.GatewayState_GATEWAY_STATE_MAINTENANCE: memstore.GatewayMaintenance,
+	walpb.GatewayState_GATEWAY_STATE_OFFLINE:     memstore.GatewayOffline,
+}
+
+// GatewayState converts a persisted state, unknown states read as active.
+func GatewayState(state walpb.GatewayState) memstore.GatewayState {
+	if s, ok := gatewayStates[state]; ok {
+		return s
+	}
+	return memstore.GatewayActive
+}
+
+// GatewayStateRecord converts a store state for persisting.
+func GatewayStateRecord(state memstore.GatewayState) walpb.GatewayState {
+	for record, s := range gatewayStates {
+		if s == state {
+			return record
+		}
+	}
+	return walpb.GatewayState_GATEWAY_STATE_ACTIVE
+}
@@ -0,0 +1,270 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	"github.com/odio4u/memstore/seeder/pkg/tracing"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+)
+
+// snapshotFile sits next to the WAL and uses the same record format, so it
+// is encrypted under the same keyring.
+const snapshotFile = "snapshot.log"
+
+type SnapshotInfo struct {
+	Path    string    `json:"path"`
+	Time    time.Time `json:"time"`
+	Size    int64     `json:"size_bytes"`
+	Records int       `json:"records"`
+}
+
+type Stats struct {
+	Path string `json:"path"`
+	Size int64  `json:"size_bytes"`
+	// Appended counts records appended since the process started
+	Appended uint64 `json:"appended_records"`
+	// SinceSnapshot counts the records the next snapshot folds in
+	SinceSnapshot uint64        `json:"records_since_snapshot"`
+	Encrypted     bool          `json:"encrypted"`
+	ActiveKey     uint32        `json:"active_key_id,omitempty"`
+	LastError     string        `json:"last_error,omitempty"`
+	Snapshot      *SnapshotInfo `json:"snapshot,omitempty"`
+}
+
+func snapshotPath(walPath string) string {
+	return filepath.Join(filepath.Dir(walPath), snapshotFile)
+}
+
+// SnapshotFile is the path of the snapshot in the data directory dir.
+func SnapshotFile(dir string) string {
+	return filepath.Join(dir, snapshotFile)
+}
+
+func (w *WALer) Stats() (Stats, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writer.Flush(); err != nil {
+		return Stats{}, err
+	}
+	info, err := w.f.Stat()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	st := Stats{
+		Path:          w.path,
+		Size:          info.Size(),
+		Appended:      w.appended,
+		SinceSnapshot: w.sinceSnapshot,
+		Encrypted:     w.keyring != nil,
+		Snapshot:      w.lastSnapshot,
+	}
+	if w.keyring != nil {
+		st.ActiveKey = w.keyring.ActiveID()
+	}
+	if w.lastErr != nil {
+		st.LastError = w.lastErr.Error()
+	}
+	return st, nil
+}
+
+// ReplaySnapshot applies the snapshot, if there is one. It must run before
+// Replay, the WAL only holds what happened after the snapshot was taken.
+func (w *WALer) ReplaySnapshot(apply func(*walpb.WalRecord) error) error {
+	path := snapshotPath(w.path)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	records, skipped, err := replayFile(path, w.keyring, w.logger, apply)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.lastSnapshot = &SnapshotInfo{
+		Path:    path,
+		Time:    info.ModTime().UTC(),
+		Size:    info.Size(),
+		Records: records,
+	}
+	w.mu.Unlock()
+
+	w.logger.Info("snapshot loaded", "records", records, "skipped", skipped, "duration", time.Since(start))
+	return nil
+}
+
+// Snapshot writes the records produced by dump to the snapshot file and then
+// cuts what it folded in off the WAL. The store appends with its region
+// locks held and dump takes them, so appends go on meanwhile and the WAL
+// keeps every record from the start of the dump on; one that also made it
+// into the dump is applied twice on replay, which puts and deletes tolerate.
+func (w *WALer) Snapshot(ctx context.Context, dump func(emit func(*walpb.WalRecord) error) error) (_ SnapshotInfo, err error) {
+	_, span := tracing.Child(ctx, "wal.Snapshot")
+	defer func() { tracing.End(span, err) }()
+
+	w.snapshotMu.Lock()
+	defer w.snapshotMu.Unlock()
+
+	w.mu.Lock()
+	if w.torn {
+		err = w.discard()
+	}
+	start, appended := w.size, w.appended
+	w.mu.Unlock()
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	info, err := WriteSnapshot(snapshotPath(w.path), w.keyring, dump)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	span.AddEvent("wal lock acquired")
+
+	// a crash before the cut replays the snapshot and then the whole old
+	// WAL, which converges on the same state
+	if err := w.cut(start); err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	w.lastSnapshot = &info
+	w.sinceSnapshot = w.appended - appended
+	w.logger.Info("snapshot written", "records", info.Records, "size", info.Size, "kept", w.sinceSnapshot)
+	return info, nil
+}
+
+// cut replaces the WAL with its records from offset on, written to a new
+// file that is renamed over it. The WAL lock must be held.
+func (w *WALer) cut(offset int64) error {
+	if w.torn {
+		if err := w.discard(); err != nil {
+			return err
+		}
+	}
+	tail := make([]byte, w.size-offset)
+	if _, err := w.f.ReadAt(tail, offset); err != nil {
+		return err
+	}
+
+	tmp := w.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_APPEND|os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(tail)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, w.path)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	w.f.Close()
+	w.f = f
+	w.writer.Reset(f)
+	w.size = int64(len(tail))
+	w.dirty = false
+	return syncDir(w.path)
+}
+
+// WriteSnapshot writes the records produced by dump to path through a
+// temporary file, so path holds either the old or the complete new snapshot.
+func WriteSnapshot(path string, keyring *Keyring, dump func(emit func(*walpb.WalRecord) error) error) (SnapshotInfo, error) {
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return SnapshotInfo{}, err
+	}
+
+	dst, err := OpenWALPath(tmp, keyring, nil)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	records := 0
+	err = dump(func(rec *walpb.WalRecord) error {
+		if _, err := dst.write(rec); err != nil {
+			return err
+		}
+		records++
+		return nil
+	})
+	if err == nil {
+		err = dst.f.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return SnapshotInfo{}, err
+	}
+	if err := renameSync(tmp, path); err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	return SnapshotInfo{
+		Path:    path,
+		Time:    info.ModTime().UTC(),
+		Size:    info.Size(),
+		Records: records,
+	}, nil
+}
+
+// Dump emits the records that rebuild store: every gateway, the state of
+// those that are not active, then every agent.
+func Dump(store *memstore.MemStore, emit func(*walpb.WalRecord) error) error {
+	gateways, agents := store.Export()
+
+	for _, g := range gateways {
+		if err := emit(putGatewayRecord(g)); err != nil {
+			return err
+		}
+		if g.State == memstore.GatewayActive {
+			continue
+		}
+		if err := emit(setStateRecord(g.Region, g.GatewayID, g.State)); err != nil {
+			return err
+		}
+	}
+
+	known := make(map[string]bool, len(gateways))
+	for _, g := range gateways {
+		known[g.Region+"\x00"+g.GatewayID] = true
+	}
+
+	for _, a := range agents {
+		// only a replayed forced delete leaves an agent without its
+		// gateway, and it would fail to apply
+		if !known[a.Region+"\x00"+a.GatewayID] {
+			continue
+		}
+		if err := emit(putAgentRecord(a, a.GatewayAddress)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package wal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	memstore "github.com/odio4u/memstore/seeder/pkg/memstore"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+)
+
+func newTestStore() *memstore.MemStore {
+	return memstore.NewMemStore(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// replayedGatewayID is the id ApplyRecord derives for a gateway.
+func replayedGatewayID(hash, ip string) string {
+	sum := sha256.Sum256([]byte(hash + "|" + ip))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	g1 := replayedGatewayID("h1", "10.0.0.1")
+	recs := []*walpb.WalRecord{
+		{Op: walpb.Operation_OP_PUT_GATEWAY, Gateway: &walpb.GatewayPutRequest{
+			Region: "eu", GatewayIp: "10.0.0.1", GatewayPort: 7000, VerifiableCredHash: "h1", Capacity: &walpb.Capacity{Cpu: 4},
+		}},
+		{Op: walpb.Operation_OP_PUT_GATEWAY, Gateway: &walpb.GatewayPutRequest{
+			Region: "us", GatewayIp: "10.0.0.2", GatewayPort: 7000, VerifiableCredHash: "h1", Capacity: &walpb.Capacity{Cpu: 2},
+		}},
+		{Op: walpb.Operation_OP_PUT_AGENT, Agent: &walpb.AgentConnectionRequest{
+			Region: "eu", AgentDomain: "a.example.com", GatewayId: g1, VerifiableCredHash: "h2",
+		}},
+		{Op: walpb.Operation_OP_SET_GATEWAY_STATE, GatewayRef: &walpb.GatewayRef{
+			Region: "eu", GatewayId: g1, State: walpb.GatewayState_GATEWAY_STATE_DRAINING,
+		}},
+	}
+	store := newTestStore()
+	for _, rec := range recs {
+		if err := ApplyRecord(store, rec); err != nil {
+			t.Fatalf("ApplyRecord(%v) error = %v", rec.Op, err)
+		}
+	}
+
+	dir := t.TempDir()
+	w, err := OpenWALPath(filepath.Join(dir, walFile), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, rec := range recs {
+		if err := w.Append(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := w.Snapshot(ctx, func(emit func(*walpb.WalRecord) error) error {
+		return Dump(store, emit)
+	})
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if info.Records != len(recs) {
+		t.Errorf("snapshot holds %d records, want %d", info.Records, len(recs))
+	}
+	st, err := w.Stats()
+	if err != nil || st.Size != 0 || st.Snapshot == nil {
+		t.Errorf("Stats() = %+v, %v, want an empty WAL and the snapshot", st, err)
+	}
+
+	// the snapshot alone rebuilds the store
+	restored := newTestStore()
+	if err := w.ReplaySnapshot(func(rec *walpb.WalRecord) error { return ApplyRecord(restored, rec) }); err != nil {
+		t.Fatalf("ReplaySnapshot() error = %v", err)
+	}
+	if err := w.Replay(func(rec *walpb.WalRecord) error { return ApplyRecord(restored, rec) }); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	wantGateways, wantAgents := store.Export()
+	gotGateways, gotAgents := restored.Export()
+	if !reflect.DeepEqual(gotGateways, wantGateways) {
+		t.Errorf("restored gateways = %+v, want %+v", gotGateways, wantGateways)
+	}
+	if !reflect.DeepEqual(gotAgents, wantAgents) {
+		t.Errorf("restored agents = %+v, want %+v", gotAgents, wantAgents)
+	}
+}
+
+func TestReplaySnapshotMissing(t *testing.T) {
+	w := &WALer{path: filepath.Join(t.TempDir(), walFile), logger: slog.Default()}
+	err := w.ReplaySnapshot(func(*walpb.WalRecord) error {
+		t.Fatal("applied a record without a snapshot")
+		return nil
+	})
+	if err != nil {
+		t.Errorf("ReplaySnapshot() error = %v", err)
+	}
+}
+
+func TestSnapshotKeepsAppends(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore()
+	w, err := OpenWALPath(filepath.Join(t.TempDir(), walFile), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	store.SetJournal(w.Journal())
+
+	if _, _, err := store.AddGateway(ctx, "eu", &memstore.GatewayData{GatewayID: "g1", GatewayIP: "10.0.0.1", GatewayPort: 7000}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a registration landing while the snapshot is dumped, which needs the
+	// region lock it holds while appending
+	_, err = w.Snapshot(ctx, func(emit func(*walpb.WalRecord) error) error {
+		done := make(chan error)
+		go func() {
+			_, _, err := store.AddGateway(ctx, "eu", &memstore.GatewayData{GatewayID: "g2", GatewayIP: "10.0.0.2", GatewayPort: 7000})
+			done <- err
+		}()
+		if err := <-done; err != nil {
+			return err
+		}
+		return Dump(store, emit)
+	})
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	got, err := replayAll(t, w.path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Gateway.GatewayId != "g2" {
+		t.Errorf("WAL after the snapshot = %v, want the put of g2", got)
+	}
+	if st, _ := w.Stats(); st.SinceSnapshot != 1 {
+		t.Errorf("SinceSnapshot = %d, want 1", st.SinceSnapshot)
+	}
+
+	// appends go on to the new file
+	if _, _, err := store.AddGateway(ctx, "eu", &memstore.GatewayData{GatewayID: "g3", GatewayIP: "10.0.0.3", GatewayPort: 7000}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := replayAll(t, w.path, nil); err != nil || len(got) != 2 {
+		t.Errorf("WAL holds %d records, %v, want 2", len(got), err)
+	}
+}
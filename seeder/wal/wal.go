@@ -2,43 +2,104 @@ package wal
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
 	"errors"
 	"hash/crc32"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
-	walpb "github.com/odio4u/agni-schema/wal"
+	"github.com/odio4u/memstore/seeder/pkg/metrics"
+	"github.com/odio4u/memstore/seeder/pkg/tracing"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/protobuf/proto"
 )
 
 const (
-	Magic   uint16 = 0xCAFE
-	version byte   = 1
+	Magic uint16 = 0xCAFE
+
+	// versionPlain records carry the protobuf payload as is.
+	versionPlain byte = 1
+	// versionSealed records carry a 4 byte data key id after the length and
+	// an AES-GCM sealed payload authenticated against the header.
+	versionSealed byte = 2
+
+	headerSize       = 8
+	sealedHeaderSize = 12
 
 	walFile = "wal.log"
+	// probeSize is written by the health probe of a WAL whose last append
+	// failed
+	probeSize = 64 * 1024
 	// walRotated  = "wal.log.1" not used yet
 	// maxWalBytes = 32 * 1024 * 1024 // 32MB
 )
 
-var ErrCorrupt = errors.New("wal corruption detected")
+var (
+	ErrCorrupt   = errors.New("wal corruption detected")
+	ErrNoKeyring = errors.New("wal record is encrypted but no key file is configured")
+)
 
 type WALer struct {
-	mu     sync.Mutex
-	f      *os.File
-	path   string
-	writer *bufio.Writer
+	mu      sync.Mutex
+	f       *os.File
+	path    string
+	writer  *bufio.Writer
+	keyring *Keyring
+	logger  *slog.Logger
+	// lastErr is the last append failure, cleared by the next good append
+	// or a good health probe
+	lastErr error
+	// size is the end of the last record written whole, a failed append is
+	// cut back to it. torn is set while that cut failed.
+	size         int64
+	torn         bool
+	appended     uint64
+	lastSnapshot *SnapshotInfo
+	// sinceSnapshot counts the records the next snapshot would fold in
+	sinceSnapshot uint64
+	// syncAppends fsyncs every append, dirty is set by writes not yet
+	// fsynced
+	syncAppends bool
+	dirty       bool
+	// snapshotMu runs one snapshot at a time, it is taken before mu
+	snapshotMu sync.Mutex
+}
+
+// File is the path of the WAL in the data directory dir.
+func File(dir string) string {
+	return filepath.Join(dir, walFile)
+}
+
+// OpenWAL opens the WAL in dir. Records are encrypted when keyring is not nil.
+func OpenWAL(dir string, keyring *Keyring, logger *slog.Logger) (*WALer, error) {
+	return OpenWALPath(File(dir), keyring, logger)
 }
 
-func OpenWAL() (*WALer, error) {
-	f, err := os.OpenFile(walFile, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+func OpenWALPath(path string, keyring *Keyring, logger *slog.Logger) (*WALer, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
 		return nil, err
 	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
 	return &WALer{
-		f:      f,
-		writer: bufio.NewWriter(f),
-		path:   walFile,
+		f:       f,
+		size:    info.Size(),
+		writer:  bufio.NewWriter(f),
+		path:    path,
+		keyring: keyring,
+		logger:  logger.With("component", "wal", "path", path),
 	}, nil
 }
 
@@ -51,36 +112,176 @@ func (w *WALer) Close() error {
 	return w.f.Close()
 }
 
-func (w *WALer) Append(rec *walpb.WalRecord) error {
+// SyncAppends makes every Append fsync before it returns. Otherwise the
+// records reach the disk on Sync or whenever the OS writes them back.
+func (w *WALer) SyncAppends(on bool) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	w.syncAppends = on
+}
 
-	data, err := proto.Marshal(rec)
+// Sync flushes buffered records and fsyncs the WAL file, if anything was
+// written since the last fsync.
+func (w *WALer) Sync(ctx context.Context) (err error) {
+	_, span := tracing.Child(ctx, "wal.Sync")
+	defer func() { tracing.End(span, err) }()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+	return w.fsync()
+}
+
+func (w *WALer) fsync() error {
+	if !w.dirty {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() {
+		metrics.WALFsyncDuration.Observe(time.Since(start).Seconds())
+	}()
+	if err := w.f.Sync(); err != nil {
+		return err
+	}
+	w.dirty = false
+	return nil
+}
+
+// Health returns the last append failure, such as ENOSPC on a full disk.
+// While there is one it probes the disk, and clears it once a write and
+// fsync go through again.
+func (w *WALer) Health() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.lastErr != nil && w.probe() == nil {
+		w.logger.Info("wal writable again", "after", w.lastErr)
+		w.lastErr = nil
+	}
+	return w.lastErr
+}
+
+// probe writes and fsyncs a scratch file next to the WAL.
+func (w *WALer) probe() error {
+	if w.torn {
+		if err := w.discard(); err != nil {
+			return err
+		}
+	}
+	f, err := os.CreateTemp(filepath.Dir(w.path), ".wal-probe-*")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.Write(make([]byte, probeSize)); err != nil {
+		return err
+	}
+	return f.Sync()
+}
 
-	crc := crc32.ChecksumIEEE(data)
+// discard cuts the WAL back to the last whole record and drops what the
+// writer still buffers, so a failed append leaves no partial record for the
+// next one to land behind. The bufio.Writer keeps its error otherwise.
+func (w *WALer) discard() error {
+	w.writer.Reset(w.f)
+	err := w.f.Truncate(w.size)
+	w.torn = err != nil
+	return err
+}
+
+func (w *WALer) Append(ctx context.Context, rec *walpb.WalRecord) error {
+	_, span := tracing.Child(ctx, "wal.Append", attribute.String("wal.op", rec.Op.String()))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	span.AddEvent("wal lock acquired")
+
+	start := time.Now()
+	var n int
+	var err error
+	if w.torn {
+		err = w.discard()
+	}
+	if err == nil {
+		n, err = w.write(rec)
+	}
+	if err == nil {
+		w.size += int64(n)
+		if w.syncAppends {
+			err = w.fsync()
+		}
+	} else if !w.torn {
+		if cut := w.discard(); cut != nil {
+			w.logger.Error("failed to cut a failed append off the wal", "err", cut, "offset", w.size)
+		}
+	}
+	metrics.WALAppendDuration.Observe(time.Since(start).Seconds())
+	metrics.WALBytesWritten.Add(float64(n))
+
+	span.SetAttributes(attribute.Int("wal.bytes", n))
+	tracing.End(span, err)
+
+	w.lastErr = err
+	if err == nil {
+		w.appended++
+		w.sinceSnapshot++
+	}
+	return err
+}
+
+// write frames and flushes one record, returning the bytes written.
+func (w *WALer) write(rec *walpb.WalRecord) (int, error) {
+	data, err := proto.Marshal(rec)
+	if err != nil {
+		return 0, err
+	}
 
-	header := make([]byte, 8)
+	var header []byte
+	if w.keyring == nil {
+		header = make([]byte, headerSize)
+		header[2] = versionPlain
+		binary.BigEndian.PutUint32(header[4:8], uint32(len(data)))
+	} else {
+		header = make([]byte, sealedHeaderSize)
+		header[2] = versionSealed
+		binary.BigEndian.PutUint32(header[4:8], uint32(SealedSize(len(data))))
+		binary.BigEndian.PutUint32(header[8:12], w.keyring.ActiveID())
+	}
 	binary.BigEndian.PutUint16(header[0:2], Magic)
-	header[2] = version
 	header[3] = byte(rec.Op)
-	binary.BigEndian.PutUint32(header[4:8], uint32(len(data)))
+
+	if w.keyring != nil {
+		// the whole header is authenticated, so a record cannot be moved
+		// to another op or key id without failing to open
+		data, err = w.keyring.Seal(data, header)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	crc := crc32.ChecksumIEEE(data)
 
 	if _, err := w.writer.Write(header); err != nil {
-		return err
+		return 0, err
 	}
 
 	if _, err := w.writer.Write(data); err != nil {
-		return err
+		return 0, err
 	}
 
 	crcBuf := make([]byte, 4)
 	binary.BigEndian.PutUint32(crcBuf, crc)
 	if _, err := w.writer.Write(crcBuf); err != nil {
-		return err
+		return 0, err
 	}
 
-	return w.writer.Flush()
+	n := len(header) + len(data) + len(crcBuf)
+	if err := w.writer.Flush(); err != nil {
+		return 0, err
+	}
+	w.dirty = true
+	return n, nil
 }
@@ -0,0 +1,270 @@
+package wal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/odio4u/memstore/seeder/pkg/metrics"
+	walpb "github.com/odio4u/memstore/seeder/wal/proto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/protobuf/proto"
+)
+
+func gatewayRecord(id string) *walpb.WalRecord {
+	return &walpb.WalRecord{
+		Op: walpb.Operation_OP_PUT_GATEWAY,
+		Gateway: &walpb.GatewayPutRequest{
+			Region:      "eu",
+			GatewayId:   id,
+			GatewayIp:   "10.0.0.1",
+			GatewayPort: 7000,
+		},
+	}
+}
+
+// appendAll writes recs to a new WAL in dir and closes it.
+func appendAll(t *testing.T, dir string, kr *Keyring, recs ...*walpb.WalRecord) string {
+	t.Helper()
+	w, err := OpenWAL(dir, kr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range recs {
+		if err := w.Append(context.Background(), rec); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return File(dir)
+}
+
+func replayAll(t *testing.T, path string, kr *Keyring) ([]*walpb.WalRecord, error) {
+	t.Helper()
+	var got []*walpb.WalRecord
+	_, _, err := replayFile(path, kr, slog.Default(), func(rec *walpb.WalRecord) error {
+		got = append(got, rec)
+		return nil
+	})
+	return got, err
+}
+
+func checkRecords(t *testing.T, got []*walpb.WalRecord, want ...*walpb.WalRecord) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("replayed %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !proto.Equal(got[i], want[i]) {
+			t.Errorf("record %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAppendReplay(t *testing.T) {
+	recs := []*walpb.WalRecord{gatewayRecord("g1"), gatewayRecord("g2"), {
+		Op:         walpb.Operation_OP_SET_GATEWAY_STATE,
+		GatewayRef: &walpb.GatewayRef{Region: "eu", GatewayId: "g1", State: walpb.GatewayState_GATEWAY_STATE_DRAINING},
+	}}
+
+	t.Run("plain", func(t *testing.T) {
+		path := appendAll(t, t.TempDir(), nil, recs...)
+		got, err := replayAll(t, path, nil)
+		if err != nil {
+			t.Fatalf("replay error = %v", err)
+		}
+		checkRecords(t, got, recs...)
+	})
+
+	t.Run("sealed", func(t *testing.T) {
+		dir := t.TempDir()
+		kr := newTestKeyring(t, dir)
+		path := appendAll(t, dir, kr, recs...)
+
+		got, err := replayAll(t, path, kr)
+		if err != nil {
+			t.Fatalf("replay error = %v", err)
+		}
+		checkRecords(t, got, recs...)
+
+		if _, err := replayAll(t, path, nil); !errors.Is(err, ErrNoKeyring) {
+			t.Errorf("replay without a keyring error = %v, want %v", err, ErrNoKeyring)
+		}
+	})
+}
+
+func TestReplaySkipped(t *testing.T) {
+	g1 := replayedGatewayID("h1", "10.0.0.1")
+	recs := []*walpb.WalRecord{
+		{Op: walpb.Operation_OP_PUT_GATEWAY, Gateway: &walpb.GatewayPutRequest{
+			Region: "eu", GatewayIp: "10.0.0.1", GatewayPort: 7000, VerifiableCredHash: "h1", Capacity: &walpb.Capacity{},
+		}},
+		// the gateway of the agent is unknown
+		{Op: walpb.Operation_OP_PUT_AGENT, Agent: &walpb.AgentConnectionRequest{
+			Region: "eu", AgentDomain: "b.example.com", GatewayId: "gone", VerifiableCredHash: "h2",
+		}},
+		{Op: walpb.Operation_OP_PUT_AGENT, Agent: &walpb.AgentConnectionRequest{
+			Region: "eu", AgentDomain: "a.example.com", GatewayId: g1, VerifiableCredHash: "h2",
+		}},
+		// a domain owned by another credential
+		{Op: walpb.Operation_OP_PUT_AGENT, Agent: &walpb.AgentConnectionRequest{
+			Region: "eu", AgentDomain: "a.example.com", GatewayId: g1, VerifiableCredHash: "h3",
+		}},
+		{Op: walpb.Operation_OP_DELETE_GATEWAY, GatewayRef: &walpb.GatewayRef{Region: "eu", GatewayId: "gone"}},
+	}
+	w, err := OpenWAL(t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, rec := range recs {
+		if err := w.Append(context.Background(), rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := newTestStore()
+	before := testutil.ToFloat64(metrics.WALReplaySkipped)
+	if err := w.Replay(func(rec *walpb.WalRecord) error { return ApplyRecord(store, rec) }); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if skipped := testutil.ToFloat64(metrics.WALReplaySkipped) - before; skipped != 3 {
+		t.Errorf("skipped %v records, want 3", skipped)
+	}
+	if got := testutil.ToFloat64(metrics.WALReplayRecords); got != 2 {
+		t.Errorf("applied %v records, want 2", got)
+	}
+	if agent, ok := store.LookupAgent(context.Background(), "eu", "a.example.com"); !ok || agent.VerifiableHash != "h2" {
+		t.Errorf("a.example.com = %+v, %v, want the first registration", agent, ok)
+	}
+}
+
+func TestReplayCorruption(t *testing.T) {
+	tests := []struct {
+		name string
+		// damage changes the file given the offset of the second record
+		damage func(data []byte, second int) []byte
+		want   error
+	}{
+		{
+			name: "crc mismatch",
+			damage: func(data []byte, second int) []byte {
+				data[second+headerSize] ^= 0xff
+				return data
+			},
+			want: ErrCorrupt,
+		},
+		{
+			name: "bad magic",
+			damage: func(data []byte, second int) []byte {
+				data[second] ^= 0xff
+				return data
+			},
+			want: ErrCorrupt,
+		},
+		{
+			name: "torn tail",
+			damage: func(data []byte, second int) []byte {
+				return data[:len(data)-3]
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := appendAll(t, t.TempDir(), nil, gatewayRecord("g1"))
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second := int(info.Size())
+			appendAll(t, filepath.Dir(path), nil, gatewayRecord("g2"))
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, tt.damage(data, second), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var records int
+			good, err := Scan(path, nil, func(Entry) error {
+				records++
+				return nil
+			})
+			if err == nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("Scan() error = %v, want %v", err, tt.want)
+			}
+			if records != 1 || good != int64(second) {
+				t.Fatalf("Scan() read %d records up to %d, want 1 up to %d", records, good, second)
+			}
+
+			res, err := Repair(path, nil)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+			if res.Records != 1 || res.Kept != int64(second) || res.Backup == "" {
+				t.Errorf("Repair() = %+v", res)
+			}
+			got, err := replayAll(t, path, nil)
+			if err != nil {
+				t.Fatalf("replay after repair error = %v", err)
+			}
+			checkRecords(t, got, gatewayRecord("g1"))
+		})
+	}
+}
+
+// failingWriter takes limit bytes and fails after, like a disk filling up
+// in the middle of a record.
+type failingWriter struct {
+	f     *os.File
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) <= w.limit {
+		w.limit -= len(p)
+		return w.f.Write(p)
+	}
+	n, _ := w.f.Write(p[:w.limit])
+	w.limit = 0
+	return n, errors.New("no space left on device")
+}
+
+func TestAppendRecoversAfterFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	w, err := OpenWAL(dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	ctx := context.Background()
+
+	if err := w.Append(ctx, gatewayRecord("g1")); err != nil {
+		t.Fatal(err)
+	}
+	w.writer = bufio.NewWriterSize(&failingWriter{f: w.f, limit: 5}, 16)
+	if err := w.Append(ctx, gatewayRecord("g2")); err == nil {
+		t.Fatal("Append() on a full disk succeeded")
+	}
+	if w.Health() != nil {
+		// the probe goes to the real disk, which has room
+		t.Fatal("Health() still failing after the disk has room")
+	}
+	if err := w.Append(ctx, gatewayRecord("g3")); err != nil {
+		t.Fatalf("Append() after the failure error = %v", err)
+	}
+
+	got, err := replayAll(t, File(dir), nil)
+	if err != nil {
+		t.Fatalf("replay error = %v", err)
+	}
+	checkRecords(t, got, gatewayRecord("g1"), gatewayRecord("g3"))
+}